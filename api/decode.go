@@ -0,0 +1,284 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// This file contains the decoding of the request bodies.
+
+const (
+	// MaxBodySize limits the size of the decoded request bodies.
+	MaxBodySize = 1 << 20
+
+	ContentTypeJSON = "application/json"
+	ContentTypeForm = "application/x-www-form-urlencoded"
+)
+
+type (
+	// DecodeError represents a request body which can't be decoded.
+	// The detail describes the body of the client, so it is safe to respond with.
+	DecodeError struct {
+		Status int
+		Code   string
+		Detail string
+		// Err is the cause, io.EOF for the empty bodies.
+		Err error
+	}
+
+	// readRecorder records the error of the reader, since the JSON decoder
+	// reports the failed reads as the malformed bodies.
+	readRecorder struct {
+		io.Reader
+		err error
+	}
+)
+
+// Error returns the detail along with the cause.
+func (e *DecodeError) Error() string {
+	if e.Err == nil {
+		return "api: " + e.Detail
+	}
+	return "api: " + e.Detail + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Decode decodes the JSON or the form body of the request into v, a pointer to a struct.
+// The form fields are named after the json tags of the struct fields. It fails with
+// a DecodeError, whose status is 415 for the other content types.
+//
+// The bodies without the content type are the one exception, they are decoded as JSON
+// rather than rejected, since many clients don't set it. Whatever isn't JSON is then
+// rejected with 400.
+func Decode(w http.ResponseWriter, r *http.Request, v any) error {
+	mediaType, err := contentType(r)
+	if err != nil {
+		return err
+	}
+
+	switch mediaType {
+	case "", ContentTypeJSON:
+		return decodeJSON(w, r, v)
+	case ContentTypeForm:
+		return decodeForm(w, r, v)
+	default:
+		return unsupportedMediaType(mediaType, ContentTypeJSON, ContentTypeForm)
+	}
+}
+
+// DecodeJSON decodes the JSON body of the request into v. It fails with a DecodeError,
+// whose status is 415 for the other content types. The bodies without the content type
+// are decoded as JSON, like with Decode.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	mediaType, err := contentType(r)
+	if err != nil {
+		return err
+	}
+	if mediaType != "" && mediaType != ContentTypeJSON {
+		return unsupportedMediaType(mediaType, ContentTypeJSON)
+	}
+	return decodeJSON(w, r, v)
+}
+
+// contentType returns the media type of the request body, empty without the header.
+func contentType(r *http.Request) (string, error) {
+	header := r.Header.Get("Content-Type")
+	if header == "" {
+		return "", nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "", &DecodeError{http.StatusUnsupportedMediaType, "unsupported_media_type", "the content type is malformed", err}
+	}
+	return mediaType, nil
+}
+
+// unsupportedMediaType returns the error of the media type, listing the supported ones.
+func unsupportedMediaType(mediaType string, supported ...string) error {
+	return &DecodeError{
+		Status: http.StatusUnsupportedMediaType,
+		Code:   "unsupported_media_type",
+		Detail: fmt.Sprintf("the content type %s isn't supported, expected %s", mediaType, strings.Join(supported, " or ")),
+	}
+}
+
+// decodeJSON decodes the single JSON value of the body, rejecting the unknown fields.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	limitBody(w, r)
+	body := &readRecorder{Reader: r.Body}
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		if tooLarge := bodyTooLarge(body.err); tooLarge != nil {
+			return tooLarge
+		}
+		return jsonError(v, err)
+	}
+
+	// Anything but the whitespace after the value is rejected.
+	var extra json.RawMessage
+	if err := decoder.Decode(&extra); !errors.Is(err, io.EOF) {
+		if tooLarge := bodyTooLarge(body.err); tooLarge != nil {
+			return tooLarge
+		}
+		return invalidBody("the body must contain a single JSON value", err)
+	}
+	return nil
+}
+
+// Read reads from the reader, recording the error other than io.EOF.
+func (r *readRecorder) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.err = err
+	}
+	return n, err
+}
+
+// jsonError describes the JSON decoding error of v to the client.
+func jsonError(v any, err error) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.Is(err, io.EOF):
+		return invalidBody("the body is empty", err)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return invalidBody("the body is truncated", err)
+	case errors.As(err, &syntaxErr):
+		return invalidBody(fmt.Sprintf("the body is malformed at offset %d", syntaxErr.Offset), err)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return invalidBody(fmt.Sprintf("the field %s must be %s", jsonName(v, typeErr.Field), typeErr.Type), err)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return invalidBody(strings.TrimPrefix(err.Error(), "json: "), err)
+	default:
+		// The values may fail to decode themselves, e.g. the malformed ids.
+		return invalidBody("the body has invalid values", err)
+	}
+}
+
+// jsonName returns the JSON name of the struct field v points to,
+// the decoder reports the Go names.
+func jsonName(v any, field string) string {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return field
+	}
+
+	f, ok := t.FieldByName(field)
+	if !ok {
+		return field
+	}
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return field
+}
+
+// decodeForm decodes the form body into the struct, rejecting the unknown fields.
+func decodeForm(w http.ResponseWriter, r *http.Request, v any) error {
+	limitBody(w, r)
+	if err := r.ParseForm(); err != nil {
+		if tooLarge := bodyTooLarge(err); tooLarge != nil {
+			return tooLarge
+		}
+		return invalidBody("the form is malformed", err)
+	}
+
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("api: can't decode a form into %T", v)
+	}
+	target = target.Elem()
+
+	fields := map[string]reflect.Value{}
+	for i := 0; i < target.NumField(); i++ {
+		name, _, _ := strings.Cut(target.Type().Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" && target.Type().Field(i).IsExported() {
+			fields[name] = target.Field(i)
+		}
+	}
+
+	// The query parameters aren't part of the body.
+	for name, values := range r.PostForm {
+		field, ok := fields[name]
+		if !ok {
+			return invalidBody(fmt.Sprintf("unknown field %q", name), nil)
+		}
+		if len(values) != 1 {
+			return invalidBody(fmt.Sprintf("the field %s must have a single value", name), nil)
+		}
+		if err := setField(field, values[0]); err != nil {
+			return invalidBody(fmt.Sprintf("the field %s must be %s", name, field.Kind()), err)
+		}
+	}
+	return nil
+}
+
+// setField parses the form value into the field of a basic kind.
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	default:
+		return fmt.Errorf("api: can't decode a form value into %s", field.Type())
+	}
+	return nil
+}
+
+// limitBody limits the body of the request to MaxBodySize.
+func limitBody(w http.ResponseWriter, r *http.Request) {
+	// The server requests always have a body, unlike the client ones.
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBodySize)
+}
+
+// invalidBody returns the error of the body the client got wrong.
+func invalidBody(detail string, err error) error {
+	return &DecodeError{http.StatusBadRequest, "invalid_body", detail, err}
+}
+
+// bodyTooLarge returns the error of the body exceeding the limit, nil for the other errors.
+func bodyTooLarge(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		return nil
+	}
+	return &DecodeError{
+		Status: http.StatusRequestEntityTooLarge,
+		Code:   "body_too_large",
+		Detail: fmt.Sprintf("the body must not exceed %d bytes", maxBytesErr.Limit),
+		Err:    err,
+	}
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type decodeRequest struct {
+	Name   string `json:"name"`
+	Age    int    `json:"age"`
+	Admin  bool   `json:"-"`
+	Active bool   `json:"active,omitempty"`
+}
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		testName       string
+		contentType    string
+		body           string
+		expected       decodeRequest
+		expectedStatus int
+		expectedCode   string
+		expectedDetail string
+		expectedIsEOF  bool
+		decodeJSONOnly bool
+	}{
+		{
+			testName:    "JSON",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"name":"alice","age":30,"active":true}`,
+			expected:    decodeRequest{Name: "alice", Age: 30, Active: true},
+		},
+		{
+			testName: "JSON without content type",
+			body:     " {\"name\":\"alice\"}\n",
+			expected: decodeRequest{Name: "alice"},
+		},
+		{
+			testName:       "JSON only without content type",
+			body:           `{"name":"alice"}`,
+			expected:       decodeRequest{Name: "alice"},
+			decodeJSONOnly: true,
+		},
+		{
+			testName:       "Form without content type",
+			body:           "name=alice",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_body",
+		},
+		{
+			testName:    "Form",
+			contentType: ContentTypeForm,
+			body:        "name=alice&age=30&active=true",
+			expected:    decodeRequest{Name: "alice", Age: 30, Active: true},
+		},
+		{
+			testName:       "Unsupported media type",
+			contentType:    "text/plain",
+			body:           "alice",
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   "unsupported_media_type",
+			expectedDetail: "the content type text/plain isn't supported, expected application/json or application/x-www-form-urlencoded",
+		},
+		{
+			testName:       "Form for JSON only",
+			contentType:    ContentTypeForm,
+			body:           "name=alice",
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   "unsupported_media_type",
+			expectedDetail: "the content type application/x-www-form-urlencoded isn't supported, expected application/json",
+			decodeJSONOnly: true,
+		},
+		{
+			testName:       "Malformed content type",
+			contentType:    "application/json; charset",
+			body:           `{}`,
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   "unsupported_media_type",
+			expectedDetail: "the content type is malformed",
+		},
+		{
+			testName:       "Empty JSON",
+			contentType:    ContentTypeJSON,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_body",
+			expectedDetail: "the body is empty",
+			expectedIsEOF:  true,
+		},
+		{
+			testName:       "Unknown JSON field",
+			contentType:    ContentTypeJSON,
+			body:           `{"name":"alice","admin":true}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_body",
+			expectedDetail: `unknown field "admin"`,
+		},
+		{
+			testName:       "Unknown form field",
+			contentType:    ContentTypeForm,
+			body:           "name=alice&admin=true",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_body",
+			expectedDetail: `unknown field "admin"`,
+		},
+		{
+			testName:       "Repeated form field",
+			contentType:    ContentTypeForm,
+			body:           "name=alice&name=bob",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_body",
+			expectedDetail: "the field name must have a single value",
+		},
+		{
+			testName:       "Invalid form value",
+			contentType:    ContentTypeForm,
+			body:           "age=old",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_body",
+			expectedDetail: "the field age must be int",
+		},
+		{
+			testName:       "Wrong JSON type",
+			contentType:    ContentTypeJSON,
+			body:           `{"age":"old"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_body",
+			expectedDetail: "the field age must be int",
+		},
+		{
+			testName:       "Malformed JSON",
+			contentType:    ContentTypeJSON,
+			body:           `{"name":}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_body",
+		},
+		{
+			testName:       "Several JSON values",
+			contentType:    ContentTypeJSON,
+			body:           `{"name":"alice"}{"name":"bob"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_body",
+			expectedDetail: "the body must contain a single JSON value",
+		},
+		{
+			testName:       "Trailing garbage",
+			contentType:    ContentTypeJSON,
+			body:           `{"name":"alice"} x`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_body",
+			expectedDetail: "the body must contain a single JSON value",
+		},
+		{
+			testName:       "Too large JSON",
+			contentType:    ContentTypeJSON,
+			body:           `{"name":"` + strings.Repeat("a", MaxBodySize) + `"}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   "body_too_large",
+			expectedDetail: "the body must not exceed 1048576 bytes",
+		},
+		{
+			testName:       "Too large form",
+			contentType:    ContentTypeForm,
+			body:           "name=" + strings.Repeat("a", MaxBodySize),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   "body_too_large",
+			expectedDetail: "the body must not exceed 1048576 bytes",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(c.body))
+			if c.contentType != "" {
+				r.Header.Set("Content-Type", c.contentType)
+			}
+
+			var (
+				req decodeRequest
+				err error
+			)
+			if c.decodeJSONOnly {
+				err = DecodeJSON(httptest.NewRecorder(), r, &req)
+			} else {
+				err = Decode(httptest.NewRecorder(), r, &req)
+			}
+
+			if c.expectedStatus == 0 {
+				require.NoError(t, err)
+				assert.Equal(t, c.expected, req)
+				return
+			}
+
+			var decodeErr *DecodeError
+			require.ErrorAs(t, err, &decodeErr)
+			assert.Equal(t, c.expectedStatus, decodeErr.Status)
+			assert.Equal(t, c.expectedCode, decodeErr.Code)
+			if c.expectedDetail != "" {
+				assert.Equal(t, c.expectedDetail, decodeErr.Detail)
+			}
+			assert.Equal(t, c.expectedIsEOF, errors.Is(err, io.EOF))
+		})
+	}
+}
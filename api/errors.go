@@ -0,0 +1,13 @@
+package api
+
+// This file contains the errors shared by the http handlers.
+
+import "errors"
+
+// Define custom errors.
+var (
+	ErrInvalidBody      = errors.New("api: invalid request body")
+	ErrInvalidID        = errors.New("api: invalid id")
+	ErrNotFound         = errors.New("api: not found")
+	ErrMethodNotAllowed = errors.New("api: method not allowed")
+)
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/httplog/v2"
+	"github.com/goccy/go-json"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// This file contains the RFC 7807 problem details responses.
+
+// ContentTypeProblem is the media type of the problem responses.
+const ContentTypeProblem = "application/problem+json"
+
+// CodeInternal is the code of the errors no mapping matches.
+const CodeInternal = "internal"
+
+type (
+	// Problem represents the problem details of a failed request, see RFC 7807.
+	// The type is left out, which means about:blank, so the title is the status text.
+	Problem struct {
+		Title  string `json:"title"`
+		Status int    `json:"status"`
+		// Detail explains the problem to the client, it never holds the internal cause.
+		Detail string `json:"detail,omitempty"`
+		// Instance is the path of the request.
+		Instance string `json:"instance,omitempty"`
+		// Code is the stable machine-readable code of the problem.
+		Code string `json:"code"`
+		// RequestID identifies the request in the server logs.
+		RequestID string `json:"request_id,omitempty"`
+		// Errors lists the invalid fields of the request.
+		Errors []FieldError `json:"errors,omitempty"`
+	}
+
+	// Mapping maps a domain error to the response status and code.
+	Mapping struct {
+		Err    error
+		Status int
+		Code   string
+	}
+)
+
+// mappings are the errors shared by the handlers of all packages.
+var mappings = []Mapping{
+	{ErrInvalidBody, http.StatusBadRequest, "invalid_body"},
+	{ErrInvalidID, http.StatusBadRequest, "invalid_id"},
+	{ErrNotFound, http.StatusNotFound, "not_found"},
+	{ErrMethodNotAllowed, http.StatusMethodNotAllowed, "method_not_allowed"},
+}
+
+// WriteError writes the problem of the first mapping whose error the error matches,
+// the shared ones are tried last. The detail is the message of the mapped error
+// without the package prefix, never of the error itself, since the wrapping errors
+// may leak the internals. The DecodeError tells what is wrong with the body and the
+// ValidationError lists the invalid fields, while the errors no mapping matches
+// are internal ones. The cause is logged along with the request in any case.
+func WriteError(w http.ResponseWriter, r *http.Request, err error, domain ...Mapping) {
+	httplog.LogEntrySetField(r.Context(), "error", slog.StringValue(err.Error()))
+
+	var decodeErr *DecodeError
+	if errors.As(err, &decodeErr) {
+		writeProblem(w, r, Problem{Status: decodeErr.Status, Detail: decodeErr.Detail, Code: decodeErr.Code})
+		return
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		writeProblem(w, r, Problem{
+			Status: http.StatusBadRequest,
+			Detail: "the request has invalid fields",
+			Code:   CodeInvalidFields,
+			Errors: validationErr.Fields,
+		})
+		return
+	}
+
+	for _, list := range [][]Mapping{domain, mappings} {
+		for _, m := range list {
+			if errors.Is(err, m.Err) {
+				WriteProblem(w, r, m.Status, m.Code, detail(m.Err))
+				return
+			}
+		}
+	}
+
+	span := trace.SpanFromContext(r.Context())
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	WriteProblem(w, r, http.StatusInternalServerError, CodeInternal, "")
+}
+
+// detail returns the message of the error without the package prefix.
+func detail(err error) string {
+	if _, message, ok := strings.Cut(err.Error(), ": "); ok {
+		return message
+	}
+	return err.Error()
+}
+
+// WriteProblem writes the problem response of the request.
+func WriteProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	writeProblem(w, r, Problem{Status: status, Detail: detail, Code: code})
+}
+
+// writeProblem fills in the problem details of the request and writes it.
+func writeProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	problem.Title = http.StatusText(problem.Status)
+	problem.Instance = r.URL.Path
+	problem.RequestID = middleware.GetReqID(r.Context())
+
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	w.WriteHeader(problem.Status)
+	// The status is written already, nothing else can be done.
+	_ = json.NewEncoder(w).Encode(problem)
+}
+
+// NotFound responds to the requests no route matches.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, ErrNotFound)
+}
+
+// MethodNotAllowed responds to the requests of a route with another method.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, ErrMethodNotAllowed)
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/httplog/v2"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTaken = errors.New("test: name is taken")
+
+func TestWriteError(t *testing.T) {
+	domain := []Mapping{{Err: errTaken, Status: http.StatusConflict, Code: "name_taken"}}
+
+	cases := []struct {
+		testName        string
+		err             error
+		expectedProblem Problem
+	}{
+		{
+			testName:        "Domain",
+			err:             fmt.Errorf("create: %w", errTaken),
+			expectedProblem: Problem{Title: "Conflict", Status: http.StatusConflict, Detail: "name is taken", Code: "name_taken"},
+		},
+		{
+			testName:        "Shared",
+			err:             ErrInvalidBody,
+			expectedProblem: Problem{Title: "Bad Request", Status: http.StatusBadRequest, Detail: "invalid request body", Code: "invalid_body"},
+		},
+		{
+			testName: "Validation",
+			err:      &ValidationError{[]FieldError{{Field: "name", Code: "too_short", Message: "must be at least 3 characters long"}}},
+			expectedProblem: Problem{
+				Title:  "Bad Request",
+				Status: http.StatusBadRequest,
+				Detail: "the request has invalid fields",
+				Code:   CodeInvalidFields,
+				Errors: []FieldError{{Field: "name", Code: "too_short", Message: "must be at least 3 characters long"}},
+			},
+		},
+		{
+			testName: "Decode",
+			err:      &DecodeError{Status: http.StatusUnsupportedMediaType, Code: "unsupported_media_type", Detail: "the content type text/plain isn't supported"},
+			expectedProblem: Problem{
+				Title:  "Unsupported Media Type",
+				Status: http.StatusUnsupportedMediaType,
+				Detail: "the content type text/plain isn't supported",
+				Code:   "unsupported_media_type",
+			},
+		},
+		{
+			testName:        "Internal",
+			err:             errors.New("error get email:pq: connection refused"),
+			expectedProblem: Problem{Title: "Internal Server Error", Status: http.StatusInternalServerError, Code: CodeInternal},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := httplog.NewLogger("test", httplog.Options{JSON: true, Concise: true, Writer: &out})
+
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID, httplog.RequestLogger(logger))
+			r.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+				WriteError(w, r, c.err, domain...)
+			})
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+			assert.Equal(t, c.expectedProblem.Status, rr.Code)
+			assert.Equal(t, ContentTypeProblem, rr.Header().Get("Content-Type"))
+
+			var problem Problem
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+			assert.NotEmpty(t, problem.RequestID)
+			c.expectedProblem.Instance, c.expectedProblem.RequestID = "/users", problem.RequestID
+			assert.Equal(t, c.expectedProblem, problem)
+
+			// The cause is logged along with the request only.
+			assert.Contains(t, out.String(), c.err.Error())
+			assert.Contains(t, out.String(), problem.RequestID)
+		})
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	r := chi.NewRouter()
+	r.NotFound(NotFound)
+	r.MethodNotAllowed(MethodNotAllowed)
+	r.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+
+	cases := []struct {
+		testName       string
+		method         string
+		path           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{testName: "NotFound", method: http.MethodGet, path: "/missing", expectedStatus: http.StatusNotFound, expectedCode: "not_found"},
+		{testName: "MethodNotAllowed", method: http.MethodPost, path: "/users", expectedStatus: http.StatusMethodNotAllowed, expectedCode: "method_not_allowed"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(c.method, c.path, nil))
+
+			assert.Equal(t, c.expectedStatus, rr.Code)
+			var problem Problem
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+			assert.Equal(t, c.expectedCode, problem.Code)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// This file contains the validation of the request fields.
+
+// CodeInvalidFields is the code of the problems listing the invalid fields.
+const CodeInvalidFields = "invalid_fields"
+
+type (
+	// FieldError represents the problem of a request field.
+	FieldError struct {
+		Field string `json:"field"`
+		// Code is the stable machine-readable code of the problem, e.g. too_short.
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+
+	// ValidationError reports all the invalid fields of a request at once.
+	ValidationError struct {
+		Fields []FieldError
+	}
+
+	// Validation collects the field errors of a request.
+	Validation struct {
+		fields []FieldError
+	}
+)
+
+// Error lists the invalid fields.
+func (e *ValidationError) Error() string {
+	names := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		names = append(names, f.Field+" "+f.Code)
+	}
+	return "api: invalid fields: " + strings.Join(names, ", ")
+}
+
+// Check adds the problem of the field unless ok.
+func (v *Validation) Check(ok bool, field, code, format string, args ...any) {
+	if !ok {
+		v.fields = append(v.fields, FieldError{field, code, fmt.Sprintf(format, args...)})
+	}
+}
+
+// Valid reports whether the field has no problems so far, so the checks
+// which make no sense for an invalid value can be skipped.
+func (v *Validation) Valid(field string) bool {
+	for _, f := range v.fields {
+		if f.Field == field {
+			return false
+		}
+	}
+	return true
+}
+
+// Err returns the ValidationError of the problems, nil without them.
+func (v *Validation) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{v.fields}
+}
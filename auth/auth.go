@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/api"
+	"github.com/google/uuid"
+)
+
+// This file contains http middlewares that authenticate incoming requests.
+
+const (
+	// SessionCookie is the name of the cookie that holds the user session.
+	SessionCookie = "session"
+)
+
+// errorMappings map the auth errors to the problem responses.
+var errorMappings = []api.Mapping{
+	{Err: ErrAuthRequired, Status: http.StatusUnauthorized, Code: "auth_required"},
+	{Err: ErrInvalidSession, Status: http.StatusUnauthorized, Code: "invalid_session"},
+	{Err: ErrSessionExpired, Status: http.StatusUnauthorized, Code: "session_expired"},
+	{Err: ErrInvalidToken, Status: http.StatusUnauthorized, Code: "invalid_token"},
+}
+
+type (
+	// Config represents the configuration options for request authentication.
+	Config struct {
+		// ServerToken authenticates requests coming from the game server.
+		ServerToken string `env:"GAME_SERVER_TOKEN" yaml:"server_token" secret:"true"`
+		// AdminToken authenticates requests coming from staff tools.
+		AdminToken string `env:"ADMIN_TOKEN" yaml:"admin_token" secret:"true"`
+		// SessionSecret signs the user sessions, an empty secret rejects every session.
+		SessionSecret string `env:"SESSION_SECRET" yaml:"session_secret" secret:"true"`
+		// SessionTTL is how long the user sessions last.
+		SessionTTL time.Duration `env:"SESSION_TTL" envDefault:"720h" yaml:"session_ttl"`
+	}
+
+	// userIDKey is the context key of the authenticated user id.
+	userIDKey struct{}
+)
+
+// RequireUser rejects requests without a valid user session signed with the session secret
+// and stores the user id in the request context.
+func RequireUser(config Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie, err := r.Cookie(SessionCookie)
+			if err != nil {
+				writeError(w, r, ErrAuthRequired)
+				return
+			}
+
+			id, err := parseSession(config, cookie.Value, time.Now())
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithUserID(r.Context(), id)))
+		})
+	}
+}
+
+// RequireToken rejects requests that do not carry the given bearer token.
+// An empty token rejects every request, so unconfigured endpoints stay closed.
+func RequireToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				writeError(w, r, ErrInvalidToken)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithUserID returns a copy of the context that carries the user id.
+func WithUserID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, userIDKey{}, id)
+}
+
+// UserID returns the authenticated user id stored in the context.
+func UserID(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(userIDKey{}).(uuid.UUID)
+	return id, ok
+}
+
+// writeError writes the problem matching the error.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	api.WriteError(w, r, err, errorMappings...)
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireUser(t *testing.T) {
+	userID := uuid.New()
+	config := Config{SessionSecret: "0123456789abcdef0123456789abcdef", SessionTTL: time.Hour}
+	session, err := NewSession(config, userID, time.Now())
+	require.NoError(t, err)
+	expired, err := NewSession(config, userID, time.Now().Add(-2*time.Hour))
+	require.NoError(t, err)
+
+	cases := []struct {
+		testName       string
+		config         Config
+		cookie         *http.Cookie
+		expectedStatus int
+	}{
+		{
+			testName:       "ok",
+			config:         config,
+			cookie:         &http.Cookie{Name: SessionCookie, Value: session},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			testName:       "missing cookie",
+			config:         config,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "unsigned user id",
+			config:         config,
+			cookie:         &http.Cookie{Name: SessionCookie, Value: userID.String()},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "other user",
+			config:         config,
+			cookie:         &http.Cookie{Name: SessionCookie, Value: uuid.NewString() + strings.TrimPrefix(session, userID.String())},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "expired",
+			config:         config,
+			cookie:         &http.Cookie{Name: SessionCookie, Value: expired},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "other secret",
+			config:         Config{SessionSecret: "fedcba9876543210fedcba9876543210", SessionTTL: time.Hour},
+			cookie:         &http.Cookie{Name: SessionCookie, Value: session},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "secret not configured",
+			cookie:         &http.Cookie{Name: SessionCookie, Value: session},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			handler := RequireUser(tc.config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id, ok := UserID(r.Context())
+				assert.True(t, ok)
+				assert.Equal(t, userID, id)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.cookie != nil {
+				req.AddCookie(tc.cookie)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestSetSession(t *testing.T) {
+	config := Config{SessionSecret: "0123456789abcdef0123456789abcdef", SessionTTL: time.Hour}
+	userID := uuid.New()
+
+	rr := httptest.NewRecorder()
+	require.NoError(t, SetSession(rr, config, userID))
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, SessionCookie, cookies[0].Name)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.True(t, cookies[0].Secure)
+	id, err := parseSession(config, cookies[0].Value, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, userID, id)
+
+	// The sessions can't be issued without the secret.
+	assert.ErrorIs(t, SetSession(httptest.NewRecorder(), Config{}, userID), ErrNoSessionSecret)
+}
+
+func TestRequireToken(t *testing.T) {
+	cases := []struct {
+		testName       string
+		token          string
+		header         string
+		expectedStatus int
+	}{
+		{
+			testName:       "ok",
+			token:          "secret",
+			header:         "Bearer secret",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			testName:       "wrong token",
+			token:          "secret",
+			header:         "Bearer other",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "missing scheme",
+			token:          "secret",
+			header:         "secret",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "token not configured",
+			token:          "",
+			header:         "Bearer ",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			handler := RequireToken(tc.token)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", tc.header)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package auth
+
+// This file contains authentication related errors.
+
+import "errors"
+
+// Define custom errors.
+var (
+	ErrAuthRequired   = errors.New("auth: authentication required")
+	ErrInvalidSession = errors.New("auth: invalid session")
+	ErrInvalidToken   = errors.New("auth: invalid token")
+	ErrSessionExpired = errors.New("auth: session expired")
+	// ErrNoSessionSecret is returned when the sessions can't be signed without the secret.
+	ErrNoSessionSecret = errors.New("auth: session secret is not configured")
+)
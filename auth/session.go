@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// This file contains the user sessions signed with the session secret.
+
+// SetSession sets the session cookie of the user.
+func SetSession(w http.ResponseWriter, config Config, id uuid.UUID) error {
+	now := time.Now()
+	value, err := NewSession(config, id, now)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    value,
+		Path:     "/",
+		Expires:  now.Add(config.SessionTTL),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// NewSession returns the session of the user, the user id and the expiry signed with the secret.
+func NewSession(config Config, id uuid.UUID, now time.Time) (string, error) {
+	if config.SessionSecret == "" {
+		return "", ErrNoSessionSecret
+	}
+
+	payload := id.String() + "." + strconv.FormatInt(now.Add(config.SessionTTL).Unix(), 10)
+	return payload + "." + sign(config.SessionSecret, payload), nil
+}
+
+// parseSession verifies the signature and the expiry of the session, returning the user id.
+// An empty secret rejects every session.
+func parseSession(config Config, value string, now time.Time) (uuid.UUID, error) {
+	i := strings.LastIndex(value, ".")
+	if config.SessionSecret == "" || i < 0 ||
+		!hmac.Equal([]byte(value[i+1:]), []byte(sign(config.SessionSecret, value[:i]))) {
+		return uuid.Nil, ErrInvalidSession
+	}
+
+	// The signed payload is well formed.
+	idValue, expiryValue, _ := strings.Cut(value[:i], ".")
+	expiry, err := strconv.ParseInt(expiryValue, 10, 64)
+	if err != nil {
+		return uuid.Nil, ErrInvalidSession
+	}
+	if now.Unix() >= expiry {
+		return uuid.Nil, ErrSessionExpired
+	}
+
+	id, err := uuid.Parse(idValue)
+	if err != nil {
+		return uuid.Nil, ErrInvalidSession
+	}
+	return id, nil
+}
+
+// sign returns the HMAC-SHA256 of the payload.
+func sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
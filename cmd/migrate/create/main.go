@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 
@@ -13,11 +14,27 @@ func main() {
 	path := flag.String("path", "", "path to the migration files")
 	// version := flag.String("version", "", "version of the migration")
 	name := flag.String("name", "", "name of the migration")
+	goMigration := flag.Bool("go", false, "create a go migration instead of the sql files")
+	tmpl := flag.String("template", "", "fill the sql files from a template: "+strings.Join(migrate.Templates(), ", "))
+	table := flag.String("table", "", "table of the template")
+	column := flag.String("column", "", "column of the template")
+	columnType := flag.String("type", "", "column type of the add_column template")
 
 	flag.Parse()
 
+	opts := migrate.CreateOptions{
+		Kind:     migrate.KindSQL,
+		Template: *tmpl,
+		Table:    *table,
+		Column:   *column,
+		Type:     *columnType,
+	}
+	if *goMigration {
+		opts.Kind = migrate.KindGo
+	}
+
 	// Create the migration files.
-	if err := migrate.CreateMigrationFiles(*path, *name); err != nil {
+	if err := migrate.CreateMigrationFiles(*path, *name, opts); err != nil {
 		log.Fatal().Err(err).Msg("create migration files")
 	}
 
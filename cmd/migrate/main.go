@@ -1,59 +1,270 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"text/tabwriter"
 	"time"
 
+	"github.com/GTA5-RP-Aristocracy/site-back/db"
 	"github.com/GTA5-RP-Aristocracy/site-back/db/migrate"
+	"github.com/GTA5-RP-Aristocracy/site-back/migrations"
+	"github.com/caarlos0/env/v11"
+	"github.com/goccy/go-json"
 	_ "github.com/lib/pq"
 	"github.com/rs/zerolog/log"
 )
 
+const usage = `usage: migrate [-path <dir>] [-db <dsn>] [-json] [-dry-run] <command> [arg]
+
+Without -path the migrations embedded in the binary are run.
+Without -db the connection is configured by the DB_* environment variables, like the site one.
+With -dry-run the statements that would run are printed and nothing is changed.
+
+The databases created before the migrations were tracked already have the user tables,
+they are adopted with baseline 2024-10-30-00-00-00 before the first up.
+
+commands:
+  status             list the applied and pending migrations, with changed ones as drift
+  plan               print the statements of the pending migrations, same as -dry-run up
+  up [N]             apply N pending migrations, all of them by default
+  down [N]           roll back N applied migrations, 1 by default
+  redo               roll back and apply again the last migration
+  goto <version>     migrate up or down to the version
+  force <version>    mark the migrations up to the version as applied without running them
+  baseline <version> like force, for a database with an empty history only
+  dump [file]        write the schema of the database to the file, db/schema.sql by default, - for stdout
+`
+
+// defaultSchemaFile is the checked in schema snapshot.
+const defaultSchemaFile = "db/schema.sql"
+
+type (
+	// result represents the outcome of a command in the JSON output.
+	result struct {
+		Command    string           `json:"command"`
+		DryRun     bool             `json:"dry_run,omitempty"`
+		Migrations []step           `json:"migrations,omitempty"`
+		Status     []migrate.Status `json:"status,omitempty"`
+		Error      string           `json:"error,omitempty"`
+	}
+
+	// step represents a migration run by the command.
+	step struct {
+		Version   string `json:"version"`
+		Name      string `json:"name"`
+		Direction string `json:"direction"`
+		// Go is set for the Go migrations, they have no statements.
+		Go bool `json:"go,omitempty"`
+		// Statements are listed in the dry run mode.
+		Statements []string `json:"statements,omitempty"`
+	}
+)
+
 func main() {
 	// Read flags.
-	path := flag.String("path", "", "path to the migration files")
-	version := flag.String("version", "", "version of the migration (time format )")
-	dbConnection := flag.String("db", "", "database connection string")
+	path := flag.String("path", "", "path to the migration files, the embedded ones by default")
+	dbConnection := flag.String("db", "", "database connection string, DB_URL or the DB_* options by default")
+	jsonOutput := flag.Bool("json", false, "print the result as JSON")
+	dryRun := flag.Bool("dry-run", false, "print the statements that would run without running them")
+	flag.Usage = func() { fmt.Fprint(flag.CommandLine.Output(), usage) }
 
 	flag.Parse()
 
-	log.Info().
-		Str("path", *path).
-		Str("version", *version).
-		Strs("flags", flag.Args()).
-		Msg("")
+	command := flag.Arg(0)
+	if command == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
 
-	direction := flag.Arg(0)
-	if direction == "" {
-		log.Fatal().Msg("missing migration direction")
+	var dbConfig db.Config
+	if err := env.Parse(&dbConfig); err != nil {
+		log.Fatal().Err(err).Msg("parse the database configuration")
+	}
+	if *dbConnection != "" {
+		dbConfig.URL = *dbConnection
 	}
-	log.Info().Str("direction", direction).Msg("")
 
-	dbInstance, err := sql.Open("postgres", *dbConnection)
+	dbInstance, err := db.ConnectDB(context.Background(), dbConfig)
 	if err != nil {
 		log.Fatal().Err(err).Msg("connect to the database")
 	}
+	defer dbInstance.Close()
+
+	if command == "dump" {
+		if err := dump(context.Background(), dbInstance, flag.Arg(1)); err != nil {
+			log.Fatal().Err(err).Msg("dump schema")
+		}
+		return
+	}
+
+	sources := migrations.Sources()
+	if *path != "" {
+		sources = []fs.FS{os.DirFS(*path)}
+	}
+
+	migrator := migrate.NewMigrator(dbInstance, sources...)
+	if command == "plan" {
+		command, *dryRun = "up", true
+	}
+	if *dryRun {
+		migrator = migrator.DryRun()
+	}
+
+	res, err := run(context.Background(), migrator, command, flag.Arg(1), *dryRun)
+	if err != nil {
+		res.Error = err.Error()
+	}
 
-	versionTime := time.Now()
-	if *version != "" {
-		versionTime, err = time.Parse(migrate.FormatVersion, *version)
-		if err != nil {
-			log.Fatal().Err(err).Msg("parse version")
+	if *jsonOutput {
+		if encodeErr := json.NewEncoder(os.Stdout).Encode(res); encodeErr != nil {
+			log.Fatal().Err(encodeErr).Msg("write result")
 		}
+	} else {
+		printResult(res)
 	}
 
-	switch migrate.MigrationDirection(direction) {
-	case migrate.MigrationUp:
-		err = migrate.RunMigrationsUp(dbInstance, *path, versionTime)
-	case migrate.MigrationDown:
-		err = migrate.RunMigrationsDown(dbInstance, *path, versionTime)
+	if err != nil {
+		log.Fatal().Err(err).Str("command", command).Msg("run migrations")
+	}
+}
+
+// run runs the command with its argument.
+func run(ctx context.Context, migrator *migrate.Migrator, command, arg string, dryRun bool) (result, error) {
+	res := result{Command: command, DryRun: dryRun}
+
+	var (
+		migrations []migrate.Migration
+		err        error
+	)
+	switch command {
+	case "status":
+		res.Status, err = migrator.Status(ctx)
+		return res, err
+	case "up":
+		n, parseErr := parseCount(arg, 0)
+		if parseErr != nil {
+			return res, parseErr
+		}
+		migrations, err = migrator.Up(ctx, n)
+	case "down":
+		n, parseErr := parseCount(arg, 1)
+		if parseErr != nil {
+			return res, parseErr
+		}
+		migrations, err = migrator.Down(ctx, n)
+	case "redo":
+		migrations, err = migrator.Redo(ctx)
+	case "goto", "force", "baseline":
+		version, parseErr := time.Parse(migrate.FormatVersion, arg)
+		if parseErr != nil {
+			return res, fmt.Errorf("parse version: %w", parseErr)
+		}
+		if command != "goto" && dryRun {
+			return res, fmt.Errorf("%s doesn't support the dry run mode", command)
+		}
+		switch command {
+		case "force":
+			return res, migrator.Force(ctx, version)
+		case "baseline":
+			return res, migrator.Baseline(ctx, version)
+		}
+		migrations, err = migrator.Goto(ctx, version)
 	default:
-		log.Fatal().Msg("invalid migration direction")
+		return res, fmt.Errorf("unknown command %q", command)
+	}
+
+	for _, m := range migrations {
+		s := step{Version: m.Version.Format(migrate.FormatVersion), Name: m.Name, Direction: string(m.Direction), Go: m.Func != nil}
+		if dryRun {
+			s.Statements = migrate.SplitStatements(m.SQL)
+		}
+		res.Migrations = append(res.Migrations, s)
 	}
+	return res, err
+}
+
+// dump writes the schema snapshot to the file.
+func dump(ctx context.Context, dbInstance *sql.DB, file string) error {
+	schema, err := migrate.DumpSchema(ctx, dbInstance)
 	if err != nil {
-		log.Fatal().Err(err).Msg("run migrations")
+		return err
 	}
 
-	log.Info().Msg("migrations run successfully")
+	switch file {
+	case "-":
+		_, err = fmt.Fprint(os.Stdout, schema)
+		return err
+	case "":
+		file = defaultSchemaFile
+	}
+	return os.WriteFile(file, []byte(schema), 0o644)
+}
+
+// parseCount parses the number of migrations to run.
+func parseCount(arg string, def int) (int, error) {
+	if arg == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid number of migrations %q", arg)
+	}
+	return n, nil
+}
+
+// printResult writes the result in a human readable form.
+func printResult(res result) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	if res.Command == "status" {
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT\tCHECKSUM")
+		for _, s := range res.Status {
+			state, appliedAt, checksum := "pending", "", s.Checksum
+			if s.Applied {
+				state, appliedAt, checksum = "applied", s.AppliedAt.Format(time.RFC3339), s.AppliedChecksum
+			}
+			if s.Drift {
+				state = "drift"
+			}
+			if s.Dirty {
+				state = "dirty"
+			}
+			if len(checksum) > 12 {
+				checksum = checksum[:12]
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt, checksum)
+		}
+		return
+	}
+
+	if res.DryRun {
+		// Statements span lines, so they are not aligned.
+		for _, s := range res.Migrations {
+			fmt.Fprintf(os.Stdout, "-- %s %s_%s\n", s.Direction, s.Version, s.Name)
+			if s.Go {
+				fmt.Fprint(os.Stdout, "-- go migration\n\n")
+			}
+			for _, statement := range s.Statements {
+				fmt.Fprintf(os.Stdout, "%s\n\n", statement)
+			}
+		}
+		if res.Error == "" {
+			fmt.Fprintf(os.Stdout, "-- %s: %d migrations planned\n", res.Command, len(res.Migrations))
+		}
+		return
+	}
+
+	for _, s := range res.Migrations {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Direction, s.Version, s.Name)
+	}
+	if res.Error == "" {
+		fmt.Fprintf(w, "%s: %d migrations run\n", res.Command, len(res.Migrations))
+	}
 }
@@ -2,14 +2,31 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
-	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/GTA5-RP-Aristocracy/site-back/api"
+	"github.com/GTA5-RP-Aristocracy/site-back/config"
 	"github.com/GTA5-RP-Aristocracy/site-back/db"
+	"github.com/GTA5-RP-Aristocracy/site-back/db/migrate"
+	"github.com/GTA5-RP-Aristocracy/site-back/health"
+	"github.com/GTA5-RP-Aristocracy/site-back/ledger"
+	"github.com/GTA5-RP-Aristocracy/site-back/metrics"
+	"github.com/GTA5-RP-Aristocracy/site-back/migrations"
+	"github.com/GTA5-RP-Aristocracy/site-back/promo"
+	"github.com/GTA5-RP-Aristocracy/site-back/server"
+	"github.com/GTA5-RP-Aristocracy/site-back/shop"
+	"github.com/GTA5-RP-Aristocracy/site-back/tracing"
 	"github.com/GTA5-RP-Aristocracy/site-back/user"
-	"github.com/caarlos0/env/v11"
+	"github.com/GTA5-RP-Aristocracy/site-back/vip"
+	"github.com/GTA5-RP-Aristocracy/site-back/webhook"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
@@ -21,51 +38,138 @@ func main() {
 	// This is the entry point of the site command.
 	// It starts the web server and listens for incoming requests
 
-	logger := zerolog.New(os.Stderr).With().Timestamp().Logger()
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "path to the YAML config file, the environment variables override it")
+	printConfig := flag.Bool("print-config", false, "print the configuration with the secrets redacted and exit")
+	flag.Parse()
 
-	// Define the configuration options.
-	dbConfig := db.Config{
-		User:     "postgres",
-		Password: "postgres",
-		Host:     "localhost",
-		Database: "gta_site",
+	// Load the configuration, all of its problems are reported at once.
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
 	}
-	err := env.Parse(&dbConfig)
+
+	// The level is validated along with the configuration.
+	level, _ := zerolog.ParseLevel(cfg.Log.Level)
+	logger := zerolog.New(os.Stderr).Level(level).With().Timestamp().Logger()
+	var logLevel slog.Level
+	logLevel.UnmarshalText([]byte(cfg.Log.Level))
+
+	// Stop on SIGINT or SIGTERM.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Trace the requests, the spans of the database queries included.
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, cfg.Log.Version, cfg.Log.Env)
 	if err != nil {
-		logger.Fatal().Err(err).Msg("failed to parse the database configuration")
+		logger.Fatal().Err(err).Msg("failed to set up the tracing")
 	}
 
 	// Connect to the database.
-	db, err := db.ConnectDB(dbConfig.User, dbConfig.Password, dbConfig.Host, dbConfig.Database)
+	db, err := db.ConnectDB(ctx, cfg.DB)
 	if err != nil {
 		logger.Fatal().Err(err).Msg("failed to connect to the database")
 	}
-	defer db.Close()
-
 	logger.Info().Msg("connected to the database")
 
+	// Apply the pending migrations before serving traffic.
+	// Replicas starting together wait for each other on the migration lock.
+	if cfg.DB.Migrate {
+		applied, err := migrate.NewMigrator(db, migrations.Sources()...).Apply(ctx)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to migrate the database")
+		}
+		logger.Info().Int("applied", len(applied)).Msg("migrated the database")
+	}
+
+	// Collect the metrics, the database pool ones included.
+	metricsRegistry := metrics.NewRegistry()
+	metrics.RegisterDB(metricsRegistry, db, cfg.DB.Database)
+	httpMetrics := metrics.NewHTTP(metricsRegistry)
+
 	// Create a new user repository.
 	userRepo := user.NewRepository(db)
 
 	// Create a new user service.
-	userService := user.NewService(userRepo)
+	userService := user.NewService(userRepo, user.NewMetrics(metricsRegistry))
+
+	// Create a new user http handler, validating the requests.
+	userPolicy, err := user.NewPolicy(cfg.User)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to load the user policy")
+	}
+	userHandler := user.NewHandler(userService, userPolicy, cfg.Auth)
+	if cfg.Auth.SessionSecret == "" {
+		logger.Warn().Msg("the user sessions are rejected without the session secret")
+	}
+
+	// Create the VIP subscriptions.
+	vipRepo := vip.NewRepository(db)
+	vipService := vip.NewService(vipRepo, vip.NewLogPublisher(logger), vip.DefaultTiers, cfg.VIP)
+	vipHandler := vip.NewHandler(vipService, cfg.Auth)
+
+	// Expire VIP subscriptions in the background.
+	expirerDone := make(chan struct{})
+	go func() {
+		vip.NewExpirer(vipService, cfg.VIP, logger).Run(ctx)
+		close(expirerDone)
+	}()
 
-	// Create a new user http handler.
-	userHandler := user.NewHandler(userService)
+	// Create the currency ledger.
+	ledgerRepo := ledger.NewRepository(db)
+	ledgerService := ledger.NewService(ledgerRepo)
+	ledgerHandler := ledger.NewHandler(ledgerService, cfg.Auth)
+
+	// Create the promo codes and referrals.
+	promoRepo := promo.NewRepository(db)
+	promoService := promo.NewService(promoRepo, ledgerService, vipService, cfg.Promo)
+	promoHandler := promo.NewHandler(promoService, cfg.Auth)
+
+	// Create the donation store, the fake payment provider is the only one so far.
+	// Without a provider the store and its webhooks aren't served.
+	var (
+		shopHandler    *shop.Handler
+		webhookHandler *webhook.Handler
+	)
+	if cfg.Shop.FakeProvider {
+		shopRepo := shop.NewRepository(db)
+		shopService := shop.NewService(shopRepo, shop.NewFakeProvider(cfg.Shop.FakeAutoCapture), map[shop.ProductKind]shop.Fulfiller{
+			shop.ProductVIP:      vip.NewFulfiller(vipService),
+			shop.ProductCurrency: ledger.NewFulfiller(ledgerService),
+		}, promoService)
+		shopHandler = shop.NewHandler(shopService, cfg.Auth)
+
+		// Create the payment provider webhooks.
+		webhookRepo := webhook.NewRepository(db)
+		webhookService := webhook.NewService(webhookRepo, shopService, cfg.Webhook.Providers()...)
+		webhookHandler = webhook.NewHandler(webhookService, cfg.Auth)
+	} else {
+		logger.Warn().Msg("the shop and the webhooks are disabled without a payment provider")
+	}
 
 	loggerRouter := httplog.NewLogger("gta-site-api", httplog.Options{
 		JSON:     true,
-		LogLevel: slog.LevelDebug,
+		LogLevel: logLevel,
 		Concise:  true,
 		// RequestHeaders:   true,
 		MessageFieldName: "message",
 		// TimeFieldFormat: time.RFC850,
 		Tags: map[string]string{
-			"version": "v0.0.1",
-			"env":     "dev",
+			"version": cfg.Log.Version,
+			"env":     cfg.Log.Env,
 		},
 		QuietDownRoutes: []string{
 			"/",
+			"/healthz",
+			"/readyz",
+			"/metrics",
 		},
 		QuietDownPeriod: 10 * time.Second,
 		// SourceFieldName: "source",
@@ -73,28 +177,71 @@ func main() {
 
 	// Start the web server.
 	r := chi.NewRouter()
+	r.Use(httpMetrics.Middleware)
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(httplog.RequestLogger(loggerRouter))
+	r.Use(tracing.Middleware)
 	r.Use(middleware.Heartbeat("/ping"))
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(middleware.Timeout(cfg.Server.HandlerTimeout))
 	r.Use(cors.Handler(cors.Options{
-		// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
-		AllowedOrigins: []string{"https://*", "http://*"},
+		AllowedOrigins: cfg.CORS.AllowedOrigins,
 		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
-		MaxAge:           300, // Maximum value not ignored by any of major browsers
+		MaxAge:           cfg.CORS.MaxAge,
 	}))
 
+	// The mounted routers inherit the problem responses.
+	r.NotFound(api.NotFound)
+	r.MethodNotAllowed(api.MethodNotAllowed)
+
 	userHandler.RegisterUserRouter(r)
+	vipHandler.RegisterVIPRouter(r)
+	ledgerHandler.RegisterLedgerRouter(r)
+	promoHandler.RegisterPromoRouter(r)
+	if shopHandler != nil {
+		shopHandler.RegisterShopRouter(r)
+		webhookHandler.RegisterWebhookRouter(r)
+	}
 
-	// TODO add signal handling for graceful shutdown
-	logger.Info().Msg("starting the web server")
-	if err := http.ListenAndServe(":8080", r); err != nil {
-		logger.Fatal().Err(err).Msg("failed to start the web server")
+	srv := server.New(cfg.Server, r, logger)
+
+	// The orchestrator stops routing to the replica which isn't ready and restarts the dead one.
+	healthRegistry := health.NewRegistry(cfg.Health)
+	healthRegistry.Register("server", health.CheckerFunc(func(ctx context.Context) error {
+		if !srv.Ready() {
+			return errors.New("the web server is shutting down")
+		}
+		return nil
+	}))
+	healthRegistry.Register("database", health.Ping(db))
+	healthRegistry.Register("migrations", health.CheckerFunc(migrate.NewMigrator(db, migrations.Sources()...).Verify))
+	health.NewHandler(healthRegistry).RegisterHealthRouter(r)
+	r.Handle("/metrics", metrics.Handler(metricsRegistry))
+
+	logger.Info().Str("addr", cfg.Server.Addr).Msg("starting the web server")
+	err = srv.Run(ctx)
+
+	// The background jobs stop along with the server, then the pool is closed.
+	stop()
+	<-expirerDone
+	if closeErr := db.Close(); closeErr != nil {
+		logger.Error().Err(closeErr).Msg("failed to close the database")
+	}
+
+	// The spans left are flushed, the signal context is done by now.
+	tracingCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	if tracingErr := shutdownTracing(tracingCtx); tracingErr != nil {
+		logger.Error().Err(tracingErr).Msg("failed to flush the spans")
+	}
+	cancel()
+
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to run the web server")
 	}
+	logger.Info().Msg("the web server stopped")
 }
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/config"
+	"github.com/GTA5-RP-Aristocracy/site-back/db"
+	"github.com/GTA5-RP-Aristocracy/site-back/ledger"
+	"github.com/GTA5-RP-Aristocracy/site-back/shop"
+	"github.com/GTA5-RP-Aristocracy/site-back/vip"
+	"github.com/GTA5-RP-Aristocracy/site-back/webhook"
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// This command helps admins with payment provider webhooks:
+//
+//	webhook replay -id <event id>       processes a stored event again
+//	webhook replay -failed [-limit N]   processes all failed events again
+//	webhook simulate -order <order id>  sends a signed fake provider event to the site
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal().Msg("missing command, expected replay or simulate")
+	}
+
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "replay":
+		replay(args)
+	case "simulate":
+		simulate(args)
+	default:
+		log.Fatal().Str("command", command).Msg("invalid command, expected replay or simulate")
+	}
+}
+
+// replay processes stored events again.
+func replay(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	id := flags.String("id", "", "id of the stored event to replay")
+	failed := flags.Bool("failed", false, "replay all failed events")
+	limit := flags.Int("limit", 100, "maximum number of failed events to replay")
+	flags.Parse(args)
+
+	if (*id == "") == !*failed {
+		log.Fatal().Msg("either -id or -failed is required")
+	}
+
+	// The configuration is shared with the site command.
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatal().Err(err).Msg("load the configuration")
+	}
+
+	dbInstance, err := db.ConnectDB(context.Background(), cfg.DB)
+	if err != nil {
+		log.Fatal().Err(err).Msg("connect to the database")
+	}
+	defer dbInstance.Close()
+
+	// Replayed payments fulfill orders just like the site does.
+	// They don't place orders, so no promo codes are applied.
+	vipService := vip.NewService(vip.NewRepository(dbInstance), vip.NewLogPublisher(log.Logger), vip.DefaultTiers, cfg.VIP)
+	ledgerService := ledger.NewService(ledger.NewRepository(dbInstance))
+	shopService := shop.NewService(shop.NewRepository(dbInstance), shop.NewFakeProvider(false), map[shop.ProductKind]shop.Fulfiller{
+		shop.ProductVIP:      vip.NewFulfiller(vipService),
+		shop.ProductCurrency: ledger.NewFulfiller(ledgerService),
+	}, nil)
+	service := webhook.NewService(webhook.NewRepository(dbInstance), shopService, cfg.Webhook.Providers()...)
+
+	ctx := context.Background()
+	var records []webhook.Record
+	if *failed {
+		records, err = service.ReplayFailed(ctx, *limit)
+	} else {
+		var eventID uuid.UUID
+		eventID, err = uuid.Parse(*id)
+		if err != nil {
+			log.Fatal().Err(err).Msg("parse event id")
+		}
+
+		// Processing errors are stored in the record.
+		var record webhook.Record
+		record, err = service.Replay(ctx, eventID)
+		if record.ID != uuid.Nil {
+			records, err = append(records, record), nil
+		}
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("replay events")
+	}
+
+	for _, record := range records {
+		log.Info().
+			Str("id", record.ID.String()).
+			Str("provider", record.Provider).
+			Str("event_id", record.EventID).
+			Str("status", string(record.Status)).
+			Str("error", record.Error).
+			Msg("replayed")
+	}
+	log.Info().Int("count", len(records)).Msg("events replayed")
+}
+
+// simulate sends a signed fake provider event to the site.
+func simulate(args []string) {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	url := flags.String("url", "http://localhost:8080/webhooks/"+shop.FakeProviderName, "webhook url of the site")
+	secret := flags.String("secret", os.Getenv("WEBHOOK_FAKE_SECRET"), "signing secret, defaults to $WEBHOOK_FAKE_SECRET")
+	eventID := flags.String("event", "", "provider event id, a random one by default")
+	eventType := flags.String("type", string(webhook.EventPaymentSucceeded), "event type")
+	orderID := flags.String("order", "", "id of the paid order")
+	reference := flags.String("reference", "", "payment reference, the fake provider one by default")
+	amount := flags.Int64("amount", 0, "paid amount in minor units")
+	currency := flags.String("currency", "RUB", "paid currency")
+	flags.Parse(args)
+
+	var payload webhook.FakePayload
+	payload.ID = *eventID
+	if payload.ID == "" {
+		payload.ID = "evt_" + uuid.NewString()
+	}
+	payload.Type = webhook.EventType(*eventType)
+	payload.Created = time.Now().Unix()
+
+	var err error
+	payload.Data.OrderID, err = uuid.Parse(*orderID)
+	if err != nil {
+		log.Fatal().Err(err).Msg("parse order id")
+	}
+	payload.Data.Reference = *reference
+	if payload.Data.Reference == "" {
+		payload.Data.Reference = "fake_" + payload.Data.OrderID.String()
+	}
+	payload.Data.Amount = *amount
+	payload.Data.Currency = *currency
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Fatal().Err(err).Msg("encode payload")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, *url, bytes.NewReader(body))
+	if err != nil {
+		log.Fatal().Err(err).Msg("create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.SignatureHeader, webhook.NewFakeProvider(*secret).Sign(body, time.Now()))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatal().Err(err).Msg("send event")
+	}
+	defer resp.Body.Close()
+
+	response, _ := io.ReadAll(resp.Body)
+	log.Info().
+		Str("event_id", payload.ID).
+		Int("status", resp.StatusCode).
+		Str("response", string(bytes.TrimSpace(response))).
+		Msg(fmt.Sprintf("event %s sent", payload.Type))
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/auth"
+	"github.com/GTA5-RP-Aristocracy/site-back/db"
+	"github.com/GTA5-RP-Aristocracy/site-back/health"
+	"github.com/GTA5-RP-Aristocracy/site-back/promo"
+	"github.com/GTA5-RP-Aristocracy/site-back/server"
+	"github.com/GTA5-RP-Aristocracy/site-back/shop"
+	"github.com/GTA5-RP-Aristocracy/site-back/tracing"
+	"github.com/GTA5-RP-Aristocracy/site-back/user"
+	"github.com/GTA5-RP-Aristocracy/site-back/vip"
+	"github.com/GTA5-RP-Aristocracy/site-back/webhook"
+	"github.com/caarlos0/env/v11"
+	"gopkg.in/yaml.v3"
+)
+
+// This file contains the application configuration loaded from the environment and a file.
+
+type (
+	// Config represents the configuration of the whole application.
+	Config struct {
+		Server  server.Config  `yaml:"server"`
+		DB      db.Config      `yaml:"db"`
+		CORS    CORSConfig     `yaml:"cors"`
+		Log     LogConfig      `yaml:"log"`
+		Health  health.Config  `yaml:"health"`
+		Tracing tracing.Config `yaml:"tracing"`
+		Auth    auth.Config    `yaml:"auth"`
+		User    user.Config    `yaml:"user"`
+		Shop    shop.Config    `yaml:"shop"`
+		Webhook webhook.Config `yaml:"webhook"`
+		VIP     vip.Config     `yaml:"vip"`
+		Promo   promo.Config   `yaml:"promo"`
+	}
+
+	// CORSConfig represents the cross-origin request options.
+	CORSConfig struct {
+		// AllowedOrigins are the origins allowed to call the API, wildcards are allowed.
+		AllowedOrigins []string `env:"CORS_ALLOWED_ORIGINS" envDefault:"https://*,http://*" yaml:"allowed_origins"`
+		// MaxAge is how long the browsers cache the preflight responses, in seconds.
+		MaxAge int `env:"CORS_MAX_AGE" envDefault:"300" yaml:"max_age"`
+	}
+
+	// LogConfig represents the logging options.
+	LogConfig struct {
+		// Level is one of debug, info, warn and error.
+		Level string `env:"LOG_LEVEL" envDefault:"info" yaml:"level"`
+		// Version and Env tag the request logs.
+		Version string `env:"APP_VERSION" envDefault:"v0.0.1" yaml:"version"`
+		Env     string `env:"APP_ENV" envDefault:"dev" yaml:"env"`
+	}
+)
+
+// Load loads the configuration. The defaults are overridden by the YAML file, if any,
+// which is overridden by the environment variables. The result is validated.
+func Load(file string) (Config, error) {
+	// The defaults alone.
+	var cfg Config
+	if err := env.ParseWithOptions(&cfg, env.Options{Environment: map[string]string{}}); err != nil {
+		return Config{}, fmt.Errorf("parse defaults: %w", err)
+	}
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return Config{}, fmt.Errorf("read config file: %w", err)
+		}
+
+		// Unknown keys are most likely typos.
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return Config{}, fmt.Errorf("parse config file %s: %w", file, err)
+		}
+	}
+
+	// The environment is parsed on its own, since the defaults would override the file,
+	// and only the variables which are set are copied over.
+	var fromEnv Config
+	set := map[string]bool{}
+	err := env.ParseWithOptions(&fromEnv, env.Options{
+		OnSet: func(tag string, value any, isDefault bool) {
+			if !isDefault {
+				set[tag] = true
+			}
+		},
+	})
+	if err != nil {
+		return Config{}, fmt.Errorf("parse environment: %w", err)
+	}
+	overlay(reflect.ValueOf(&cfg).Elem(), reflect.ValueOf(fromEnv), set)
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// overlay copies the fields of the environment variables which are set from src to dst.
+func overlay(dst, src reflect.Value, set map[string]bool) {
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		if key, ok := field.Tag.Lookup("env"); ok {
+			if set[key] {
+				dst.Field(i).Set(src.Field(i))
+			}
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			overlay(dst.Field(i), src.Field(i), set)
+		}
+	}
+}
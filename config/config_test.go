@@ -0,0 +1,122 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile writes the config file to a temporary directory.
+func writeFile(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+	return file
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load("")
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8080", cfg.Server.Addr)
+	assert.Equal(t, 60*time.Second, cfg.Server.HandlerTimeout)
+	assert.Equal(t, "localhost", cfg.DB.Host)
+	assert.Equal(t, []string{"https://*", "http://*"}, cfg.CORS.AllowedOrigins)
+	assert.Equal(t, "dev", cfg.Log.Env)
+	// The fake payment provider is opt-in.
+	assert.False(t, cfg.Shop.FakeProvider)
+	assert.False(t, cfg.Shop.FakeAutoCapture)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	file := writeFile(t, `
+server:
+  addr: ":9090"
+  handler_timeout: 30s
+db:
+  host: db.internal
+  port: 6432
+cors:
+  allowed_origins: ["https://example.com"]
+shop:
+  fake_provider: true
+  fake_auto_capture: true
+`)
+	t.Setenv("DB_HOST", "db.override")
+	t.Setenv("LOG_LEVEL", "debug")
+
+	cfg, err := Load(file)
+	require.NoError(t, err)
+
+	// The file overrides the defaults.
+	assert.Equal(t, ":9090", cfg.Server.Addr)
+	assert.Equal(t, 30*time.Second, cfg.Server.HandlerTimeout)
+	assert.Equal(t, 6432, cfg.DB.Port)
+	assert.Equal(t, []string{"https://example.com"}, cfg.CORS.AllowedOrigins)
+	assert.True(t, cfg.Shop.FakeAutoCapture)
+	// The environment overrides the file.
+	assert.Equal(t, "db.override", cfg.DB.Host)
+	assert.Equal(t, "debug", cfg.Log.Level)
+	// The rest keeps the defaults.
+	assert.Equal(t, "gta_site", cfg.DB.Database)
+	assert.Equal(t, 75*time.Second, cfg.Server.WriteTimeout)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	t.Run("UnknownKey", func(t *testing.T) {
+		_, err := Load(writeFile(t, "db:\n  hots: localhost\n"))
+		assert.ErrorContains(t, err, "hots")
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("AllProblems", func(t *testing.T) {
+		t.Setenv("HTTP_WRITE_TIMEOUT", "10s")
+		t.Setenv("DB_SSLMODE", "sometimes")
+		t.Setenv("LOG_LEVEL", "verbose")
+		t.Setenv("ADMIN_TOKEN", "short")
+		t.Setenv("SESSION_SECRET", "short")
+		t.Setenv("TRACING_EXPORTER", "jaeger")
+		t.Setenv("TRACING_SAMPLE_RATIO", "2")
+		t.Setenv("APP_ENV", "production")
+		t.Setenv("SHOP_FAKE_PROVIDER", "true")
+
+		_, err := Load("")
+		require.Error(t, err)
+		for _, key := range []string{"server.write_timeout", "db.ssl_mode", "log.level", "auth.admin_token", "auth.session_secret", "tracing.exporter", "tracing.sample_ratio", "shop.fake_provider"} {
+			assert.ErrorContains(t, err, key)
+		}
+	})
+}
+
+func TestConfig_Print(t *testing.T) {
+	t.Setenv("DB_PASS", "hunter2")
+	t.Setenv("ADMIN_TOKEN", "0123456789abcdef")
+
+	cfg, err := Load("")
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, cfg.Print(&out))
+
+	assert.NotContains(t, out.String(), "hunter2")
+	assert.NotContains(t, out.String(), "0123456789abcdef")
+	assert.Contains(t, out.String(), "password: "+redacted)
+	// The secrets which aren't set stay empty.
+	assert.Contains(t, out.String(), `server_token: ""`)
+	// The printed configuration is the same.
+	assert.Equal(t, "hunter2", cfg.DB.Password)
+
+	// It loads back as a config file.
+	t.Setenv("DB_PASS", "")
+	printed, err := Load(writeFile(t, out.String()))
+	require.NoError(t, err)
+	assert.Equal(t, cfg.Server, printed.Server)
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// This file contains the printing of the configuration with the secrets redacted.
+
+// redacted replaces the secrets which are set.
+const redacted = "REDACTED"
+
+// Print writes the configuration as YAML, the fields tagged secret are redacted.
+// The output is a valid config file.
+func (c Config) Print(w io.Writer) error {
+	redact(reflect.ValueOf(&c).Elem())
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// redact replaces the secret strings of the struct, empty ones stay empty
+// to show they aren't set.
+func redact(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		switch {
+		case field.Tag.Get("secret") == "true" && field.Type.Kind() == reflect.String:
+			if v.Field(i).String() != "" {
+				v.Field(i).SetString(redacted)
+			}
+		case field.Type.Kind() == reflect.Struct:
+			redact(v.Field(i))
+		}
+	}
+}
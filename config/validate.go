@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/tracing"
+)
+
+// This file contains the validation of the configuration.
+
+var (
+	// sslModes are the SSL modes supported by the database driver.
+	sslModes = []string{"disable", "require", "verify-ca", "verify-full"}
+	// logLevels are the supported log levels.
+	logLevels = []string{"debug", "info", "warn", "error"}
+)
+
+const (
+	// devEnv is the env of the local development.
+	devEnv = "dev"
+	// minTokenLength is the minimum length of the auth tokens, so they can't be guessed.
+	minTokenLength = 16
+	// minSecretLength is the minimum length of the session secret.
+	minSecretLength = 32
+	// minPasswordLength is the least password length the policy may require.
+	minPasswordLength = 8
+)
+
+// problems collects the configuration problems.
+type problems []error
+
+// check adds the problem of the key unless ok.
+func (p *problems) check(ok bool, key, format string, args ...any) {
+	if !ok {
+		*p = append(*p, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+}
+
+// positive checks that the duration is positive.
+func (p *problems) positive(d time.Duration, key string) {
+	p.check(d > 0, key, "must be positive, got %s", d)
+}
+
+// Validate reports all the problems of the configuration at once.
+func (c Config) Validate() error {
+	var p problems
+
+	p.check(c.Server.Addr != "", "server.addr", "is required")
+	p.positive(c.Server.ReadTimeout, "server.read_timeout")
+	p.positive(c.Server.ReadHeaderTimeout, "server.read_header_timeout")
+	p.positive(c.Server.WriteTimeout, "server.write_timeout")
+	p.positive(c.Server.IdleTimeout, "server.idle_timeout")
+	p.positive(c.Server.HandlerTimeout, "server.handler_timeout")
+	p.positive(c.Server.ShutdownTimeout, "server.shutdown_timeout")
+	p.check(c.Server.WriteTimeout > c.Server.HandlerTimeout, "server.write_timeout",
+		"must be longer than server.handler_timeout, so the timed out requests get a response")
+	p.check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay", "must not be negative")
+	p.check(c.Server.MaxHeaderBytes > 0, "server.max_header_bytes", "must be positive")
+
+	if c.DB.URL == "" {
+		p.check(c.DB.Host != "", "db.host", "is required without db.url")
+		p.check(c.DB.User != "", "db.user", "is required without db.url")
+		p.check(c.DB.Database != "", "db.database", "is required without db.url")
+		p.check(c.DB.Port > 0 && c.DB.Port < 1<<16, "db.port", "must be a valid port, got %d", c.DB.Port)
+		p.check(slices.Contains(sslModes, c.DB.SSLMode), "db.ssl_mode", "must be one of %v, got %q", sslModes, c.DB.SSLMode)
+	}
+	p.check(c.DB.StatementTimeout >= 0, "db.statement_timeout", "must not be negative")
+	p.check(c.DB.MaxOpenConns >= 0, "db.max_open_conns", "must not be negative")
+	p.check(c.DB.MaxIdleConns >= 0, "db.max_idle_conns", "must not be negative")
+	p.check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "db.max_idle_conns",
+		"must not exceed db.max_open_conns")
+	p.check(c.DB.ConnMaxLifetime >= 0, "db.conn_max_lifetime", "must not be negative")
+	p.check(c.DB.ConnMaxIdleTime >= 0, "db.conn_max_idle_time", "must not be negative")
+	p.check(c.DB.ConnectTimeout >= 0, "db.connect_timeout", "must not be negative")
+
+	p.check(len(c.CORS.AllowedOrigins) > 0, "cors.allowed_origins", "is required")
+	p.check(c.CORS.MaxAge >= 0, "cors.max_age", "must not be negative")
+
+	p.positive(c.Health.Timeout, "health.timeout")
+
+	p.check(slices.Contains(tracing.Exporters, c.Tracing.Exporter), "tracing.exporter",
+		"must be one of %v, got %q", tracing.Exporters, c.Tracing.Exporter)
+	p.check(c.Tracing.ServiceName != "", "tracing.service_name", "is required")
+	p.check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio",
+		"must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+
+	p.check(slices.Contains(logLevels, c.Log.Level), "log.level", "must be one of %v, got %q", logLevels, c.Log.Level)
+
+	// The endpoints of an empty token stay closed.
+	p.check(c.Auth.ServerToken == "" || len(c.Auth.ServerToken) >= minTokenLength, "auth.server_token",
+		"must be at least %d characters long", minTokenLength)
+	p.check(c.Auth.AdminToken == "" || len(c.Auth.AdminToken) >= minTokenLength, "auth.admin_token",
+		"must be at least %d characters long", minTokenLength)
+	// The user sessions are rejected without the secret.
+	p.check(c.Auth.SessionSecret == "" || len(c.Auth.SessionSecret) >= minSecretLength, "auth.session_secret",
+		"must be at least %d characters long", minSecretLength)
+	p.positive(c.Auth.SessionTTL, "auth.session_ttl")
+
+	p.check(c.User.NameMinLength > 0, "user.name_min_length", "must be positive")
+	p.check(c.User.NameMaxLength >= c.User.NameMinLength, "user.name_max_length", "must not be less than user.name_min_length")
+	p.check(c.User.PasswordMinLength >= minPasswordLength, "user.password_min_length", "must be at least %d", minPasswordLength)
+	p.check(c.User.PasswordMaxLength >= c.User.PasswordMinLength, "user.password_max_length",
+		"must not be less than user.password_min_length")
+
+	p.positive(c.VIP.ExpireInterval, "vip.expire_interval")
+	p.check(c.VIP.GracePeriod >= 0, "vip.grace_period", "must not be negative")
+
+	p.check(c.Promo.ReferralPlaytime >= 0, "promo.referral_playtime", "must not be negative")
+	p.check(c.Promo.ReferralCurrency >= 0, "promo.referral_currency", "must not be negative")
+	p.check(c.Promo.ReferralVIPTier == "" || c.Promo.ReferralVIPDays > 0, "promo.referral_vip_days",
+		"must be positive with promo.referral_vip_tier")
+
+	// The fake payment provider gives everything away for free.
+	p.check(!c.Shop.FakeProvider || c.Log.Env == devEnv, "shop.fake_provider",
+		"is allowed in the %s env only, got %q", devEnv, c.Log.Env)
+	p.check(c.Shop.FakeProvider || !c.Shop.FakeAutoCapture, "shop.fake_auto_capture", "requires shop.fake_provider")
+
+	if len(p) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(p...))
+	}
+	return nil
+}
@@ -1,11 +1,86 @@
 package db
 
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
 type (
 	// Config represents the configuration options for the database.
 	Config struct {
-		User     string `env:"DB_USER"`
-		Password string `env:"DB_PASS"`
-		Host     string `env:"DB_HOST"`
-		Database string `env:"DB_NAME"`
+		// URL is a full connection string, it overrides the connection options below.
+		URL      string `env:"DB_URL" yaml:"url" secret:"true"`
+		User     string `env:"DB_USER" envDefault:"postgres" yaml:"user"`
+		Password string `env:"DB_PASS" envDefault:"postgres" yaml:"password" secret:"true"`
+		Host     string `env:"DB_HOST" envDefault:"localhost" yaml:"host"`
+		Port     int    `env:"DB_PORT" envDefault:"5432" yaml:"port"`
+		Database string `env:"DB_NAME" envDefault:"gta_site" yaml:"database"`
+
+		// SSLMode is one of disable, require, verify-ca and verify-full.
+		SSLMode string `env:"DB_SSLMODE" envDefault:"disable" yaml:"ssl_mode"`
+		// SSLRootCert, SSLCert and SSLKey are the paths of the certificates.
+		SSLRootCert string `env:"DB_SSLROOTCERT" yaml:"ssl_root_cert"`
+		SSLCert     string `env:"DB_SSLCERT" yaml:"ssl_cert"`
+		SSLKey      string `env:"DB_SSLKEY" yaml:"ssl_key"`
+
+		// ApplicationName tells the connections apart in pg_stat_activity.
+		ApplicationName string `env:"DB_APPLICATION_NAME" envDefault:"site-back" yaml:"application_name"`
+		// StatementTimeout aborts the statements running longer, zero disables it.
+		StatementTimeout time.Duration `env:"DB_STATEMENT_TIMEOUT" yaml:"statement_timeout"`
+
+		// MaxOpenConns and MaxIdleConns limit the connection pool, zero means no limit
+		// for the open connections and the database/sql default for the idle ones.
+		MaxOpenConns int `env:"DB_MAX_OPEN_CONNS" envDefault:"25" yaml:"max_open_conns"`
+		MaxIdleConns int `env:"DB_MAX_IDLE_CONNS" envDefault:"5" yaml:"max_idle_conns"`
+		// ConnMaxLifetime and ConnMaxIdleTime close the old and the unused connections.
+		ConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" envDefault:"30m" yaml:"conn_max_lifetime"`
+		ConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" envDefault:"5m" yaml:"conn_max_idle_time"`
+
+		// ConnectTimeout is how long the initial ping is retried, so the services
+		// may start before the database is ready.
+		ConnectTimeout time.Duration `env:"DB_CONNECT_TIMEOUT" envDefault:"30s" yaml:"connect_timeout"`
+
+		// Migrate applies the embedded migrations on startup.
+		Migrate bool `env:"DB_MIGRATE" yaml:"migrate"`
 	}
 )
+
+// DSN returns the connection string, either the URL or the one built from the options.
+func (c Config) DSN() string {
+	if c.URL != "" {
+		return c.URL
+	}
+
+	query := url.Values{}
+	query.Set("sslmode", c.SSLMode)
+	for key, value := range map[string]string{
+		"sslrootcert":      c.SSLRootCert,
+		"sslcert":          c.SSLCert,
+		"sslkey":           c.SSLKey,
+		"application_name": c.ApplicationName,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	// The unknown keys are sent to the server as the session settings.
+	if c.StatementTimeout > 0 {
+		query.Set("statement_timeout", strconv.FormatInt(c.StatementTimeout.Milliseconds(), 10))
+	}
+
+	host := c.Host
+	if c.Port != 0 {
+		host = net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     host,
+		Path:     "/" + c.Database,
+		RawQuery: query.Encode(),
+	}
+	return dsn.String()
+}
@@ -1,21 +1,66 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// minBackoff and maxBackoff bound the delay between the connection attempts.
+	minBackoff = 100 * time.Millisecond
+	maxBackoff = 5 * time.Second
 )
 
-func ConnectDB(user, pass, host, database string) (*sql.DB, error) {
+// ConnectDB opens the connection pool and waits until the database answers the ping.
+// The queries are traced with the global tracer provider.
+func ConnectDB(ctx context.Context, cfg Config) (*sql.DB, error) {
 	// Connect to the database.
-	db, err := sql.Open("postgres", fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", user, pass, host, database))
+	connector, err := pq.NewConnector(cfg.DSN())
 	if err != nil {
 		return nil, err
 	}
+	db := sql.OpenDB(traceConnector(connector))
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	// Check if the connection is successful.
-	if err := db.Ping(); err != nil {
+	if err := pingWithRetry(ctx, db.PingContext, cfg.ConnectTimeout); err != nil {
+		db.Close()
 		return nil, err
 	}
 
 	return db, nil
 }
+
+// pingWithRetry pings until it succeeds or the timeout runs out, doubling the delay
+// between the attempts. The last ping error is returned. Without a timeout it pings once.
+func pingWithRetry(ctx context.Context, ping func(ctx context.Context) error, timeout time.Duration) error {
+	if timeout <= 0 {
+		return ping(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := minBackoff
+	for attempt := 1; ; attempt++ {
+		err := ping(ctx)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("ping the database after %d attempts: %w", attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
@@ -0,0 +1,221 @@
+package migrate
+
+// This file contains the schema snapshot read from the database catalog.
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type (
+	// schema represents the objects of the public schema.
+	schema struct {
+		tables    []table
+		indexes   []string
+		functions []string
+		triggers  []string
+	}
+
+	// table represents a table with its columns and constraints.
+	table struct {
+		name        string
+		columns     []column
+		constraints []constraint
+	}
+
+	// column represents a table column.
+	column struct {
+		name       string
+		typ        string
+		notNull    bool
+		defaultSQL string
+	}
+
+	// constraint represents a table constraint.
+	constraint struct {
+		name       string
+		definition string
+	}
+)
+
+// DumpSchema returns the definitions of the tables, indexes, functions and triggers
+// of the public schema, so the schema changes can be reviewed as a diff of the snapshot.
+// The migration history table is left out. The objects are sorted, so the output
+// only changes along with the schema.
+func DumpSchema(ctx context.Context, db *sql.DB) (string, error) {
+	s, err := readSchema(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	return formatSchema(s), nil
+}
+
+// readSchema reads the public schema from the catalog.
+func readSchema(ctx context.Context, q querier) (schema, error) {
+	var s schema
+
+	tables := map[string]*table{}
+	err := queryEach(ctx, q, `SELECT c.relname FROM pg_class c
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = 'public' AND c.relkind IN ('r', 'p') AND c.relname <> 'schema_migrations'`,
+		func(rows *sql.Rows) error {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			tables[name] = &table{name: name}
+			return nil
+		})
+	if err != nil {
+		return schema{}, fmt.Errorf("read tables: %w", err)
+	}
+
+	err = queryEach(ctx, q, `SELECT c.relname, a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull, COALESCE(pg_get_expr(d.adbin, d.adrelid), '')
+FROM pg_attribute a
+JOIN pg_class c ON c.oid = a.attrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE n.nspname = 'public' AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY c.relname, a.attnum`,
+		func(rows *sql.Rows) error {
+			var (
+				tableName string
+				col       column
+			)
+			if err := rows.Scan(&tableName, &col.name, &col.typ, &col.notNull, &col.defaultSQL); err != nil {
+				return err
+			}
+			if t, ok := tables[tableName]; ok {
+				t.columns = append(t.columns, col)
+			}
+			return nil
+		})
+	if err != nil {
+		return schema{}, fmt.Errorf("read columns: %w", err)
+	}
+
+	err = queryEach(ctx, q, `SELECT c.relname, con.conname, pg_get_constraintdef(con.oid)
+FROM pg_constraint con
+JOIN pg_class c ON c.oid = con.conrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = 'public'`,
+		func(rows *sql.Rows) error {
+			var (
+				tableName string
+				con       constraint
+			)
+			if err := rows.Scan(&tableName, &con.name, &con.definition); err != nil {
+				return err
+			}
+			if t, ok := tables[tableName]; ok {
+				t.constraints = append(t.constraints, con)
+			}
+			return nil
+		})
+	if err != nil {
+		return schema{}, fmt.Errorf("read constraints: %w", err)
+	}
+
+	// The indexes backing the constraints are part of the constraint definitions.
+	err = queryEach(ctx, q, `SELECT i.indexdef FROM pg_indexes i
+WHERE i.schemaname = 'public' AND i.tablename <> 'schema_migrations'
+AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conname = i.indexname AND con.connamespace = 'public'::regnamespace)`,
+		scanInto(&s.indexes))
+	if err != nil {
+		return schema{}, fmt.Errorf("read indexes: %w", err)
+	}
+
+	err = queryEach(ctx, q, `SELECT pg_get_functiondef(p.oid) FROM pg_proc p
+JOIN pg_namespace n ON n.oid = p.pronamespace
+WHERE n.nspname = 'public' AND p.prokind IN ('f', 'p')`,
+		scanInto(&s.functions))
+	if err != nil {
+		return schema{}, fmt.Errorf("read functions: %w", err)
+	}
+
+	err = queryEach(ctx, q, `SELECT pg_get_triggerdef(t.oid) FROM pg_trigger t
+JOIN pg_class c ON c.oid = t.tgrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = 'public' AND NOT t.tgisinternal`,
+		scanInto(&s.triggers))
+	if err != nil {
+		return schema{}, fmt.Errorf("read triggers: %w", err)
+	}
+
+	for _, t := range tables {
+		s.tables = append(s.tables, *t)
+	}
+	return s, nil
+}
+
+// queryEach runs the query and calls scan for each row.
+func queryEach(ctx context.Context, q querier, query string, scan func(rows *sql.Rows) error) error {
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// scanInto appends the single string column of each row to the list.
+func scanInto(list *[]string) func(rows *sql.Rows) error {
+	return func(rows *sql.Rows) error {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return err
+		}
+		*list = append(*list, value)
+		return nil
+	}
+}
+
+// formatSchema writes the schema as SQL. The tables, constraints and the other objects
+// are sorted by name, while the columns keep the table order.
+func formatSchema(s schema) string {
+	var b strings.Builder
+	b.WriteString("-- This file is generated by the migrate dump command, don't edit it.\n")
+
+	tables := append([]table(nil), s.tables...)
+	sort.Slice(tables, func(i, j int) bool { return tables[i].name < tables[j].name })
+	for _, t := range tables {
+		lines := make([]string, 0, len(t.columns)+len(t.constraints))
+		for _, c := range t.columns {
+			line := "    " + c.name + " " + c.typ
+			if c.notNull {
+				line += " NOT NULL"
+			}
+			if c.defaultSQL != "" {
+				line += " DEFAULT " + c.defaultSQL
+			}
+			lines = append(lines, line)
+		}
+
+		constraints := append([]constraint(nil), t.constraints...)
+		sort.Slice(constraints, func(i, j int) bool { return constraints[i].name < constraints[j].name })
+		for _, c := range constraints {
+			lines = append(lines, "    CONSTRAINT "+c.name+" "+c.definition)
+		}
+
+		fmt.Fprintf(&b, "\nCREATE TABLE %s (\n%s\n);\n", t.name, strings.Join(lines, ",\n"))
+	}
+
+	for _, objects := range [][]string{s.indexes, s.functions, s.triggers} {
+		sorted := append([]string(nil), objects...)
+		sort.Strings(sorted)
+		for _, definition := range sorted {
+			fmt.Fprintf(&b, "\n%s;\n", strings.TrimRight(strings.TrimSpace(definition), ";"))
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSchema(t *testing.T) {
+	s := schema{
+		tables: []table{
+			{
+				name: "users",
+				columns: []column{
+					{name: "uuid", typ: "uuid", notNull: true},
+					{name: "email", typ: "character varying(255)", notNull: true},
+					{name: "created", typ: "timestamp without time zone", notNull: true, defaultSQL: "now()"},
+				},
+				constraints: []constraint{
+					{name: "users_pkey", definition: "PRIMARY KEY (uuid)"},
+					{name: "users_email_key", definition: "UNIQUE (email)"},
+				},
+			},
+			{
+				name:    "news",
+				columns: []column{{name: "title", typ: "text"}},
+			},
+		},
+		indexes: []string{
+			"CREATE INDEX users_created_index ON public.users USING btree (created)",
+			"CREATE INDEX news_title_index ON public.news USING btree (title)",
+		},
+		functions: []string{"CREATE OR REPLACE FUNCTION public.touch()\n RETURNS trigger\n LANGUAGE plpgsql\nAS $function$BEGIN RETURN NEW; END$function$\n"},
+		triggers:  []string{"CREATE TRIGGER users_touch BEFORE UPDATE ON public.users FOR EACH ROW EXECUTE FUNCTION touch()"},
+	}
+
+	expected := `-- This file is generated by the migrate dump command, don't edit it.
+
+CREATE TABLE news (
+    title text
+);
+
+CREATE TABLE users (
+    uuid uuid NOT NULL,
+    email character varying(255) NOT NULL,
+    created timestamp without time zone NOT NULL DEFAULT now(),
+    CONSTRAINT users_email_key UNIQUE (email),
+    CONSTRAINT users_pkey PRIMARY KEY (uuid)
+);
+
+CREATE INDEX news_title_index ON public.news USING btree (title);
+
+CREATE INDEX users_created_index ON public.users USING btree (created);
+
+CREATE OR REPLACE FUNCTION public.touch()
+ RETURNS trigger
+ LANGUAGE plpgsql
+AS $function$BEGIN RETURN NEW; END$function$;
+
+CREATE TRIGGER users_touch BEFORE UPDATE ON public.users FOR EACH ROW EXECUTE FUNCTION touch();
+`
+	assert.Equal(t, expected, formatSchema(s))
+
+	// The input order doesn't matter.
+	s.tables[0], s.tables[1] = s.tables[1], s.tables[0]
+	assert.Equal(t, expected, formatSchema(s))
+}
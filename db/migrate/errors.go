@@ -0,0 +1,20 @@
+package migrate
+
+// This file contains migration related errors.
+
+import "errors"
+
+// Define custom errors.
+var (
+	ErrInvalidName      = errors.New("migrate: invalid migration file name")
+	ErrDuplicateVersion = errors.New("migrate: duplicate migration version")
+	ErrMissingPair      = errors.New("migrate: missing migration file")
+	ErrUnknownVersion   = errors.New("migrate: unknown migration version")
+	ErrNothingApplied   = errors.New("migrate: no migration has been applied")
+	ErrSchemaAhead      = errors.New("migrate: database has migrations unknown to this build")
+	ErrChecksumMismatch = errors.New("migrate: applied migration has been changed, restore it or force the version")
+	ErrDirty            = errors.New("migrate: a migration failed halfway, fix the schema and force the version")
+	ErrPending          = errors.New("migrate: database lacks migrations of this build")
+	ErrInvalidCount     = errors.New("migrate: number of migrations must be positive")
+	ErrHistoryExists    = errors.New("migrate: database has applied migrations already, use force to change them")
+)
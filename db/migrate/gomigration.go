@@ -0,0 +1,122 @@
+package migrate
+
+// This file contains the migrations written in Go.
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+type (
+	// GoMigrationFunc migrates the data which can't be migrated with SQL alone.
+	// It runs in the transaction which records it in the history.
+	GoMigrationFunc func(ctx context.Context, tx *sql.Tx) error
+
+	// goMigration represents a registered Go migration.
+	goMigration struct {
+		name     string
+		up, down GoMigrationFunc
+	}
+)
+
+var (
+	// registry holds the registered Go migrations by version.
+	registry = map[time.Time]goMigration{}
+
+	// goTemplate is the stub of a new Go migration.
+	goTemplate = template.Must(template.New("migration").Parse(`package {{.Package}}
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/db/migrate"
+)
+
+// The package must be imported by the binaries running the migrations.
+func init() {
+	migrate.Register("{{.Version}}", "{{.Name}}", up{{.Func}}, down{{.Func}})
+}
+
+func up{{.Func}}(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
+
+// down{{.Func}} may be replaced with nil if the migration can't be rolled back.
+func down{{.Func}}(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
+`))
+)
+
+// Register registers the Go migration of the version, it is meant to be called from init.
+// The migrations run in the version order along with the SQL ones.
+// A nil down function makes the migration irreversible.
+// It panics if the version is invalid or already registered.
+func Register(version, name string, up, down GoMigrationFunc) {
+	parsed, err := time.Parse(FormatVersion, version)
+	if err != nil {
+		panic(fmt.Sprintf("migrate: invalid version of the go migration %s: %v", name, err))
+	}
+	if !namePattern.MatchString(name) || up == nil {
+		panic(fmt.Sprintf("migrate: invalid go migration %s_%s", version, name))
+	}
+	if _, ok := registry[parsed]; ok {
+		panic(fmt.Sprintf("migrate: go migration %s registered twice", version))
+	}
+
+	registry[parsed] = goMigration{name, up, down}
+}
+
+// mergeGo adds the Go migrations to the files, keeping the up migrations sorted.
+func mergeGo(files migrations, registered map[time.Time]goMigration) (migrations, error) {
+	for version, g := range registered {
+		if files.has(version) {
+			return migrations{}, fmt.Errorf("%w %s: go migration %s clashes with a sql one", ErrDuplicateVersion, version.Format(FormatVersion), g.name)
+		}
+
+		files.up = append(files.up, Migration{Version: version, Name: g.name, Direction: MigrationUp, Func: g.up})
+		if g.down != nil {
+			files.down[version] = Migration{Version: version, Name: g.name, Direction: MigrationDown, Func: g.down}
+		}
+	}
+
+	sortMigrations(files.up, MigrationUp)
+	return files, nil
+}
+
+// createGoMigration creates a Go migration stub, its package is named after the directory.
+func createGoMigration(path, version, name string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("resolve path: %w", err)
+	}
+	pkg := filepath.Base(abs)
+	if !namePattern.MatchString(pkg) || pkg[0] >= '0' && pkg[0] <= '9' {
+		return fmt.Errorf("%w: directory %s isn't a valid package name", ErrInvalidName, pkg)
+	}
+
+	// snake_case to CamelCase.
+	var fn strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part != "" {
+			fn.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+
+	file, err := os.OpenFile(filepath.Join(path, fmt.Sprintf("%s_%s.go", version, name)), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("create migration file: %w", err)
+	}
+	defer file.Close()
+
+	return goTemplate.Execute(file, struct {
+		Package, Version, Name, Func string
+	}{pkg, version, name, fn.String()})
+}
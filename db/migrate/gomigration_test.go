@@ -0,0 +1,63 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func noop(context.Context, *sql.Tx) error { return nil }
+
+func TestMergeGo(t *testing.T) {
+	files := testFiles(t, "2026-10-19-10-00-00", "2026-10-19-12-00-00")
+	version := time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC)
+
+	merged, err := mergeGo(files, map[time.Time]goMigration{version: {"rehash_passwords", noop, nil}})
+	require.NoError(t, err)
+
+	// The Go migration runs between the SQL ones.
+	assert.Equal(t, []string{"up 2026-10-19-10-00-00", "up 2026-10-19-11-00-00", "up 2026-10-19-12-00-00"}, steps(merged.up))
+	assert.NotNil(t, merged.up[1].Func)
+	// Without a down function it can't be rolled back.
+	_, err = rollbacks(merged, testRecords(t, "2026-10-19-11-00-00"))
+	assert.ErrorIs(t, err, ErrMissingPair)
+
+	clash := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	_, err = mergeGo(testFiles(t, "2026-10-19-12-00-00"), map[time.Time]goMigration{clash: {"backfill", noop, noop}})
+	assert.ErrorIs(t, err, ErrDuplicateVersion)
+}
+
+func TestRegister(t *testing.T) {
+	version := time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC)
+	t.Cleanup(func() { delete(registry, version) })
+
+	Register("2026-10-19-11-00-00", "backfill_slugs", noop, noop)
+
+	assert.Equal(t, "backfill_slugs", registry[version].name)
+	assert.Panics(t, func() { Register("2026-10-19-11-00-00", "backfill_slugs", noop, noop) })
+	assert.Panics(t, func() { Register("2026-10-19", "backfill_slugs", noop, noop) })
+	assert.Panics(t, func() { Register("2026-10-19-11-30-00", "backfill_slugs", nil, noop) })
+}
+
+func TestCreateMigrationFiles_Go(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+
+	require.NoError(t, CreateMigrationFiles(dir, "rehash_passwords", CreateOptions{Kind: KindGo}))
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*_rehash_passwords.go"))
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+
+	file, err := parser.ParseFile(token.NewFileSet(), matches[0], nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "migrations", file.Name.Name)
+}
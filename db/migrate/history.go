@@ -0,0 +1,136 @@
+package migrate
+
+// This file contains the migration history stored in the database.
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type (
+	// querier is implemented by *sql.DB, *sql.Conn and *sql.Tx, so the history is written
+	// in the transaction of the migration when there is one.
+	querier interface {
+		ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+		QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+		QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	}
+
+	// Record represents an applied migration.
+	Record struct {
+		Version   time.Time
+		Name      string
+		Checksum  string
+		AppliedAt time.Time
+		Duration  time.Duration
+		// Dirty is set while a migration which runs outside of a transaction is in progress,
+		// it stays set if the migration fails halfway.
+		Dirty bool
+	}
+)
+
+// ensureHistory creates the migration history table if it doesn't exist.
+func ensureHistory(ctx context.Context, q querier) error {
+	_, err := q.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version VARCHAR(19) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    checksum VARCHAR(64) NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    dirty BOOLEAN NOT NULL DEFAULT FALSE
+)`)
+	return err
+}
+
+// readHistory returns the applied migrations like appliedRecords
+// without creating the history table if it doesn't exist yet.
+func readHistory(ctx context.Context, q querier) ([]Record, error) {
+	var exists bool
+	err := q.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	return appliedRecords(ctx, q)
+}
+
+// appliedRecords returns the applied migrations, oldest first.
+func appliedRecords(ctx context.Context, q querier) ([]Record, error) {
+	rows, err := q.QueryContext(ctx, "SELECT version, name, checksum, applied_at, duration_ms, dirty FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var (
+			r          Record
+			version    string
+			durationMS int64
+		)
+		if err := rows.Scan(&version, &r.Name, &r.Checksum, &r.AppliedAt, &durationMS, &r.Dirty); err != nil {
+			return nil, err
+		}
+		if r.Version, err = time.Parse(FormatVersion, version); err != nil {
+			return nil, err
+		}
+		r.Duration = time.Duration(durationMS) * time.Millisecond
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// startMigration marks the migration as dirty until it is finished.
+func startMigration(ctx context.Context, q querier, m Migration) error {
+	if m.Direction == MigrationDown {
+		_, err := q.ExecContext(ctx, "UPDATE schema_migrations SET dirty = TRUE WHERE version = $1", m.Version.Format(FormatVersion))
+		return err
+	}
+
+	_, err := q.ExecContext(ctx,
+		"INSERT INTO schema_migrations (version, name, checksum, applied_at, dirty) VALUES ($1, $2, $3, $4, TRUE)",
+		m.Version.Format(FormatVersion), m.Name, m.Checksum(), time.Now().UTC())
+	return err
+}
+
+// finishMigration stores the applied migration or removes the rolled back one.
+func finishMigration(ctx context.Context, q querier, m Migration, duration time.Duration) error {
+	if m.Direction == MigrationDown {
+		_, err := q.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", m.Version.Format(FormatVersion))
+		return err
+	}
+
+	_, err := q.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, name, checksum, applied_at, duration_ms, dirty) VALUES ($1, $2, $3, $4, $5, FALSE)
+ON CONFLICT (version) DO UPDATE SET checksum = EXCLUDED.checksum, duration_ms = EXCLUDED.duration_ms, dirty = FALSE`,
+		m.Version.Format(FormatVersion), m.Name, m.Checksum(), time.Now().UTC(), duration.Milliseconds())
+	return err
+}
+
+// forceHistory makes the history list exactly the given migrations as applied and clean.
+func forceHistory(ctx context.Context, q querier, migrations []Migration) error {
+	versions := make([]string, 0, len(migrations))
+	for _, m := range migrations {
+		versions = append(versions, m.Version.Format(FormatVersion))
+	}
+
+	_, err := q.ExecContext(ctx, "DELETE FROM schema_migrations WHERE NOT (version = ANY($1))", pq.Array(versions))
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)
+ON CONFLICT (version) DO UPDATE SET checksum = EXCLUDED.checksum, dirty = FALSE`,
+			m.Version.Format(FormatVersion), m.Name, m.Checksum(), time.Now().UTC())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
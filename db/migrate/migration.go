@@ -1,10 +1,15 @@
 package migrate
 
 import (
-	"database/sql"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -14,123 +19,247 @@ const (
 
 	MigrationUp   MigrationDirection = "up"
 	MigrationDown MigrationDirection = "down"
+
+	KindSQL MigrationKind = "sql"
+	KindGo  MigrationKind = "go"
+
+	// DirectiveNoTransaction in the header of a migration file runs it outside of a transaction,
+	// e.g. for CREATE INDEX CONCURRENTLY.
+	DirectiveNoTransaction = "-- migrate:no-transaction"
 )
 
 type (
 	MigrationDirection string
-)
 
-func CreateMigrationFiles(path string, name string) error {
-	// Create the migration file.
-	migrationFileUp, err := os.Create(
-		filepath.Join(path, fmt.Sprintf("%s_%s.%s.sql", time.Now().Format(FormatVersion), name, MigrationUp)),
-	)
-	if err != nil {
-		return fmt.Errorf("create migration file: %w", err)
+	// MigrationKind is the kind of the migration files to create.
+	MigrationKind string
+
+	// Migration represents a migration file or a Go migration.
+	Migration struct {
+		Version   time.Time
+		Name      string
+		Direction MigrationDirection
+		SQL       string
+		// NoTransaction is set by DirectiveNoTransaction.
+		NoTransaction bool
+		// Func is set for the Go migrations instead of the SQL.
+		Func GoMigrationFunc
 	}
-	defer migrationFileUp.Close()
 
-	migrationFileDown, err := os.Create(
-		filepath.Join(path, fmt.Sprintf("%s_%s.%s.sql", time.Now().Format(FormatVersion), name, MigrationDown)),
-	)
-	if err != nil {
-		return fmt.Errorf("create migration file: %w", err)
+	// migrationFile represents a migration file found in a source.
+	migrationFile struct {
+		source    fs.FS
+		path      string
+		version   time.Time
+		name      string
+		direction MigrationDirection
 	}
-	defer migrationFileDown.Close()
+)
 
-	return nil
+var (
+	// migrationNamePattern matches the migration file names.
+	migrationNamePattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}-\d{2}-\d{2}-\d{2})_([a-z0-9_]+)\.(up|down)\.sql$`)
+	// namePattern matches the names of new migrations.
+	namePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// Checksum returns the hex encoded sha256 of the migration.
+// The code of the Go migrations isn't checked, they share the checksum of no SQL.
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.SQL))
+	return hex.EncodeToString(sum[:])
 }
 
-func RunMigrationsUp(db *sql.DB, path string, version time.Time) error {
-	// Read the migration files.
-	migrations, err := ReadMigrationFiles(path, version, MigrationUp)
+// CreateMigrationFiles creates the up and down SQL files of a new migration,
+// optionally filled from a template, or a Go file registering the migration functions.
+func CreateMigrationFiles(path string, name string, opts CreateOptions) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("%w: name must consist of lowercase letters, digits and underscores", ErrInvalidName)
+	}
+
+	// Both files share the version.
+	version := time.Now().Format(FormatVersion)
+
+	if opts.Kind == KindGo {
+		return createGoMigration(path, version, name)
+	}
+
+	up, down, err := opts.render()
 	if err != nil {
-		return fmt.Errorf("read migration files: %w", err)
+		return fmt.Errorf("render template: %w", err)
 	}
 
-	// Run the migration files.
-	for _, migration := range migrations {
-		_, err := db.Exec(migration)
-		if err != nil {
-			return fmt.Errorf("run migration: %w", err)
+	// Create the migration files.
+	for direction, content := range map[MigrationDirection]string{MigrationUp: up, MigrationDown: down} {
+		file := filepath.Join(path, fmt.Sprintf("%s_%s.%s.sql", version, name, direction))
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			return fmt.Errorf("create migration file: %w", err)
 		}
 	}
 
 	return nil
 }
 
-func RunMigrationsDown(db *sql.DB, path string, version time.Time) error {
-	// Read the migration files.
-	migrations, err := ReadMigrationFiles(path, version, MigrationDown)
+// ReadMigrationFiles reads the migration files of the direction found in the sources,
+// e.g. the embedded migrations of the packages or a directory opened with os.DirFS.
+// Up migrations older than the version are returned oldest first,
+// down migrations newer than the version are returned newest first.
+// The zero version returns all the migrations.
+// All the migration files are validated, whatever the direction and the version.
+func ReadMigrationFiles(version time.Time, direction MigrationDirection, sources ...fs.FS) ([]Migration, error) {
+	files, err := findMigrationFiles(sources)
 	if err != nil {
-		return fmt.Errorf("read migration files: %w", err)
+		return nil, err
 	}
 
-	// Run the migration files.
-	for _, migration := range migrations {
-		queries := strings.Split(migration, ";")
-		for _, query := range queries {
-			_, err := db.Exec(query)
-			if err != nil {
-				return fmt.Errorf("run migration: %w", err)
-			}
+	var migrations []Migration
+	for _, file := range files {
+		if file.direction != direction {
+			continue
 		}
-	}
 
-	return nil
-}
+		// Check if the migration file is older than the version.
+		if !version.IsZero() && (direction == MigrationUp && file.version.After(version) ||
+			direction == MigrationDown && file.version.Before(version)) {
+			continue
+		}
 
-func ReadMigrationFiles(path string, version time.Time, direction MigrationDirection) ([]string, error) {
-	var migrations []string
+		// Read the migration file.
+		migration, err := fs.ReadFile(file.source, file.path)
+		if err != nil {
+			return nil, fmt.Errorf("read migration file: %w", err)
+		}
 
-	if version == (time.Time{}) {
-		version = time.Now()
+		migrations = append(migrations, Migration{
+			Version:       file.version,
+			Name:          file.name,
+			Direction:     direction,
+			SQL:           string(migration),
+			NoTransaction: hasDirective(string(migration), DirectiveNoTransaction),
+		})
 	}
 
-	err := filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	// The files of all the packages are run in the order of their versions.
+	sortMigrations(migrations, direction)
+
+	return migrations, nil
+}
+
+// sortMigrations sorts up migrations oldest first and down migrations newest first.
+func sortMigrations(migrations []Migration, direction MigrationDirection) {
+	sort.Slice(migrations, func(i, j int) bool {
+		if direction == MigrationDown {
+			return migrations[i].Version.After(migrations[j].Version)
 		}
+		return migrations[i].Version.Before(migrations[j].Version)
+	})
+}
+
+// findMigrationFiles returns the sql files of the sources.
+// It fails with all the misnamed files, duplicate versions and missing pairs found.
+func findMigrationFiles(sources []fs.FS) ([]migrationFile, error) {
+	var (
+		files []migrationFile
+		errs  []error
+	)
 
-		// Check if the file is a migration file.
-		if !info.IsDir() && strings.HasSuffix(info.Name(), string(direction)+".sql") {
-			fileVersion, err := getVersionFromMigrationName(info.Name())
+	for _, source := range sources {
+		err := fs.WalkDir(source, ".", func(filePath string, entry fs.DirEntry, err error) error {
 			if err != nil {
-				return fmt.Errorf("get version from migration name: %w", err)
+				return err
 			}
-
-			// Check if the migration file is older than the version.
-			if direction == MigrationUp && fileVersion.After(version) ||
-				direction == MigrationDown && fileVersion.Before(version) {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
 				return nil
 			}
 
-			// Add the migration file to the list.
-			migrationFile, err := os.OpenFile(filePath, os.O_RDONLY, 0644)
+			file, err := parseMigrationName(entry.Name())
 			if err != nil {
-				return fmt.Errorf("open migration file: %w", err)
+				errs = append(errs, fmt.Errorf("%s: %w", filePath, err))
+				return nil
 			}
-			defer migrationFile.Close()
+			file.source, file.path = source, filePath
+			files = append(files, file)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("traverse migration files: %w", err)
+		}
+	}
 
-			// Read the migration file.
-			migration := make([]byte, info.Size())
-			_, err = migrationFile.Read(migration)
-			if err != nil {
-				return fmt.Errorf("read migration file: %w", err)
-			}
+	// Each version must have exactly one up and one down file of the same name.
+	byVersion := map[time.Time][]migrationFile{}
+	for _, file := range files {
+		byVersion[file.version] = append(byVersion[file.version], file)
+	}
+	for _, versionFiles := range byVersion {
+		errs = append(errs, checkPair(versionFiles))
+	}
 
-			migrations = append(migrations, string(migration))
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// checkPair checks that the files of one version are an up and down pair.
+func checkPair(files []migrationFile) error {
+	var up, down []migrationFile
+	for _, file := range files {
+		if file.direction == MigrationUp {
+			up = append(up, file)
+		} else {
+			down = append(down, file)
 		}
+	}
 
-		return nil
-	})
-	if err != nil {
-		return nil, fmt.Errorf("traverse migration files: %w", err)
+	switch {
+	case len(up) > 1 || len(down) > 1 || len(up) == 1 && len(down) == 1 && up[0].name != down[0].name:
+		var paths []string
+		for _, file := range files {
+			paths = append(paths, file.path)
+		}
+		sort.Strings(paths)
+		return fmt.Errorf("%w %s: %s", ErrDuplicateVersion, files[0].version.Format(FormatVersion), strings.Join(paths, ", "))
+	case len(down) == 0:
+		return fmt.Errorf("%w: %s has no %s migration", ErrMissingPair, up[0].path, MigrationDown)
+	case len(up) == 0:
+		return fmt.Errorf("%w: %s has no %s migration", ErrMissingPair, down[0].path, MigrationUp)
 	}
+	return nil
+}
 
-	return migrations, nil
+// hasDirective reports whether the comments heading the migration contain the directive.
+func hasDirective(migration, directive string) bool {
+	for _, line := range strings.Split(migration, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			return false
+		}
+		if line == directive {
+			return true
+		}
+	}
+	return false
 }
 
-func getVersionFromMigrationName(migrationName string) (time.Time, error) {
-	return time.Parse(FormatVersion, strings.Split(migrationName, "_")[0])
+// parseMigrationName parses a file name of the <version>_<name>.<direction>.sql format.
+func parseMigrationName(fileName string) (migrationFile, error) {
+	match := migrationNamePattern.FindStringSubmatch(fileName)
+	if match == nil {
+		return migrationFile{}, fmt.Errorf("%w: expected <%s>_<name>.<up|down>.sql with a lowercase name", ErrInvalidName, FormatVersion)
+	}
+
+	version, err := time.Parse(FormatVersion, match[1])
+	if err != nil {
+		return migrationFile{}, fmt.Errorf("%w: version: %w", ErrInvalidName, err)
+	}
+
+	return migrationFile{
+		version:   version,
+		name:      match[2],
+		direction: MigrationDirection(match[3]),
+	}, nil
 }
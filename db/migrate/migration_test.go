@@ -0,0 +1,172 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeMigrations creates empty up and down files of the migrations in the package directories.
+func writeMigrations(t *testing.T, files map[string][]string) string {
+	root := t.TempDir()
+	for pkg, names := range files {
+		dir := filepath.Join(root, pkg, "migrations")
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		for _, name := range names {
+			for _, direction := range []MigrationDirection{MigrationUp, MigrationDown} {
+				file := filepath.Join(dir, name+"."+string(direction)+".sql")
+				require.NoError(t, os.WriteFile(file, []byte(name), 0o644))
+			}
+		}
+	}
+	return root
+}
+
+func versions(migrations []Migration) []string {
+	var result []string
+	for _, migration := range migrations {
+		result = append(result, migration.Version.Format(FormatVersion))
+	}
+	return result
+}
+
+func TestReadMigrationFiles_Order(t *testing.T) {
+	root := writeMigrations(t, map[string][]string{
+		"user": {"2024-07-27-11-23-57_user", "2026-10-19-14-00-00_user_email"},
+		"shop": {"2026-10-19-10-00-00_shop"},
+	})
+
+	up, err := ReadMigrationFiles(time.Time{}, MigrationUp, os.DirFS(root))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2024-07-27-11-23-57", "2026-10-19-10-00-00", "2026-10-19-14-00-00"}, versions(up))
+	assert.Equal(t, "user", up[0].Name)
+	assert.Equal(t, "user_email", up[2].Name)
+
+	down, err := ReadMigrationFiles(time.Time{}, MigrationDown, os.DirFS(root))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2026-10-19-14-00-00", "2026-10-19-10-00-00", "2024-07-27-11-23-57"}, versions(down))
+}
+
+func TestReadMigrationFiles_Version(t *testing.T) {
+	root := writeMigrations(t, map[string][]string{
+		"shop": {"2026-10-19-10-00-00_shop", "2026-10-19-11-00-00_webhook", "2026-10-19-12-00-00_vip"},
+	})
+	version := time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC)
+
+	up, err := ReadMigrationFiles(version, MigrationUp, os.DirFS(root))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2026-10-19-10-00-00", "2026-10-19-11-00-00"}, versions(up))
+
+	down, err := ReadMigrationFiles(version, MigrationDown, os.DirFS(root))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2026-10-19-12-00-00", "2026-10-19-11-00-00"}, versions(down))
+}
+
+func TestHasDirective(t *testing.T) {
+	cases := []struct {
+		testName  string
+		migration string
+		expected  bool
+	}{
+		{
+			testName:  "header",
+			migration: "-- Build the index without locking the table.\n-- migrate:no-transaction\n\nCREATE INDEX CONCURRENTLY a ON b (c);",
+			expected:  true,
+		},
+		{
+			testName:  "none",
+			migration: "-- An index.\nCREATE INDEX a ON b (c);",
+		},
+		{
+			testName:  "after the first statement",
+			migration: "CREATE INDEX a ON b (c);\n-- migrate:no-transaction",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			assert.Equal(t, tc.expected, hasDirective(tc.migration, DirectiveNoTransaction))
+		})
+	}
+}
+
+func TestReadMigrationFiles_Invalid(t *testing.T) {
+	cases := []struct {
+		testName      string
+		files         []string
+		expectedError error
+	}{
+		{
+			testName:      "date only version",
+			files:         []string{"2024-10-30_user_name_field.up.sql", "2024-10-30_user_name_field.down.sql"},
+			expectedError: ErrInvalidName,
+		},
+		{
+			testName:      "invalid date",
+			files:         []string{"2024-13-30-00-00-00_user.up.sql", "2024-13-30-00-00-00_user.down.sql"},
+			expectedError: ErrInvalidName,
+		},
+		{
+			testName:      "unknown direction",
+			files:         []string{"2024-10-30-00-00-00_user.sideways.sql"},
+			expectedError: ErrInvalidName,
+		},
+		{
+			testName:      "duplicate version",
+			files:         []string{"2024-10-30-00-00-00_a.up.sql", "2024-10-30-00-00-00_a.down.sql", "2024-10-30-00-00-00_b.up.sql", "2024-10-30-00-00-00_b.down.sql"},
+			expectedError: ErrDuplicateVersion,
+		},
+		{
+			testName:      "missing down",
+			files:         []string{"2024-10-30-00-00-00_a.up.sql"},
+			expectedError: ErrMissingPair,
+		},
+		{
+			testName:      "missing up",
+			files:         []string{"2024-10-30-00-00-00_a.down.sql"},
+			expectedError: ErrMissingPair,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			root := t.TempDir()
+			for _, file := range tc.files {
+				require.NoError(t, os.WriteFile(filepath.Join(root, file), nil, 0o644))
+			}
+
+			_, err := ReadMigrationFiles(time.Time{}, MigrationUp, os.DirFS(root))
+
+			assert.ErrorIs(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestReadMigrationFiles_Sources(t *testing.T) {
+	user := fstest.MapFS{
+		"migrations/2024-07-27-11-23-57_user.up.sql":   {Data: []byte("CREATE TABLE user_storage ();")},
+		"migrations/2024-07-27-11-23-57_user.down.sql": {Data: []byte("DROP TABLE user_storage;")},
+	}
+	shop := fstest.MapFS{
+		"migrations/2026-10-19-10-00-00_shop.up.sql":   {Data: []byte("CREATE TABLE shop_product ();")},
+		"migrations/2026-10-19-10-00-00_shop.down.sql": {Data: []byte("DROP TABLE shop_product;")},
+	}
+
+	up, err := ReadMigrationFiles(time.Time{}, MigrationUp, shop, user)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2024-07-27-11-23-57", "2026-10-19-10-00-00"}, versions(up))
+	assert.Equal(t, "CREATE TABLE user_storage ();", up[0].SQL)
+
+	// Versions must be unique across the sources.
+	clash := fstest.MapFS{
+		"migrations/2026-10-19-10-00-00_vip.up.sql":   {},
+		"migrations/2026-10-19-10-00-00_vip.down.sql": {},
+	}
+	_, err = ReadMigrationFiles(time.Time{}, MigrationUp, shop, clash)
+	assert.ErrorIs(t, err, ErrDuplicateVersion)
+}
@@ -0,0 +1,496 @@
+package migrate
+
+// This file contains the migrator running the migration files against the database.
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// lockID is the key of the advisory lock taken while migrating.
+const lockID int64 = 4_021_778_362
+
+type (
+	// Migrator runs the migration files found in the sources.
+	Migrator struct {
+		db      *sql.DB
+		sources []fs.FS
+		// dryRun plans the migrations without running them.
+		dryRun bool
+	}
+
+	// Status represents the state of a migration.
+	Status struct {
+		Version   string     `json:"version"`
+		Name      string     `json:"name"`
+		Applied   bool       `json:"applied"`
+		AppliedAt *time.Time `json:"applied_at,omitempty"`
+		Dirty     bool       `json:"dirty,omitempty"`
+		// Drift is set if the up file has been changed since it was applied.
+		Drift bool `json:"drift,omitempty"`
+		// Checksum is the checksum of the up file, empty if the file is gone.
+		Checksum string `json:"checksum"`
+		// AppliedChecksum is the checksum of the up file at the time it was applied.
+		AppliedChecksum string `json:"applied_checksum,omitempty"`
+	}
+
+	// migrations holds the migration files of both directions.
+	migrations struct {
+		// up is ordered oldest first.
+		up []Migration
+		// down is keyed by the version.
+		down map[time.Time]Migration
+	}
+)
+
+// NewMigrator creates a new migrator of the migration files found in the sources.
+func NewMigrator(db *sql.DB, sources ...fs.FS) *Migrator {
+	return &Migrator{db: db, sources: sources}
+}
+
+// DryRun returns a migrator which plans the migrations without running them.
+// Its commands return the migrations that would run, the database is only read.
+func (m *Migrator) DryRun() *Migrator {
+	return &Migrator{db: m.db, sources: m.sources, dryRun: true}
+}
+
+// Status returns the state of the migrations, oldest first.
+// Applied migrations whose files are gone are listed as well.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	files, err := m.read()
+	if err != nil {
+		return nil, err
+	}
+	records, err := readHistory(ctx, m.db)
+	if err != nil {
+		return nil, fmt.Errorf("read migration history: %w", err)
+	}
+
+	return status(files, records), nil
+}
+
+// Up applies n pending migrations, all of them if n is 0, oldest first.
+func (m *Migrator) Up(ctx context.Context, n int) ([]Migration, error) {
+	return m.run(ctx, func(files migrations, records []Record) ([]Migration, error) {
+		steps := pending(files, records)
+		if n > 0 && n < len(steps) {
+			steps = steps[:n]
+		}
+		return steps, nil
+	})
+}
+
+// Apply applies all the pending migrations like Up, unless the database has been migrated
+// by a newer build, in which case it fails with ErrSchemaAhead. It is meant to run on startup.
+func (m *Migrator) Apply(ctx context.Context) ([]Migration, error) {
+	return m.run(ctx, func(files migrations, records []Record) ([]Migration, error) {
+		if unknown := unknownRecords(files, records); len(unknown) > 0 {
+			last := unknown[len(unknown)-1]
+			return nil, fmt.Errorf("%w: %d migrations, the last one is %s_%s", ErrSchemaAhead, len(unknown), last.Version.Format(FormatVersion), last.Name)
+		}
+		return pending(files, records), nil
+	})
+}
+
+// Verify checks that the database has been migrated to the version of this build.
+// It fails with ErrPending if migrations are missing and with ErrDirty if one failed halfway.
+// The database may be ahead of the build, so the replicas of the previous build keep
+// working during a rolling deploy. It doesn't take the migration lock.
+func (m *Migrator) Verify(ctx context.Context) error {
+	files, err := m.read()
+	if err != nil {
+		return err
+	}
+	records, err := readHistory(ctx, m.db)
+	if err != nil {
+		return fmt.Errorf("read migration history: %w", err)
+	}
+
+	return verify(files, records)
+}
+
+// Down rolls back n applied migrations, newest first.
+// It fails with ErrInvalidCount if n is less than 1.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidCount, n)
+	}
+
+	return m.run(ctx, func(files migrations, records []Record) ([]Migration, error) {
+		if n > len(records) {
+			n = len(records)
+		}
+		return rollbacks(files, records[len(records)-n:])
+	})
+}
+
+// Redo rolls back and applies again the last applied migration.
+func (m *Migrator) Redo(ctx context.Context) ([]Migration, error) {
+	return m.run(ctx, func(files migrations, records []Record) ([]Migration, error) {
+		if len(records) == 0 {
+			return nil, ErrNothingApplied
+		}
+		last := records[len(records)-1]
+
+		steps, err := rollbacks(files, []Record{last})
+		if err != nil {
+			return nil, err
+		}
+		for _, up := range files.up {
+			if up.Version.Equal(last.Version) {
+				return append(steps, up), nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s has no %s migration", ErrMissingPair, last.Version.Format(FormatVersion), MigrationUp)
+	})
+}
+
+// Goto migrates up or down so the version is the last applied migration.
+func (m *Migrator) Goto(ctx context.Context, version time.Time) ([]Migration, error) {
+	return m.run(ctx, func(files migrations, records []Record) ([]Migration, error) {
+		if !files.has(version) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownVersion, version.Format(FormatVersion))
+		}
+
+		var newer []Record
+		for _, r := range records {
+			if r.Version.After(version) {
+				newer = append(newer, r)
+			}
+		}
+		steps, err := rollbacks(files, newer)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, up := range pending(files, records) {
+			if !up.Version.After(version) {
+				steps = append(steps, up)
+			}
+		}
+		return steps, nil
+	})
+}
+
+// Force records the migrations up to the version as applied and the newer ones as not,
+// without running them. It repairs the history once a failed migration has been fixed by hand,
+// and accepts the current checksums of the applied migrations.
+func (m *Migrator) Force(ctx context.Context, version time.Time) error {
+	return m.record(ctx, version, false)
+}
+
+// Baseline records the migrations up to the version as applied without running them,
+// like Force, but only if the history is empty. It adopts a database whose schema was
+// created before its migrations were tracked, it fails with ErrHistoryExists otherwise.
+func (m *Migrator) Baseline(ctx context.Context, version time.Time) error {
+	return m.record(ctx, version, true)
+}
+
+// record makes the history list the migrations up to the version as applied.
+// With onlyEmpty set the history must be empty.
+func (m *Migrator) record(ctx context.Context, version time.Time, onlyEmpty bool) error {
+	files, err := m.read()
+	if err != nil {
+		return err
+	}
+	applied, err := upTo(files, version)
+	if err != nil {
+		return err
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		if err := ensureHistory(ctx, conn); err != nil {
+			return fmt.Errorf("create migration history: %w", err)
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		if onlyEmpty {
+			records, err := appliedRecords(ctx, tx)
+			if err != nil {
+				return fmt.Errorf("read migration history: %w", err)
+			}
+			if len(records) > 0 {
+				return fmt.Errorf("%w: %d migrations", ErrHistoryExists, len(records))
+			}
+		}
+
+		if err := forceHistory(ctx, tx, applied); err != nil {
+			return fmt.Errorf("force migration history: %w", err)
+		}
+		return tx.Commit()
+	})
+}
+
+// run runs the migrations planned from the files and the history under the lock.
+// It returns the migrations run so far, even if one of them failed.
+// In the dry run mode it returns the planned migrations.
+func (m *Migrator) run(ctx context.Context, plan func(files migrations, records []Record) ([]Migration, error)) ([]Migration, error) {
+	files, err := m.read()
+	if err != nil {
+		return nil, err
+	}
+
+	if m.dryRun {
+		records, err := readHistory(ctx, m.db)
+		if err != nil {
+			return nil, fmt.Errorf("read migration history: %w", err)
+		}
+		if err := check(files, records); err != nil {
+			return nil, err
+		}
+		return plan(files, records)
+	}
+
+	var done []Migration
+	err = m.withLock(ctx, func(conn *sql.Conn) error {
+		if err := ensureHistory(ctx, conn); err != nil {
+			return fmt.Errorf("create migration history: %w", err)
+		}
+		records, err := appliedRecords(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("read migration history: %w", err)
+		}
+		if err := check(files, records); err != nil {
+			return err
+		}
+
+		steps, err := plan(files, records)
+		if err != nil {
+			return err
+		}
+		for _, step := range steps {
+			if err := runMigration(ctx, conn, step); err != nil {
+				return fmt.Errorf("run migration %s_%s.%s: %w", step.Version.Format(FormatVersion), step.Name, step.Direction, err)
+			}
+			done = append(done, step)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// read reads the migration files of both directions along with the registered Go migrations.
+func (m *Migrator) read() (migrations, error) {
+	up, err := ReadMigrationFiles(time.Time{}, MigrationUp, m.sources...)
+	if err != nil {
+		return migrations{}, fmt.Errorf("read migration files: %w", err)
+	}
+	down, err := ReadMigrationFiles(time.Time{}, MigrationDown, m.sources...)
+	if err != nil {
+		return migrations{}, fmt.Errorf("read migration files: %w", err)
+	}
+
+	files := migrations{up: up, down: make(map[time.Time]Migration, len(down))}
+	for _, migration := range down {
+		files.down[migration.Version] = migration
+	}
+	return mergeGo(files, registry)
+}
+
+// withLock runs fn holding the migration lock, so concurrent runs wait for each other.
+// Session level advisory locks belong to a connection, hence fn gets the connection holding it.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+
+	return fn(conn)
+}
+
+// runMigration runs the SQL or Go migration along with the record of it in the history.
+// Both are committed in one transaction unless the SQL migration opts out of it,
+// in which case the migration is marked as dirty until it is finished.
+func runMigration(ctx context.Context, conn *sql.Conn, m Migration) error {
+	start := time.Now()
+
+	if m.NoTransaction {
+		if err := startMigration(ctx, conn, m); err != nil {
+			return fmt.Errorf("record migration: %w", err)
+		}
+		// Statements are sent one by one, multiple statements would run in an implicit transaction.
+		for _, statement := range SplitStatements(m.SQL) {
+			if _, err := conn.ExecContext(ctx, statement); err != nil {
+				return err
+			}
+		}
+		if err := finishMigration(ctx, conn, m, time.Since(start)); err != nil {
+			return fmt.Errorf("record migration: %w", err)
+		}
+		return nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if m.Func != nil {
+		err = m.Func(ctx, tx)
+	} else {
+		_, err = tx.ExecContext(ctx, m.SQL)
+	}
+	if err != nil {
+		return err
+	}
+	if err := finishMigration(ctx, tx, m, time.Since(start)); err != nil {
+		return fmt.Errorf("record migration: %w", err)
+	}
+	return tx.Commit()
+}
+
+// has reports whether there is a migration of the version.
+func (files migrations) has(version time.Time) bool {
+	for _, up := range files.up {
+		if up.Version.Equal(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// check refuses to migrate a database with a migration which failed halfway
+// or an applied migration whose file has been changed since.
+func check(files migrations, records []Record) error {
+	checksums := make(map[time.Time]string, len(files.up))
+	for _, up := range files.up {
+		checksums[up.Version] = up.Checksum()
+	}
+
+	var errs []error
+	for _, r := range records {
+		name := r.Version.Format(FormatVersion) + "_" + r.Name
+		if r.Dirty {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrDirty, name))
+		}
+		if checksum, ok := checksums[r.Version]; ok && checksum != r.Checksum {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrChecksumMismatch, name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// verify reports the pending and the dirty migrations.
+func verify(files migrations, records []Record) error {
+	var errs []error
+	if steps := pending(files, records); len(steps) > 0 {
+		last := steps[len(steps)-1]
+		errs = append(errs, fmt.Errorf("%w: %d migrations, the last one is %s_%s", ErrPending, len(steps), last.Version.Format(FormatVersion), last.Name))
+	}
+	for _, r := range records {
+		if r.Dirty {
+			errs = append(errs, fmt.Errorf("%w: %s_%s", ErrDirty, r.Version.Format(FormatVersion), r.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pending returns the up migrations which haven't been applied yet, oldest first.
+func pending(files migrations, records []Record) []Migration {
+	applied := make(map[time.Time]bool, len(records))
+	for _, r := range records {
+		applied[r.Version] = true
+	}
+
+	var steps []Migration
+	for _, up := range files.up {
+		if !applied[up.Version] {
+			steps = append(steps, up)
+		}
+	}
+	return steps
+}
+
+// upTo returns the up migrations up to the version, oldest first.
+// It fails with ErrUnknownVersion if the version has no migration.
+func upTo(files migrations, version time.Time) ([]Migration, error) {
+	if !files.has(version) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownVersion, version.Format(FormatVersion))
+	}
+
+	var steps []Migration
+	for _, up := range files.up {
+		if !up.Version.After(version) {
+			steps = append(steps, up)
+		}
+	}
+	return steps, nil
+}
+
+// unknownRecords returns the applied migrations which have no files.
+func unknownRecords(files migrations, records []Record) []Record {
+	var unknown []Record
+	for _, r := range records {
+		if !files.has(r.Version) {
+			unknown = append(unknown, r)
+		}
+	}
+	return unknown
+}
+
+// rollbacks returns the down migrations of the applied ones, newest first.
+func rollbacks(files migrations, records []Record) ([]Migration, error) {
+	var steps []Migration
+	for i := len(records) - 1; i >= 0; i-- {
+		down, ok := files.down[records[i].Version]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s_%s has no %s migration", ErrMissingPair, records[i].Version.Format(FormatVersion), records[i].Name, MigrationDown)
+		}
+		steps = append(steps, down)
+	}
+	return steps, nil
+}
+
+// status merges the migration files with the history.
+func status(files migrations, records []Record) []Status {
+	byVersion := make(map[time.Time]Record, len(records))
+	for _, r := range records {
+		byVersion[r.Version] = r
+	}
+
+	var result []Status
+	for _, up := range files.up {
+		s := Status{Version: up.Version.Format(FormatVersion), Name: up.Name, Checksum: up.Checksum()}
+		if r, ok := byVersion[up.Version]; ok {
+			s.Applied, s.AppliedAt, s.Dirty, s.AppliedChecksum = true, &r.AppliedAt, r.Dirty, r.Checksum
+			s.Drift = s.Checksum != s.AppliedChecksum
+			delete(byVersion, up.Version)
+		}
+		result = append(result, s)
+	}
+
+	// The history may list migrations which don't exist in this build.
+	for _, r := range records {
+		if _, ok := byVersion[r.Version]; !ok {
+			continue
+		}
+		result = append(result, Status{
+			Version:         r.Version.Format(FormatVersion),
+			Name:            r.Name,
+			Applied:         true,
+			AppliedAt:       &r.AppliedAt,
+			Dirty:           r.Dirty,
+			AppliedChecksum: r.Checksum,
+		})
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	return result
+}
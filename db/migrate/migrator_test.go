@@ -0,0 +1,202 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFiles returns the migrations of the versions, each with an up and down file.
+func testFiles(t *testing.T, versions ...string) migrations {
+	files := migrations{down: map[time.Time]Migration{}}
+	for _, v := range versions {
+		version, err := time.Parse(FormatVersion, v)
+		require.NoError(t, err)
+		files.up = append(files.up, Migration{Version: version, Name: "m", Direction: MigrationUp, SQL: "up " + v})
+		files.down[version] = Migration{Version: version, Name: "m", Direction: MigrationDown, SQL: "down " + v}
+	}
+	return files
+}
+
+// testRecords returns the history of the applied versions.
+func testRecords(t *testing.T, versions ...string) []Record {
+	var records []Record
+	for _, v := range versions {
+		version, err := time.Parse(FormatVersion, v)
+		require.NoError(t, err)
+		records = append(records, Record{Version: version, Name: "m", Checksum: Migration{SQL: "up " + v}.Checksum()})
+	}
+	return records
+}
+
+func steps(migrations []Migration) []string {
+	var result []string
+	for _, m := range migrations {
+		result = append(result, string(m.Direction)+" "+m.Version.Format(FormatVersion))
+	}
+	return result
+}
+
+func TestPending(t *testing.T) {
+	files := testFiles(t, "2026-10-19-10-00-00", "2026-10-19-11-00-00", "2026-10-19-12-00-00")
+	records := testRecords(t, "2026-10-19-10-00-00", "2026-10-19-11-00-00")
+
+	assert.Equal(t, []string{"up 2026-10-19-12-00-00"}, steps(pending(files, records)))
+}
+
+func TestUpTo(t *testing.T) {
+	files := testFiles(t, "2026-10-19-10-00-00", "2026-10-19-11-00-00", "2026-10-19-12-00-00")
+
+	version, err := time.Parse(FormatVersion, "2026-10-19-11-00-00")
+	require.NoError(t, err)
+	up, err := upTo(files, version)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"up 2026-10-19-10-00-00", "up 2026-10-19-11-00-00"}, steps(up))
+
+	// The version must be one of the migrations.
+	_, err = upTo(files, version.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+}
+
+func TestRollbacks(t *testing.T) {
+	files := testFiles(t, "2026-10-19-10-00-00", "2026-10-19-11-00-00", "2026-10-19-12-00-00")
+
+	down, err := rollbacks(files, testRecords(t, "2026-10-19-10-00-00", "2026-10-19-11-00-00"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"down 2026-10-19-11-00-00", "down 2026-10-19-10-00-00"}, steps(down))
+
+	// The history may have migrations of another build.
+	_, err = rollbacks(files, testRecords(t, "2026-10-19-13-00-00"))
+	assert.ErrorIs(t, err, ErrMissingPair)
+}
+
+func TestMigrator_Down_InvalidCount(t *testing.T) {
+	// The count is checked before the database is used.
+	migrator := NewMigrator(nil)
+
+	for _, n := range []int{0, -1} {
+		migrations, err := migrator.Down(context.Background(), n)
+		assert.ErrorIs(t, err, ErrInvalidCount)
+		assert.Empty(t, migrations)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	files := testFiles(t, "2026-10-19-10-00-00", "2026-10-19-11-00-00")
+	records := testRecords(t, "2026-10-19-10-00-00", "2026-10-19-12-00-00")
+	records[0].Dirty = true
+
+	result := status(files, records)
+
+	require.Len(t, result, 3)
+	assert.True(t, result[0].Applied)
+	assert.True(t, result[0].Dirty)
+	assert.Equal(t, result[0].Checksum, result[0].AppliedChecksum)
+	assert.False(t, result[1].Applied)
+	assert.Nil(t, result[1].AppliedAt)
+	assert.Equal(t, "2026-10-19-12-00-00", result[2].Version)
+	assert.True(t, result[2].Applied)
+	assert.Empty(t, result[2].Checksum)
+}
+
+func TestUnknownRecords(t *testing.T) {
+	files := testFiles(t, "2026-10-19-10-00-00", "2026-10-19-11-00-00")
+
+	assert.Empty(t, unknownRecords(files, testRecords(t, "2026-10-19-10-00-00")))
+	assert.Len(t, unknownRecords(files, testRecords(t, "2026-10-19-10-00-00", "2026-10-19-12-00-00")), 1)
+}
+
+func TestCheck(t *testing.T) {
+	files := testFiles(t, "2026-10-19-10-00-00", "2026-10-19-11-00-00")
+
+	cases := []struct {
+		testName      string
+		change        func(records []Record)
+		expectedError error
+	}{
+		{
+			testName: "ok",
+			change:   func([]Record) {},
+		},
+		{
+			testName:      "dirty",
+			change:        func(records []Record) { records[1].Dirty = true },
+			expectedError: ErrDirty,
+		},
+		{
+			testName:      "changed file",
+			change:        func(records []Record) { records[0].Checksum = "edited" },
+			expectedError: ErrChecksumMismatch,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			records := testRecords(t, "2026-10-19-10-00-00", "2026-10-19-11-00-00")
+			tc.change(records)
+
+			err := check(files, records)
+
+			if tc.expectedError == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestStatus_Drift(t *testing.T) {
+	files := testFiles(t, "2026-10-19-10-00-00", "2026-10-19-11-00-00")
+	records := testRecords(t, "2026-10-19-10-00-00", "2026-10-19-11-00-00")
+	records[1].Checksum = "edited"
+
+	result := status(files, records)
+
+	assert.False(t, result[0].Drift)
+	assert.True(t, result[1].Drift)
+}
+
+func TestVerify(t *testing.T) {
+	files := testFiles(t, "2026-10-19-10-00-00", "2026-10-19-11-00-00")
+
+	cases := []struct {
+		testName      string
+		records       []Record
+		expectedError error
+	}{
+		{
+			testName: "up to date",
+			records:  testRecords(t, "2026-10-19-10-00-00", "2026-10-19-11-00-00"),
+		},
+		{
+			testName: "ahead",
+			records:  testRecords(t, "2026-10-19-10-00-00", "2026-10-19-11-00-00", "2026-10-19-12-00-00"),
+		},
+		{
+			testName:      "pending",
+			records:       testRecords(t, "2026-10-19-10-00-00"),
+			expectedError: ErrPending,
+		},
+		{
+			testName:      "dirty",
+			records:       append(testRecords(t, "2026-10-19-10-00-00"), Record{Version: files.up[1].Version, Name: "m", Dirty: true}),
+			expectedError: ErrDirty,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			err := verify(files, tc.records)
+
+			if tc.expectedError == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package migrate
+
+// This file contains the splitter of migrations into SQL statements.
+
+import "strings"
+
+// SplitStatements splits the SQL into statements on the semicolons outside of
+// string literals, quoted identifiers, dollar-quoted bodies and comments.
+// The statements are trimmed and the empty ones are dropped.
+func SplitStatements(sql string) []string {
+	var (
+		statements []string
+		start      int
+	)
+
+	add := func(end int) {
+		statement := strings.TrimSpace(sql[start:end])
+		if body := strings.TrimSpace(strings.TrimSuffix(statement, ";")); body != "" && !isComment(body) {
+			statements = append(statements, statement)
+		}
+	}
+
+	for i := 0; i < len(sql); {
+		switch c := sql[i]; {
+		case c == ';':
+			add(i + 1)
+			i++
+			start = i
+		case c == '\'':
+			// E'...' strings escape quotes with backslashes.
+			backslash := i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e') && (i < 2 || !isIdentifier(sql[i-2]))
+			i = skipQuoted(sql, i, '\'', backslash)
+		case c == '"':
+			i = skipQuoted(sql, i, '"', false)
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(sql)
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			i = skipBlockComment(sql, i)
+		case c == '$' && (i == 0 || !isIdentifier(sql[i-1])):
+			i = skipDollarQuoted(sql, i)
+		default:
+			i++
+		}
+	}
+	add(len(sql))
+
+	return statements
+}
+
+// skipQuoted returns the index after the literal opened at i, doubled quotes are escapes.
+func skipQuoted(sql string, i int, quote byte, backslash bool) int {
+	for i++; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if backslash {
+				i++
+			}
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+// skipBlockComment returns the index after the block comment opened at i, block comments nest.
+func skipBlockComment(sql string, i int) int {
+	depth := 0
+	for i < len(sql) {
+		switch {
+		case strings.HasPrefix(sql[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(sql[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return len(sql)
+}
+
+// skipDollarQuoted returns the index after the $tag$ quoted body opened at i.
+// A $ which doesn't open a tag, e.g. a $1 parameter, is skipped alone.
+func skipDollarQuoted(sql string, i int) int {
+	end := i + 1
+	for end < len(sql) && isIdentifier(sql[end]) && !(end == i+1 && sql[end] >= '0' && sql[end] <= '9') {
+		end++
+	}
+	if end >= len(sql) || sql[end] != '$' {
+		return i + 1
+	}
+
+	tag := sql[i : end+1]
+	if close := strings.Index(sql[end+1:], tag); close >= 0 {
+		return end + 1 + close + len(tag)
+	}
+	return len(sql)
+}
+
+// isIdentifier reports whether c may be a part of an unquoted identifier.
+func isIdentifier(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// isComment reports whether the statement consists of comments only.
+func isComment(statement string) bool {
+	for statement != "" {
+		switch {
+		case strings.HasPrefix(statement, "--"):
+			end := strings.IndexByte(statement, '\n')
+			if end < 0 {
+				return true
+			}
+			statement = statement[end+1:]
+		case strings.HasPrefix(statement, "/*"):
+			statement = statement[skipBlockComment(statement, 0):]
+		default:
+			return false
+		}
+		statement = strings.TrimSpace(statement)
+	}
+	return true
+}
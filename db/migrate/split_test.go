@@ -0,0 +1,68 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	cases := []struct {
+		testName string
+		sql      string
+		expected []string
+	}{
+		{
+			testName: "statements",
+			sql:      "CREATE TABLE a (id INT);\n\nDROP TABLE b;",
+			expected: []string{"CREATE TABLE a (id INT);", "DROP TABLE b;"},
+		},
+		{
+			testName: "no trailing semicolon",
+			sql:      "SELECT 1;\nSELECT 2",
+			expected: []string{"SELECT 1;", "SELECT 2"},
+		},
+		{
+			testName: "string literals",
+			sql:      "INSERT INTO a VALUES ('x;y', 'it''s;');\nSELECT E'\\';';",
+			expected: []string{"INSERT INTO a VALUES ('x;y', 'it''s;');", "SELECT E'\\';';"},
+		},
+		{
+			testName: "quoted identifiers",
+			sql:      `CREATE TABLE "a;b" (id INT); SELECT 1;`,
+			expected: []string{`CREATE TABLE "a;b" (id INT);`, "SELECT 1;"},
+		},
+		{
+			testName: "dollar quoting",
+			sql: "CREATE FUNCTION f() RETURNS TRIGGER AS $$\nBEGIN\n    RAISE EXCEPTION 'no';\n    RETURN NULL;\nEND;\n$$ LANGUAGE plpgsql;\n" +
+				"DO $body$ BEGIN PERFORM 1; END $body$;",
+			expected: []string{
+				"CREATE FUNCTION f() RETURNS TRIGGER AS $$\nBEGIN\n    RAISE EXCEPTION 'no';\n    RETURN NULL;\nEND;\n$$ LANGUAGE plpgsql;",
+				"DO $body$ BEGIN PERFORM 1; END $body$;",
+			},
+		},
+		{
+			testName: "parameters are not dollar quotes",
+			sql:      "PREPARE p AS SELECT $1; EXECUTE p(1);",
+			expected: []string{"PREPARE p AS SELECT $1;", "EXECUTE p(1);"},
+		},
+		{
+			testName: "comments",
+			sql:      "-- migrate:no-transaction\n-- a; b\nCREATE INDEX CONCURRENTLY i ON a (id); /* c; /* nested; */ d; */ SELECT 1;\n-- trailing;",
+			expected: []string{
+				"-- migrate:no-transaction\n-- a; b\nCREATE INDEX CONCURRENTLY i ON a (id);",
+				"/* c; /* nested; */ d; */ SELECT 1;",
+			},
+		},
+		{
+			testName: "empty",
+			sql:      " ;\n-- nothing\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			assert.Equal(t, tc.expected, SplitStatements(tc.sql))
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package migrate
+
+// This file contains the templates of new SQL migrations.
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const (
+	TemplateCreateTable = "create_table"
+	TemplateAddColumn   = "add_column"
+	TemplateAddIndex    = "add_index"
+)
+
+type (
+	// CreateOptions represents the options of a new migration.
+	CreateOptions struct {
+		// Kind is the kind of the migration, SQL by default.
+		Kind MigrationKind
+		// Template fills the SQL files, they are empty by default.
+		Template string
+		// Table, Column and Type are the template parameters.
+		Table  string
+		Column string
+		Type   string
+	}
+
+	// sqlTemplate represents the up and down templates of a migration.
+	sqlTemplate struct {
+		up, down *template.Template
+		// needs lists the required parameters.
+		needs []string
+	}
+)
+
+var (
+	// identifierPattern matches the table and column names.
+	identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+	// templates are the SQL templates by name. The indexes are built concurrently,
+	// so the tables aren't locked for writes meanwhile.
+	templates = map[string]sqlTemplate{
+		TemplateCreateTable: {
+			up: template.Must(template.New("up").Parse(`CREATE TABLE IF NOT EXISTS {{.Table}} (
+    id UUID PRIMARY KEY,
+    created TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated TIMESTAMP NOT NULL DEFAULT NOW()
+);`)),
+			down:  template.Must(template.New("down").Parse(`DROP TABLE IF EXISTS {{.Table}};`)),
+			needs: []string{"table"},
+		},
+		TemplateAddColumn: {
+			up:    template.Must(template.New("up").Parse(`ALTER TABLE {{.Table}} ADD COLUMN IF NOT EXISTS {{.Column}} {{.Type}};`)),
+			down:  template.Must(template.New("down").Parse(`ALTER TABLE {{.Table}} DROP COLUMN IF EXISTS {{.Column}};`)),
+			needs: []string{"table", "column", "type"},
+		},
+		TemplateAddIndex: {
+			up: template.Must(template.New("up").Parse(DirectiveNoTransaction + `
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS {{.Table}}_{{.Column}}_index ON {{.Table}} ({{.Column}});`)),
+			down: template.Must(template.New("down").Parse(DirectiveNoTransaction + `
+
+DROP INDEX CONCURRENTLY IF EXISTS {{.Table}}_{{.Column}}_index;`)),
+			needs: []string{"table", "column"},
+		},
+	}
+)
+
+// Templates returns the names of the SQL templates.
+func Templates() []string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// render renders the up and down SQL of the template, both are empty without a template.
+func (o CreateOptions) render() (up, down string, err error) {
+	if o.Template == "" {
+		return "", "", nil
+	}
+
+	t, ok := templates[o.Template]
+	if !ok {
+		return "", "", fmt.Errorf("unknown template %q, expected one of %s", o.Template, strings.Join(Templates(), ", "))
+	}
+
+	values := map[string]string{"table": o.Table, "column": o.Column, "type": o.Type}
+	for _, param := range t.needs {
+		switch {
+		case values[param] == "":
+			return "", "", fmt.Errorf("template %s requires the %s", o.Template, param)
+		case param != "type" && !identifierPattern.MatchString(values[param]):
+			return "", "", fmt.Errorf("invalid %s %q", param, values[param])
+		}
+	}
+
+	var upSQL, downSQL strings.Builder
+	if err := t.up.Execute(&upSQL, o); err != nil {
+		return "", "", err
+	}
+	if err := t.down.Execute(&downSQL, o); err != nil {
+		return "", "", err
+	}
+	return upSQL.String(), downSQL.String(), nil
+}
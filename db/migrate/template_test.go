@@ -0,0 +1,98 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateOptions_Render(t *testing.T) {
+	tests := []struct {
+		testName string
+		opts     CreateOptions
+		up, down string
+		err      bool
+	}{
+		{
+			testName: "Empty",
+		},
+		{
+			testName: "CreateTable",
+			opts:     CreateOptions{Template: TemplateCreateTable, Table: "news"},
+			up: `CREATE TABLE IF NOT EXISTS news (
+    id UUID PRIMARY KEY,
+    created TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated TIMESTAMP NOT NULL DEFAULT NOW()
+);`,
+			down: "DROP TABLE IF EXISTS news;",
+		},
+		{
+			testName: "AddColumn",
+			opts:     CreateOptions{Template: TemplateAddColumn, Table: "users", Column: "discord", Type: "VARCHAR(255)"},
+			up:       "ALTER TABLE users ADD COLUMN IF NOT EXISTS discord VARCHAR(255);",
+			down:     "ALTER TABLE users DROP COLUMN IF EXISTS discord;",
+		},
+		{
+			testName: "AddIndex",
+			opts:     CreateOptions{Template: TemplateAddIndex, Table: "users", Column: "email"},
+			up:       DirectiveNoTransaction + "\n\nCREATE INDEX CONCURRENTLY IF NOT EXISTS users_email_index ON users (email);",
+			down:     DirectiveNoTransaction + "\n\nDROP INDEX CONCURRENTLY IF EXISTS users_email_index;",
+		},
+		{
+			testName: "UnknownTemplate",
+			opts:     CreateOptions{Template: "drop_table", Table: "users"},
+			err:      true,
+		},
+		{
+			testName: "MissingType",
+			opts:     CreateOptions{Template: TemplateAddColumn, Table: "users", Column: "discord"},
+			err:      true,
+		},
+		{
+			testName: "InvalidTable",
+			opts:     CreateOptions{Template: TemplateCreateTable, Table: "users; DROP TABLE users"},
+			err:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			up, down, err := tt.opts.render()
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.up, up)
+			assert.Equal(t, tt.down, down)
+		})
+	}
+}
+
+func TestCreateMigrationFiles_Template(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, CreateMigrationFiles(dir, "user_email_index", CreateOptions{Template: TemplateAddIndex, Table: "users", Column: "email"}))
+
+	// The created files are read back as a valid pair.
+	files := fstest.MapFS{}
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		require.NoError(t, err)
+		files["migrations/"+entry.Name()] = &fstest.MapFile{Data: data}
+	}
+
+	migrations, err := ReadMigrationFiles(time.Time{}, MigrationUp, files)
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	assert.Equal(t, "user_email_index", migrations[0].Name)
+	assert.True(t, migrations[0].NoTransaction)
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// errQuery is returned by the failing connections.
+var errQuery = errors.New("query failed")
+
+// failingConn is a recording connection which fails to run the statements.
+type failingConn struct {
+	recordingConn
+}
+
+func (c *failingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return nil, errQuery
+}
+
+// failingConnector opens the failing connections.
+type failingConnector struct {
+	connector
+}
+
+func (c failingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &failingConn{recordingConn{c.driver}}, nil
+}
+
+func TestTraceConnector(t *testing.T) {
+	cases := []struct {
+		testName      string
+		connector     driver.Connector
+		expectedError error
+		expectedCode  codes.Code
+	}{
+		{
+			testName:     "Success",
+			connector:    connector{&recordingDriver{}},
+			expectedCode: codes.Unset,
+		},
+		{
+			testName:      "Error",
+			connector:     failingConnector{connector{&recordingDriver{}}},
+			expectedError: errQuery,
+			expectedCode:  codes.Error,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			db := sql.OpenDB(tracedConnector{c.connector, tracer})
+			t.Cleanup(func() { db.Close() })
+
+			ctx, parent := tracer.Start(context.Background(), "parent")
+			_, err := db.ExecContext(ctx, "insert into user_storage (id) values ($1)", "secret")
+			parent.End()
+			assert.ErrorIs(t, err, c.expectedError)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 2)
+			span := spans[0]
+			assert.Equal(t, "INSERT", span.Name())
+			assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+			assert.Equal(t, c.expectedCode, span.Status().Code)
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+			assert.Contains(t, span.Attributes(), semconv.DBSystemPostgreSQL)
+			assert.Contains(t, span.Attributes(), semconv.DBQueryText("insert into user_storage (id) values ($1)"))
+			// The arguments aren't recorded.
+			for _, attr := range span.Attributes() {
+				assert.NotContains(t, attr.Value.Emit(), "secret")
+			}
+		})
+	}
+}
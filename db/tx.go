@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// maxTxAttempts is how many times a transaction failing to serialize is run.
+	maxTxAttempts = 3
+	// txRetryDelay is the delay before the first retry, it doubles with every retry.
+	txRetryDelay = 20 * time.Millisecond
+)
+
+type (
+	// Querier is implemented by both *sql.DB and *sql.Tx, so the repositories
+	// run their queries in the transaction of the context when there is one.
+	Querier interface {
+		ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+		QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+		QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	}
+
+	// TxManager runs functions in database transactions.
+	TxManager struct {
+		db   *sql.DB
+		opts *sql.TxOptions
+	}
+
+	// txKey is the context key of the transaction.
+	txKey struct{}
+
+	// txState represents the transaction of the context.
+	txState struct {
+		tx *sql.Tx
+		// depth is the number of the nested InTx calls, it names the savepoints.
+		depth int
+	}
+)
+
+// NewTxManager creates a new transaction manager.
+func NewTxManager(db *sql.DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+// WithIsolation returns a copy of the manager beginning the transactions with the isolation level.
+func (m *TxManager) WithIsolation(level sql.IsolationLevel) *TxManager {
+	return &TxManager{db: m.db, opts: &sql.TxOptions{Isolation: level}}
+}
+
+// InTx runs the function in a transaction, the repositories called with the context
+// passed to it run their queries in the transaction. The transaction is committed
+// if the function returns nil and rolled back otherwise.
+//
+// A nested call runs in a savepoint of the outer transaction, so its error rolls back
+// only its own changes if the outer function handles the error.
+//
+// The outermost call runs the function again if the transaction fails to serialize
+// or deadlocks, so the function must not have side effects outside of the database.
+func (m *TxManager) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return inSavepoint(ctx, state, fn)
+	}
+
+	delay := txRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := m.inTx(ctx, fn)
+		if err == nil || attempt == maxTxAttempts || !IsSerializationFailure(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// inTx runs the function in a new transaction.
+func (m *TxManager) inTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := m.db.BeginTx(ctx, m.opts)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, &txState{tx: tx})); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// inSavepoint runs the function in a savepoint of the transaction.
+func inSavepoint(ctx context.Context, state *txState, fn func(ctx context.Context) error) error {
+	nested := &txState{tx: state.tx, depth: state.depth + 1}
+	savepoint := fmt.Sprintf("sp_%d", nested.depth)
+
+	if _, err := state.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return fmt.Errorf("create savepoint: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, nested)); err != nil {
+		if _, rollbackErr := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("rollback to savepoint: %w", rollbackErr))
+		}
+		return err
+	}
+
+	if _, err := state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint); err != nil {
+		return fmt.Errorf("release savepoint: %w", err)
+	}
+	return nil
+}
+
+// QuerierFrom returns the transaction of the context, or the database outside of InTx.
+func QuerierFrom(ctx context.Context, db *sql.DB) Querier {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return state.tx
+	}
+	return db
+}
+
+// IsUniqueViolation reports whether the error is a violation of the unique constraint or index,
+// of any of them if the constraint is empty.
+func IsUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "23505" && (constraint == "" || pqErr.Constraint == constraint)
+}
+
+// IsSerializationFailure reports whether the error is a serialization failure or a deadlock,
+// the transactions failing with them may succeed when run again.
+func IsSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
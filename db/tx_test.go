@@ -0,0 +1,218 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDriver is a database driver which records the statements instead of running them.
+type recordingDriver struct {
+	log []string
+}
+
+type recordingConn struct {
+	driver *recordingDriver
+}
+
+type recordingTx struct {
+	driver *recordingDriver
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) {
+	return &recordingConn{d}, nil
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare isn't supported")
+}
+
+func (c *recordingConn) Close() error { return nil }
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *recordingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.driver.log = append(c.driver.log, "BEGIN")
+	return &recordingTx{c.driver}, nil
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.driver.log = append(c.driver.log, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (t *recordingTx) Commit() error {
+	t.driver.log = append(t.driver.log, "COMMIT")
+	return nil
+}
+
+func (t *recordingTx) Rollback() error {
+	t.driver.log = append(t.driver.log, "ROLLBACK")
+	return nil
+}
+
+// newRecordingDB opens a database recording its statements in the returned driver.
+func newRecordingDB(t *testing.T) (*sql.DB, *recordingDriver) {
+	d := &recordingDriver{}
+	db := sql.OpenDB(connector{d})
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+// connector opens the connections of the recording driver.
+type connector struct {
+	driver *recordingDriver
+}
+
+func (c connector) Connect(ctx context.Context) (driver.Conn, error) { return c.driver.Open("") }
+
+func (c connector) Driver() driver.Driver { return c.driver }
+
+// insert runs a statement in the transaction of the context.
+func insert(ctx context.Context, db *sql.DB, query string) error {
+	_, err := QuerierFrom(ctx, db).ExecContext(ctx, query)
+	return err
+}
+
+func TestTxManager_InTx(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	cases := []struct {
+		testName      string
+		fn            func(t *testing.T, ctx context.Context, m *TxManager, db *sql.DB) error
+		expectedError error
+		expectedLog   []string
+	}{
+		{
+			testName: "Commit",
+			fn: func(t *testing.T, ctx context.Context, m *TxManager, db *sql.DB) error {
+				return insert(ctx, db, "INSERT user")
+			},
+			expectedLog: []string{"BEGIN", "INSERT user", "COMMIT"},
+		},
+		{
+			testName: "Rollback",
+			fn: func(t *testing.T, ctx context.Context, m *TxManager, db *sql.DB) error {
+				require.NoError(t, insert(ctx, db, "INSERT user"))
+				return errFailed
+			},
+			expectedError: errFailed,
+			expectedLog:   []string{"BEGIN", "INSERT user", "ROLLBACK"},
+		},
+		{
+			testName: "NestedRelease",
+			fn: func(t *testing.T, ctx context.Context, m *TxManager, db *sql.DB) error {
+				require.NoError(t, insert(ctx, db, "INSERT user"))
+				return m.InTx(ctx, func(ctx context.Context) error {
+					return insert(ctx, db, "INSERT role")
+				})
+			},
+			expectedLog: []string{"BEGIN", "INSERT user", "SAVEPOINT sp_1", "INSERT role", "RELEASE SAVEPOINT sp_1", "COMMIT"},
+		},
+		{
+			testName: "NestedRollback",
+			fn: func(t *testing.T, ctx context.Context, m *TxManager, db *sql.DB) error {
+				require.NoError(t, insert(ctx, db, "INSERT user"))
+				err := m.InTx(ctx, func(ctx context.Context) error {
+					require.NoError(t, insert(ctx, db, "INSERT audit"))
+					return errFailed
+				})
+				assert.ErrorIs(t, err, errFailed)
+				// The outer transaction goes on without the nested changes.
+				return nil
+			},
+			expectedLog: []string{"BEGIN", "INSERT user", "SAVEPOINT sp_1", "INSERT audit", "ROLLBACK TO SAVEPOINT sp_1", "COMMIT"},
+		},
+		{
+			testName: "DeeplyNested",
+			fn: func(t *testing.T, ctx context.Context, m *TxManager, db *sql.DB) error {
+				return m.InTx(ctx, func(ctx context.Context) error {
+					return m.InTx(ctx, func(ctx context.Context) error { return nil })
+				})
+			},
+			expectedLog: []string{"BEGIN", "SAVEPOINT sp_1", "SAVEPOINT sp_2", "RELEASE SAVEPOINT sp_2", "RELEASE SAVEPOINT sp_1", "COMMIT"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			db, d := newRecordingDB(t)
+			m := NewTxManager(db)
+
+			err := m.InTx(context.Background(), func(ctx context.Context) error {
+				return tc.fn(t, ctx, m, db)
+			})
+
+			assert.ErrorIs(t, err, tc.expectedError)
+			assert.Equal(t, tc.expectedLog, d.log)
+		})
+	}
+}
+
+func TestTxManager_InTx_Retry(t *testing.T) {
+	db, d := newRecordingDB(t)
+	m := NewTxManager(db)
+
+	attempts := 0
+	err := m.InTx(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if attempts < 2 {
+			return fmt.Errorf("update balance: %w", &pq.Error{Code: "40001"})
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, []string{"BEGIN", "ROLLBACK", "BEGIN", "COMMIT"}, d.log)
+
+	// The failures persisting after all the attempts are returned.
+	attempts = 0
+	err = m.InTx(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return &pq.Error{Code: "40P01"}
+	})
+	assert.True(t, IsSerializationFailure(err))
+	assert.Equal(t, maxTxAttempts, attempts)
+}
+
+func TestTxManager_InTx_NoRetry(t *testing.T) {
+	db, _ := newRecordingDB(t)
+	m := NewTxManager(db)
+
+	attempts := 0
+	err := m.InTx(context.Background(), func(ctx context.Context) error {
+		attempts++
+		// A nested failure is retried along with the outermost transaction only.
+		return m.InTx(ctx, func(ctx context.Context) error {
+			return &pq.Error{Code: "23505"}
+		})
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestQuerierFrom(t *testing.T) {
+	db, _ := newRecordingDB(t)
+	m := NewTxManager(db)
+
+	assert.Equal(t, Querier(db), QuerierFrom(context.Background(), db))
+
+	err := m.InTx(context.Background(), func(ctx context.Context) error {
+		_, ok := QuerierFrom(ctx, db).(*sql.Tx)
+		assert.True(t, ok)
+		return nil
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,11 @@
+package health
+
+import "time"
+
+type (
+	// Config represents the configuration options for the health checks.
+	Config struct {
+		// Timeout limits each check, the probes of the orchestrator time out as well.
+		Timeout time.Duration `env:"HEALTH_CHECK_TIMEOUT" envDefault:"2s" yaml:"timeout"`
+	}
+)
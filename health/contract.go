@@ -0,0 +1,26 @@
+package health
+
+import "context"
+
+// This file defines the health check interfaces.
+
+type (
+	// Checker checks a dependency of the service.
+	Checker interface {
+		// Check returns an error if the dependency is unavailable.
+		Check(ctx context.Context) error
+	}
+
+	// CheckerFunc adapts a function to the Checker interface.
+	CheckerFunc func(ctx context.Context) error
+
+	// Pinger is implemented by *sql.DB.
+	Pinger interface {
+		PingContext(ctx context.Context) error
+	}
+)
+
+// Check calls the function.
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
@@ -0,0 +1,60 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/goccy/go-json"
+)
+
+// This file contains the health check http handlers.
+
+const (
+	pathLiveness  = "/healthz"
+	pathReadiness = "/readyz"
+)
+
+type (
+	// Handler represents the liveness and readiness probes of the orchestrator.
+	Handler struct {
+		registry *Registry
+	}
+)
+
+// NewHandler creates a new health check http handler.
+func NewHandler(registry *Registry) *Handler {
+	return &Handler{registry}
+}
+
+// RegisterHealthRouter registers the probe routes.
+func (h *Handler) RegisterHealthRouter(r chi.Router) {
+	r.Get(pathLiveness, h.Liveness)
+	r.Get(pathReadiness, h.Readiness)
+}
+
+// Liveness responds while the process is able to serve requests,
+// it doesn't check the dependencies, so they don't get the process restarted.
+func (h *Handler) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Report{Status: StatusOK})
+}
+
+// Readiness runs the checks and responds with 503 Service Unavailable
+// if any of the required ones fails.
+func (h *Handler) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := h.registry.Run(r.Context())
+
+	status := http.StatusOK
+	if report.Status == StatusFail {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// writeJSON writes the response in JSON format.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	// The probes must not be cached by proxies.
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
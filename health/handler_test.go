@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	var dbDown bool
+	registry := NewRegistry(Config{Timeout: time.Second})
+	registry.Register("database", CheckerFunc(func(ctx context.Context) error {
+		if dbDown {
+			return errors.New("connection refused")
+		}
+		return nil
+	}))
+	registry.RegisterOptional("cache", failing(errors.New("timeout")))
+
+	r := chi.NewRouter()
+	NewHandler(registry).RegisterHealthRouter(r)
+
+	cases := []struct {
+		testName       string
+		path           string
+		dbDown         bool
+		expectedStatus int
+		expectedReport string
+	}{
+		{
+			testName:       "Alive",
+			path:           "/healthz",
+			dbDown:         true,
+			expectedStatus: http.StatusOK,
+			expectedReport: StatusOK,
+		},
+		{
+			testName:       "Ready",
+			path:           "/readyz",
+			expectedStatus: http.StatusOK,
+			expectedReport: StatusDegraded,
+		},
+		{
+			testName:       "NotReady",
+			path:           "/readyz",
+			dbDown:         true,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedReport: StatusFail,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			dbDown = tc.dbDown
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			var report Report
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+			assert.Equal(t, tc.expectedReport, report.Status)
+			if tc.path == "/readyz" {
+				assert.Contains(t, report.Checks, "database")
+				assert.Equal(t, "timeout", report.Checks["cache"].Error)
+			}
+		})
+	}
+}
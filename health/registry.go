@@ -0,0 +1,139 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// This file contains the registry running the health checks.
+
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDegraded = "degraded"
+)
+
+type (
+	// Registry runs the registered checks concurrently, each within the timeout.
+	Registry struct {
+		timeout time.Duration
+		mu      sync.RWMutex
+		checks  []check
+	}
+
+	// check represents a registered check.
+	check struct {
+		name     string
+		checker  Checker
+		optional bool
+	}
+
+	// Report represents the result of all the checks.
+	Report struct {
+		// Status is ok if all the checks pass, degraded if only optional ones fail
+		// and fail otherwise.
+		Status string                 `json:"status"`
+		Checks map[string]CheckResult `json:"checks,omitempty"`
+	}
+
+	// CheckResult represents the result of a single check.
+	CheckResult struct {
+		Status     string `json:"status"`
+		Optional   bool   `json:"optional,omitempty"`
+		DurationMS int64  `json:"duration_ms"`
+		Error      string `json:"error,omitempty"`
+	}
+)
+
+// NewRegistry creates a new registry of checks.
+func NewRegistry(config Config) *Registry {
+	return &Registry{timeout: config.Timeout}
+}
+
+// Register adds a check the service can't work without.
+func (r *Registry) Register(name string, checker Checker) {
+	r.add(check{name: name, checker: checker})
+}
+
+// RegisterOptional adds a check of a dependency the service works without,
+// e.g. a mailer or a cache. Its failure degrades the service but keeps it ready.
+func (r *Registry) RegisterOptional(name string, checker Checker) {
+	r.add(check{name: name, checker: checker, optional: true})
+}
+
+// add adds the check, the names must be unique.
+func (r *Registry) add(c check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.checks {
+		if existing.name == c.name {
+			panic(fmt.Sprintf("health: check %s registered twice", c.name))
+		}
+	}
+	r.checks = append(r.checks, c)
+}
+
+// Run runs all the checks and reports their results.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]check(nil), r.checks...)
+	r.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status == StatusOK {
+			continue
+		}
+		if !c.optional {
+			report.Status = StatusFail
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// run runs the check within the timeout, even if the checker ignores the context.
+func (r *Registry) run(ctx context.Context, c check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timed out: %w", ctx.Err())
+	}
+
+	result := CheckResult{Status: StatusOK, Optional: c.optional, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status, result.Error = StatusFail, err.Error()
+	}
+	return result
+}
+
+// Ping checks the database answers the ping.
+func Ping(db Pinger) Checker {
+	return CheckerFunc(db.PingContext)
+}
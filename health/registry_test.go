@@ -0,0 +1,97 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ok is a check which passes.
+var ok = CheckerFunc(func(ctx context.Context) error { return nil })
+
+// failing returns a check which fails with the error.
+func failing(err error) Checker {
+	return CheckerFunc(func(ctx context.Context) error { return err })
+}
+
+func TestRegistry_Run(t *testing.T) {
+	errDown := errors.New("connection refused")
+
+	cases := []struct {
+		testName       string
+		register       func(r *Registry)
+		expectedStatus string
+	}{
+		{
+			testName:       "NoChecks",
+			register:       func(r *Registry) {},
+			expectedStatus: StatusOK,
+		},
+		{
+			testName: "AllPass",
+			register: func(r *Registry) {
+				r.Register("database", ok)
+				r.RegisterOptional("mailer", ok)
+			},
+			expectedStatus: StatusOK,
+		},
+		{
+			testName: "OptionalFails",
+			register: func(r *Registry) {
+				r.Register("database", ok)
+				r.RegisterOptional("mailer", failing(errDown))
+			},
+			expectedStatus: StatusDegraded,
+		},
+		{
+			testName: "RequiredFails",
+			register: func(r *Registry) {
+				r.Register("database", failing(errDown))
+				r.RegisterOptional("mailer", failing(errDown))
+			},
+			expectedStatus: StatusFail,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			r := NewRegistry(Config{Timeout: time.Second})
+			tc.register(r)
+
+			report := r.Run(context.Background())
+
+			assert.Equal(t, tc.expectedStatus, report.Status)
+			for _, result := range report.Checks {
+				if result.Status == StatusFail {
+					assert.Equal(t, errDown.Error(), result.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistry_Run_Timeout(t *testing.T) {
+	r := NewRegistry(Config{Timeout: 20 * time.Millisecond})
+	r.Register("stuck", CheckerFunc(func(ctx context.Context) error {
+		// The check doesn't honor the context.
+		time.Sleep(time.Second)
+		return nil
+	}))
+
+	start := time.Now()
+	report := r.Run(context.Background())
+
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Contains(t, report.Checks["stuck"].Error, "timed out")
+}
+
+func TestRegistry_Register_Twice(t *testing.T) {
+	r := NewRegistry(Config{Timeout: time.Second})
+	r.Register("database", ok)
+
+	assert.Panics(t, func() { r.RegisterOptional("database", ok) })
+}
@@ -0,0 +1,53 @@
+package ledger
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// This file defines the ledger related interfaces.
+
+type (
+	// Service represents the ledger service interface.
+	Service interface {
+		// Wallet returns the wallet of a user, creating it on first use.
+		Wallet(ctx context.Context, userID uuid.UUID) (Account, error)
+		// Post records a balanced transaction. Posting the same idempotency key again
+		// returns the stored transaction.
+		Post(ctx context.Context, t Transaction) (Transaction, error)
+		// Adjust gives or takes the currency of a player on behalf of staff.
+		Adjust(ctx context.Context, adjustment Adjustment) (Transaction, error)
+		// Reverse cancels a transaction with a new one, a transaction is reversed only once.
+		Reverse(ctx context.Context, id uuid.UUID, reversal Reversal) (Transaction, error)
+		// GetTransaction fetches a transaction by id.
+		GetTransaction(ctx context.Context, id uuid.UUID) (Transaction, error)
+		// FindByIdempotencyKey fetches a transaction by its idempotency key.
+		FindByIdempotencyKey(ctx context.Context, key string) (Transaction, error)
+		// Balance fetches the currency held by a player.
+		Balance(ctx context.Context, userID uuid.UUID) (Balance, error)
+		// Statement fetches the latest transactions of a player's wallet.
+		Statement(ctx context.Context, userID uuid.UUID, limit int) ([]StatementLine, error)
+	}
+
+	// Repository represents the ledger repository interface.
+	Repository interface {
+		// EnsureWallet returns the wallet of a user, creating it if it doesn't exist.
+		EnsureWallet(ctx context.Context, userID uuid.UUID) (Account, error)
+		// FindWallet returns the wallet of a user.
+		FindWallet(ctx context.Context, userID uuid.UUID) (Account, error)
+		// Post inserts the transaction with its entries in a single database transaction.
+		// The wallets of the entries are locked while posting and the posting fails with
+		// ErrInsufficientFunds if a wallet ends up negative, unless allowNegative is set.
+		// It reports false and returns the stored transaction if the idempotency key exists.
+		Post(ctx context.Context, t Transaction, allowNegative bool) (Transaction, bool, error)
+		// FindTransaction returns a transaction with its entries by id.
+		FindTransaction(ctx context.Context, id uuid.UUID) (Transaction, error)
+		// FindByIdempotencyKey returns a transaction with its entries by the idempotency key.
+		FindByIdempotencyKey(ctx context.Context, key string) (Transaction, error)
+		// Balance returns the sum of the account entries.
+		Balance(ctx context.Context, accountID uuid.UUID) (int64, error)
+		// Statement returns the latest entries of the account with the running balance.
+		Statement(ctx context.Context, accountID uuid.UUID, limit int) ([]StatementLine, error)
+	}
+)
@@ -0,0 +1,18 @@
+package ledger
+
+// This file contains ledger related errors.
+
+import "errors"
+
+// Define custom errors.
+var (
+	ErrAccountNotFound        = errors.New("ledger: account not found")
+	ErrTransactionNotFound    = errors.New("ledger: transaction not found")
+	ErrUnbalanced             = errors.New("ledger: transaction entries don't sum up to zero")
+	ErrInvalidEntries         = errors.New("ledger: invalid transaction entries")
+	ErrInvalidAmount          = errors.New("ledger: amount must not be zero")
+	ErrMissingIdempotencyKey  = errors.New("ledger: idempotency key is required")
+	ErrIdempotencyKeyConflict = errors.New("ledger: idempotency key is used by a different transaction")
+	ErrInsufficientFunds      = errors.New("ledger: insufficient funds")
+	ErrNotReversible          = errors.New("ledger: transaction can't be reversed")
+)
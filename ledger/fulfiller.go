@@ -0,0 +1,66 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/shop"
+)
+
+// This file connects the ledger to the shop.
+
+type (
+	// Fulfiller deposits paid currency products to wallets, it implements shop.Fulfiller.
+	Fulfiller struct {
+		service Service
+	}
+)
+
+// NewFulfiller creates a new shop fulfiller for currency products.
+func NewFulfiller(service Service) *Fulfiller {
+	return &Fulfiller{service}
+}
+
+// Fulfill moves the product currency times the item quantity from the shop account to the wallet.
+// The order item makes the idempotency key, so repeated calls deposit the currency only once.
+func (f *Fulfiller) Fulfill(ctx context.Context, order shop.Order, item shop.OrderItem, product shop.Product) error {
+	wallet, err := f.service.Wallet(ctx, order.UserID)
+	if err != nil {
+		return fmt.Errorf("find wallet: %w", err)
+	}
+
+	amount := product.CurrencyAmount * int64(item.Quantity)
+	_, err = f.service.Post(ctx, Transaction{
+		IdempotencyKey: purchaseKey(item),
+		Kind:           TransactionPurchase,
+		Description:    fmt.Sprintf("%s x%d, order %s", product.Name, item.Quantity, order.ID),
+		Entries: []Entry{
+			{AccountID: ShopAccountID, Amount: -amount},
+			{AccountID: wallet.ID, Amount: amount},
+		},
+	})
+	return err
+}
+
+// Revoke reverses the deposit of the order item, if any.
+func (f *Fulfiller) Revoke(ctx context.Context, order shop.Order, item shop.OrderItem, _ shop.Product) error {
+	t, err := f.service.FindByIdempotencyKey(ctx, purchaseKey(item))
+	if errors.Is(err, ErrTransactionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = f.service.Reverse(ctx, t.ID, Reversal{
+		Reason:   "refund of order " + order.ID.String(),
+		IssuedBy: "shop",
+	})
+	return err
+}
+
+// purchaseKey returns the idempotency key of the order item deposit.
+func purchaseKey(item shop.OrderItem) string {
+	return "shop:order-item:" + item.ID.String()
+}
@@ -0,0 +1,213 @@
+package ledger
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/api"
+	"github.com/GTA5-RP-Aristocracy/site-back/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+)
+
+// This file contains ledger related http handlers.
+
+const (
+	pathRoot          = "/ledger"
+	pathMe            = "/me"
+	pathBalance       = "/balance"
+	pathStatement     = "/statement"
+	pathAdmin         = "/admin"
+	pathAdjustments   = "/adjustments"
+	pathTransaction   = "/transactions/{id}"
+	pathReverse       = "/transactions/{id}/reverse"
+	pathUserBalance   = "/users/{userID}/balance"
+	pathUserStatement = "/users/{userID}/statement"
+)
+
+// errorMappings map the ledger errors to the problem responses.
+var errorMappings = []api.Mapping{
+	{Err: ErrAccountNotFound, Status: http.StatusNotFound, Code: "account_not_found"},
+	{Err: ErrTransactionNotFound, Status: http.StatusNotFound, Code: "transaction_not_found"},
+	{Err: ErrInvalidAmount, Status: http.StatusBadRequest, Code: "invalid_amount"},
+	{Err: ErrMissingIdempotencyKey, Status: http.StatusBadRequest, Code: "missing_idempotency_key"},
+	{Err: ErrUnbalanced, Status: http.StatusBadRequest, Code: "unbalanced"},
+	{Err: ErrInvalidEntries, Status: http.StatusBadRequest, Code: "invalid_entries"},
+	{Err: ErrIdempotencyKeyConflict, Status: http.StatusConflict, Code: "idempotency_key_conflict"},
+	{Err: ErrNotReversible, Status: http.StatusConflict, Code: "not_reversible"},
+	{Err: ErrInsufficientFunds, Status: http.StatusUnprocessableEntity, Code: "insufficient_funds"},
+}
+
+type (
+	// Handler represents a set of http handlers for the currency ledger.
+	Handler struct {
+		service Service
+		auth    auth.Config
+	}
+)
+
+// NewHandler creates a new ledger http handler.
+func NewHandler(service Service, authConfig auth.Config) *Handler {
+	return &Handler{service, authConfig}
+}
+
+// RegisterLedgerRouter registers ledger routes.
+func (h *Handler) RegisterLedgerRouter(externalRouter chi.Router) {
+	r := chi.NewRouter()
+
+	// Player wallet.
+	r.Route(pathMe, func(r chi.Router) {
+		r.Use(auth.RequireUser(h.auth))
+		r.Get(pathBalance, h.MyBalance)
+		r.Get(pathStatement, h.MyStatement)
+	})
+
+	// Staff management.
+	r.Route(pathAdmin, func(r chi.Router) {
+		r.Use(auth.RequireToken(h.auth.AdminToken))
+		r.Post(pathAdjustments, h.Adjust)
+		r.Get(pathTransaction, h.GetTransaction)
+		r.Post(pathReverse, h.Reverse)
+		r.Get(pathUserBalance, h.UserBalance)
+		r.Get(pathUserStatement, h.UserStatement)
+	})
+
+	externalRouter.Mount(pathRoot, r)
+}
+
+// MyBalance handles the balance request of the current user.
+func (h *Handler) MyBalance(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.UserID(r.Context())
+	h.balance(w, r, userID)
+}
+
+// MyStatement handles the statement request of the current user.
+func (h *Handler) MyStatement(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.UserID(r.Context())
+	h.statement(w, r, userID)
+}
+
+// UserBalance handles the staff request of a user's balance.
+func (h *Handler) UserBalance(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseID(w, r, "userID")
+	if !ok {
+		return
+	}
+	h.balance(w, r, userID)
+}
+
+// UserStatement handles the staff request of a user's statement.
+func (h *Handler) UserStatement(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseID(w, r, "userID")
+	if !ok {
+		return
+	}
+	h.statement(w, r, userID)
+}
+
+// Adjust handles the staff request to give or take the currency of a player.
+func (h *Handler) Adjust(w http.ResponseWriter, r *http.Request) {
+	var adjustment Adjustment
+	if err := api.DecodeJSON(w, r, &adjustment); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	t, err := h.service.Adjust(r.Context(), adjustment)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, t)
+}
+
+// GetTransaction handles the staff request of a transaction.
+func (h *Handler) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	t, err := h.service.GetTransaction(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, t)
+}
+
+// Reverse handles the staff request to cancel a transaction.
+func (h *Handler) Reverse(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	// The reason is optional, so is the body.
+	var reversal Reversal
+	if err := api.DecodeJSON(w, r, &reversal); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, err)
+		return
+	}
+
+	t, err := h.service.Reverse(r.Context(), id, reversal)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, t)
+}
+
+// balance writes the balance of the user.
+func (h *Handler) balance(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	balance, err := h.service.Balance(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, balance)
+}
+
+// statement writes the latest transactions of the user.
+func (h *Handler) statement(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	lines, err := h.service.Statement(r.Context(), userID, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, lines)
+}
+
+// parseID parses the uuid url parameter.
+func parseID(w http.ResponseWriter, r *http.Request, param string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, param))
+	if err != nil {
+		writeError(w, r, api.ErrInvalidID)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writeError writes the problem matching the error.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	api.WriteError(w, r, err, errorMappings...)
+}
+
+// writeJSON writes the response in JSON format.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
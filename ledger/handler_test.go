@@ -0,0 +1,145 @@
+package ledger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAuth signs the sessions of the tests.
+var testAuth = auth.Config{SessionSecret: "0123456789abcdef0123456789abcdef", SessionTTL: time.Hour}
+
+// newSession returns the signed session of the user.
+func newSession(t *testing.T, id uuid.UUID) string {
+	session, err := auth.NewSession(testAuth, id, time.Now())
+	require.NoError(t, err)
+	return session
+}
+
+type MockService struct {
+	Service
+	funcAdjust  func(adjustment Adjustment) (Transaction, error)
+	funcBalance func(userID uuid.UUID) (Balance, error)
+}
+
+func (m *MockService) Adjust(ctx context.Context, adjustment Adjustment) (Transaction, error) {
+	return m.funcAdjust(adjustment)
+}
+
+func (m *MockService) Balance(ctx context.Context, userID uuid.UUID) (Balance, error) {
+	return m.funcBalance(userID)
+}
+
+func TestHandlerMyBalance(t *testing.T) {
+	userID := uuid.New()
+
+	cases := []struct {
+		testName       string
+		session        string
+		expectedStatus int
+	}{
+		{
+			testName:       "ok",
+			session:        newSession(t, userID),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			testName:       "unauthenticated",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			router := chi.NewRouter()
+			handler := NewHandler(&MockService{
+				funcBalance: func(id uuid.UUID) (Balance, error) {
+					assert.Equal(t, userID, id)
+					return Balance{UserID: id, Amount: 100}, nil
+				},
+			}, testAuth)
+			handler.RegisterLedgerRouter(router)
+
+			req := httptest.NewRequest(http.MethodGet, "/ledger/me/balance", nil)
+			if tc.session != "" {
+				req.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: tc.session})
+			}
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestHandlerAdjust(t *testing.T) {
+	cases := []struct {
+		testName       string
+		requestBody    string
+		token          string
+		funcAdjust     func(adjustment Adjustment) (Transaction, error)
+		expectedStatus int
+	}{
+		{
+			testName:    "ok",
+			requestBody: `{"user_id":"123e4567-e89b-12d3-a456-426614174000","amount":100,"idempotency_key":"ticket-1","reason":"compensation"}`,
+			token:       "admin",
+			funcAdjust: func(adjustment Adjustment) (Transaction, error) {
+				assert.Equal(t, int64(100), adjustment.Amount)
+				return Transaction{Kind: TransactionAdjustment}, nil
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			testName:       "without token",
+			requestBody:    `{}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:    "insufficient funds",
+			requestBody: `{"user_id":"123e4567-e89b-12d3-a456-426614174000","amount":-100,"idempotency_key":"ticket-2"}`,
+			token:       "admin",
+			funcAdjust: func(adjustment Adjustment) (Transaction, error) {
+				return Transaction{}, ErrInsufficientFunds
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			testName:    "key reused",
+			requestBody: `{"user_id":"123e4567-e89b-12d3-a456-426614174000","amount":200,"idempotency_key":"ticket-1"}`,
+			token:       "admin",
+			funcAdjust: func(adjustment Adjustment) (Transaction, error) {
+				return Transaction{}, ErrIdempotencyKeyConflict
+			},
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			router := chi.NewRouter()
+			handler := NewHandler(&MockService{funcAdjust: tc.funcAdjust}, auth.Config{AdminToken: "admin"})
+			handler.RegisterLedgerRouter(router)
+
+			req := httptest.NewRequest(http.MethodPost, "/ledger/admin/adjustments", strings.NewReader(tc.requestBody))
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
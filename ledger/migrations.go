@@ -0,0 +1,8 @@
+package ledger
+
+import "embed"
+
+// Migrations holds the SQL migrations of the currency ledger tables.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
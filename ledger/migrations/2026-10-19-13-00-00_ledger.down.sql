@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS ledger_entry;
+DROP TABLE IF EXISTS ledger_transaction;
+DROP TABLE IF EXISTS ledger_account;
+DROP FUNCTION IF EXISTS ledger_check_balanced;
+DROP FUNCTION IF EXISTS ledger_forbid_change;
\ No newline at end of file
@@ -0,0 +1,61 @@
+CREATE TABLE IF NOT EXISTS ledger_account (
+    id UUID PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    user_id UUID UNIQUE REFERENCES user_storage (id),
+    created TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((kind = 'wallet') = (user_id IS NOT NULL))
+);
+
+INSERT INTO ledger_account (id, kind) VALUES
+    ('00000000-0000-0000-0000-000000000001', 'mint'),
+    ('00000000-0000-0000-0000-000000000002', 'shop')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS ledger_transaction (
+    id UUID PRIMARY KEY,
+    idempotency_key VARCHAR(255) NOT NULL UNIQUE,
+    kind VARCHAR(32) NOT NULL,
+    reverses_id UUID UNIQUE REFERENCES ledger_transaction (id),
+    description TEXT NOT NULL DEFAULT '',
+    issued_by VARCHAR(255) NOT NULL DEFAULT '',
+    created TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS ledger_entry (
+    transaction_id UUID NOT NULL REFERENCES ledger_transaction (id),
+    account_id UUID NOT NULL REFERENCES ledger_account (id),
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+    PRIMARY KEY (transaction_id, account_id)
+);
+
+CREATE INDEX IF NOT EXISTS ledger_entry_account_index ON ledger_entry (account_id);
+
+-- Every transaction must sum up to zero once its database transaction commits.
+CREATE OR REPLACE FUNCTION ledger_check_balanced() RETURNS TRIGGER AS $$
+BEGIN
+    IF (SELECT SUM(amount) FROM ledger_entry WHERE transaction_id = NEW.transaction_id) <> 0 THEN
+        RAISE EXCEPTION 'ledger transaction % is not balanced', NEW.transaction_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER ledger_entry_balanced
+    AFTER INSERT ON ledger_entry
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION ledger_check_balanced();
+
+-- Posted transactions are never changed, mistakes are fixed with reversals.
+CREATE OR REPLACE FUNCTION ledger_forbid_change() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger records are append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ledger_transaction_append_only
+    BEFORE UPDATE OR DELETE ON ledger_transaction
+    FOR EACH ROW EXECUTE FUNCTION ledger_forbid_change();
+
+CREATE TRIGGER ledger_entry_append_only
+    BEFORE UPDATE OR DELETE ON ledger_entry
+    FOR EACH ROW EXECUTE FUNCTION ledger_forbid_change();
\ No newline at end of file
@@ -0,0 +1,116 @@
+package ledger
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// This file defines the ledger models, e.g. accounts, transactions and their entries.
+
+const (
+	// AccountWallet holds the currency of a player.
+	AccountWallet AccountKind = "wallet"
+	// AccountMint issues the currency given by staff and rewards, its balance is the negated total of them.
+	AccountMint AccountKind = "mint"
+	// AccountShop issues the currency sold in the shop, its balance is the negated total of sales.
+	AccountShop AccountKind = "shop"
+
+	// TransactionPurchase moves the currency bought in the shop to a wallet.
+	TransactionPurchase TransactionKind = "purchase"
+	// TransactionAdjustment moves the currency between the mint and a wallet on behalf of staff.
+	TransactionAdjustment TransactionKind = "adjustment"
+	// TransactionReward moves the currency given by promo codes and referrals from the mint to a wallet.
+	TransactionReward TransactionKind = "reward"
+	// TransactionReversal cancels another transaction by posting its entries negated.
+	TransactionReversal TransactionKind = "reversal"
+)
+
+// The system accounts are created by the migration with the fixed ids.
+var (
+	MintAccountID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	ShopAccountID = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+)
+
+type (
+	// AccountKind represents the owner type of an account.
+	AccountKind string
+
+	// TransactionKind represents the reason of a transaction.
+	TransactionKind string
+
+	// Account represents a ledger account. Only wallets belong to a user.
+	Account struct {
+		ID      uuid.UUID     `json:"id"`
+		Kind    AccountKind   `json:"kind"`
+		UserID  uuid.NullUUID `json:"user_id"`
+		Created time.Time     `json:"created"`
+	}
+
+	// Transaction represents an atomic set of entries that sum up to zero.
+	// Transactions are never changed or deleted, mistakes are fixed with reversals.
+	Transaction struct {
+		ID uuid.UUID `json:"id"`
+		// IdempotencyKey makes retried postings create the transaction only once.
+		IdempotencyKey string          `json:"idempotency_key"`
+		Kind           TransactionKind `json:"kind"`
+		ReversesID     uuid.NullUUID   `json:"reverses_id"`
+		Description    string          `json:"description"`
+		IssuedBy       string          `json:"issued_by,omitempty"`
+		Created        time.Time       `json:"created"`
+		Entries        []Entry         `json:"entries"`
+	}
+
+	// Entry represents a change of an account balance, positive amounts credit the account.
+	Entry struct {
+		AccountID uuid.UUID `json:"account_id"`
+		Amount    int64     `json:"amount"`
+	}
+
+	// Balance represents the currency held by a player.
+	Balance struct {
+		UserID uuid.UUID `json:"user_id"`
+		Amount int64     `json:"amount"`
+	}
+
+	// StatementLine represents a transaction as seen in a player's wallet.
+	StatementLine struct {
+		TransactionID uuid.UUID       `json:"transaction_id"`
+		Kind          TransactionKind `json:"kind"`
+		Description   string          `json:"description"`
+		Amount        int64           `json:"amount"`
+		// Balance is the wallet balance right after the transaction.
+		Balance int64     `json:"balance"`
+		Created time.Time `json:"created"`
+	}
+
+	// Adjustment represents a staff request to give or take the currency of a player.
+	Adjustment struct {
+		UserID uuid.UUID `json:"user_id"`
+		// Amount is added to the wallet, negative amounts take the currency away.
+		Amount         int64  `json:"amount"`
+		IdempotencyKey string `json:"idempotency_key"`
+		Reason         string `json:"reason"`
+		IssuedBy       string `json:"issued_by"`
+	}
+
+	// Reversal represents a request to cancel a transaction.
+	Reversal struct {
+		Reason   string `json:"reason"`
+		IssuedBy string `json:"issued_by"`
+	}
+)
+
+// Sum returns the sum of the entry amounts, it is zero for a balanced transaction.
+func (t Transaction) Sum() int64 {
+	var sum int64
+	for _, e := range t.Entries {
+		sum += e.Amount
+	}
+	return sum
+}
+
+// ReversalKey returns the idempotency key of the reversal of the transaction.
+func ReversalKey(id uuid.UUID) string {
+	return "reversal:" + id.String()
+}
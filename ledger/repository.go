@@ -0,0 +1,219 @@
+package ledger
+
+// This file contains ledger repository related code.
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/db"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	accountColumns     = "id, kind, user_id, created"
+	transactionColumns = "id, idempotency_key, kind, reverses_id, description, issued_by, created"
+)
+
+type (
+	// repository implements the Repository interface.
+	repository struct {
+		db *sql.DB
+		tx *db.TxManager
+	}
+)
+
+// errPosted is returned by the transaction of an already posted ledger transaction, rolling it back.
+var errPosted = errors.New("ledger: transaction is already posted")
+
+// NewRepository creates a new ledger repository.
+// It runs the queries in the transaction of the context, see db.TxManager.
+func NewRepository(sqlDB *sql.DB) Repository {
+	return &repository{sqlDB, db.NewTxManager(sqlDB)}
+}
+
+// querier returns the transaction of the context or the database.
+func (r *repository) querier(ctx context.Context) db.Querier {
+	return db.QuerierFrom(ctx, r.db)
+}
+
+// EnsureWallet returns the wallet of a user, creating it if it doesn't exist.
+func (r *repository) EnsureWallet(ctx context.Context, userID uuid.UUID) (Account, error) {
+	_, err := r.querier(ctx).ExecContext(ctx,
+		"INSERT INTO ledger_account (id, kind, user_id) VALUES ($1, $2, $3) ON CONFLICT (user_id) DO NOTHING",
+		uuid.New(), AccountWallet, userID)
+	if err != nil {
+		return Account{}, err
+	}
+	return r.FindWallet(ctx, userID)
+}
+
+// FindWallet returns the wallet of a user.
+func (r *repository) FindWallet(ctx context.Context, userID uuid.UUID) (Account, error) {
+	var a Account
+	err := r.querier(ctx).QueryRowContext(ctx, "SELECT "+accountColumns+" FROM ledger_account WHERE user_id = $1", userID).
+		Scan(&a.ID, &a.Kind, &a.UserID, &a.Created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Account{}, ErrAccountNotFound
+	}
+	return a, err
+}
+
+// Post inserts the transaction with its entries in a single database transaction.
+func (r *repository) Post(ctx context.Context, t Transaction, allowNegative bool) (Transaction, bool, error) {
+	accounts := make([]uuid.UUID, 0, len(t.Entries))
+	var debited []uuid.UUID
+	for _, e := range t.Entries {
+		accounts = append(accounts, e.AccountID)
+		if e.Amount < 0 {
+			debited = append(debited, e.AccountID)
+		}
+	}
+
+	err := r.tx.InTx(ctx, func(ctx context.Context) error {
+		q := r.querier(ctx)
+
+		// Lock the wallets in a stable order, so concurrent postings don't deadlock
+		// and the balance check below sees every committed entry.
+		// The system accounts aren't locked, they may go negative.
+		var found int
+		err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM ledger_account WHERE id = ANY($1)", pq.Array(accounts)).Scan(&found)
+		if err != nil {
+			return fmt.Errorf("find accounts: %w", err)
+		}
+		if found != len(accounts) {
+			return ErrAccountNotFound
+		}
+		_, err = q.ExecContext(ctx,
+			"SELECT id FROM ledger_account WHERE id = ANY($1) AND kind = $2 ORDER BY id FOR UPDATE",
+			pq.Array(accounts), AccountWallet)
+		if err != nil {
+			return fmt.Errorf("lock wallets: %w", err)
+		}
+
+		err = q.QueryRowContext(ctx,
+			"INSERT INTO ledger_transaction (id, idempotency_key, kind, reverses_id, description, issued_by) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING RETURNING created",
+			t.ID, t.IdempotencyKey, t.Kind, t.ReversesID, t.Description, t.IssuedBy).
+			Scan(&t.Created)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errPosted
+		}
+		if err != nil {
+			return fmt.Errorf("insert transaction: %w", err)
+		}
+
+		for _, e := range t.Entries {
+			_, err := q.ExecContext(ctx,
+				"INSERT INTO ledger_entry (transaction_id, account_id, amount) VALUES ($1, $2, $3)",
+				t.ID, e.AccountID, e.Amount)
+			if err != nil {
+				return fmt.Errorf("insert entry: %w", err)
+			}
+		}
+
+		if !allowNegative && len(debited) > 0 {
+			var overdrawn bool
+			err := q.QueryRowContext(ctx,
+				`SELECT EXISTS (
+					SELECT 1 FROM ledger_entry e JOIN ledger_account a ON a.id = e.account_id
+					WHERE e.account_id = ANY($1) AND a.kind = $2
+					GROUP BY e.account_id HAVING SUM(e.amount) < 0
+				)`,
+				pq.Array(debited), AccountWallet).Scan(&overdrawn)
+			if err != nil {
+				return fmt.Errorf("check balances: %w", err)
+			}
+			if overdrawn {
+				return ErrInsufficientFunds
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errPosted) {
+		// The transaction has already been posted.
+		stored, err := r.FindByIdempotencyKey(ctx, t.IdempotencyKey)
+		if errors.Is(err, ErrTransactionNotFound) && t.ReversesID.Valid {
+			// Another reversal of the same transaction, posted with a different key.
+			return Transaction{}, false, ErrNotReversible
+		}
+		return stored, false, err
+	}
+	if err != nil {
+		return Transaction{}, false, err
+	}
+	return t, true, nil
+}
+
+// FindTransaction returns a transaction with its entries by id.
+func (r *repository) FindTransaction(ctx context.Context, id uuid.UUID) (Transaction, error) {
+	return r.findTransaction(ctx, "id", id)
+}
+
+// FindByIdempotencyKey returns a transaction with its entries by the idempotency key.
+func (r *repository) FindByIdempotencyKey(ctx context.Context, key string) (Transaction, error) {
+	return r.findTransaction(ctx, "idempotency_key", key)
+}
+
+// Balance returns the sum of the account entries.
+func (r *repository) Balance(ctx context.Context, accountID uuid.UUID) (int64, error) {
+	var balance int64
+	err := r.querier(ctx).QueryRowContext(ctx, "SELECT COALESCE(SUM(amount), 0) FROM ledger_entry WHERE account_id = $1", accountID).Scan(&balance)
+	return balance, err
+}
+
+// Statement returns the latest entries of the account with the running balance.
+func (r *repository) Statement(ctx context.Context, accountID uuid.UUID, limit int) ([]StatementLine, error) {
+	rows, err := r.querier(ctx).QueryContext(ctx,
+		`SELECT id, kind, description, amount, balance, created FROM (
+			SELECT t.id, t.kind, t.description, e.amount, t.created,
+				SUM(e.amount) OVER (ORDER BY t.created, t.id) AS balance
+			FROM ledger_entry e JOIN ledger_transaction t ON t.id = e.transaction_id
+			WHERE e.account_id = $1
+		) s ORDER BY created DESC, id DESC LIMIT $2`,
+		accountID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []StatementLine{}
+	for rows.Next() {
+		var l StatementLine
+		if err := rows.Scan(&l.TransactionID, &l.Kind, &l.Description, &l.Amount, &l.Balance, &l.Created); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// findTransaction returns a transaction with its entries by the unique column.
+func (r *repository) findTransaction(ctx context.Context, column string, value any) (Transaction, error) {
+	var t Transaction
+	err := r.querier(ctx).QueryRowContext(ctx, "SELECT "+transactionColumns+" FROM ledger_transaction WHERE "+column+" = $1", value).
+		Scan(&t.ID, &t.IdempotencyKey, &t.Kind, &t.ReversesID, &t.Description, &t.IssuedBy, &t.Created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Transaction{}, ErrTransactionNotFound
+	}
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	rows, err := r.querier(ctx).QueryContext(ctx, "SELECT account_id, amount FROM ledger_entry WHERE transaction_id = $1 ORDER BY account_id", t.ID)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("find entries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.AccountID, &e.Amount); err != nil {
+			return Transaction{}, err
+		}
+		t.Entries = append(t.Entries, e)
+	}
+	return t, rows.Err()
+}
@@ -0,0 +1,239 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// This file contains the ledger service implementation.
+
+// tracer traces the service methods.
+var tracer = otel.Tracer("github.com/GTA5-RP-Aristocracy/site-back/ledger")
+
+const (
+	// maxStatement limits the number of statement lines fetched at once.
+	maxStatement = 100
+)
+
+type (
+	// service implements the Service interface.
+	service struct {
+		repo Repository
+	}
+)
+
+// NewService creates a new ledger service.
+func NewService(repo Repository) Service {
+	return &service{repo}
+}
+
+// Wallet returns the wallet of a user, creating it on first use.
+func (s *service) Wallet(ctx context.Context, userID uuid.UUID) (Account, error) {
+	ctx, span := tracer.Start(ctx, "ledger.Wallet")
+	defer span.End()
+
+	return s.repo.EnsureWallet(ctx, userID)
+}
+
+// Post records a balanced transaction, wallets can't go negative.
+func (s *service) Post(ctx context.Context, t Transaction) (Transaction, error) {
+	ctx, span := tracer.Start(ctx, "ledger.Post")
+	defer span.End()
+
+	return s.post(ctx, t, false)
+}
+
+// Adjust gives or takes the currency of a player on behalf of staff.
+// The currency comes from and goes back to the mint account.
+func (s *service) Adjust(ctx context.Context, adjustment Adjustment) (Transaction, error) {
+	ctx, span := tracer.Start(ctx, "ledger.Adjust")
+	defer span.End()
+
+	if adjustment.Amount == 0 {
+		return Transaction{}, ErrInvalidAmount
+	}
+
+	wallet, err := s.repo.EnsureWallet(ctx, adjustment.UserID)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("ensure wallet: %w", err)
+	}
+
+	return s.post(ctx, Transaction{
+		IdempotencyKey: adjustment.IdempotencyKey,
+		Kind:           TransactionAdjustment,
+		Description:    adjustment.Reason,
+		IssuedBy:       adjustment.IssuedBy,
+		Entries: []Entry{
+			{AccountID: MintAccountID, Amount: -adjustment.Amount},
+			{AccountID: wallet.ID, Amount: adjustment.Amount},
+		},
+	}, false)
+}
+
+// Reverse cancels a transaction by posting its entries negated.
+// A reversal may leave a wallet negative, e.g. when refunded currency has already been spent,
+// the debt is then covered by the next deposits.
+func (s *service) Reverse(ctx context.Context, id uuid.UUID, reversal Reversal) (Transaction, error) {
+	ctx, span := tracer.Start(ctx, "ledger.Reverse")
+	defer span.End()
+
+	original, err := s.repo.FindTransaction(ctx, id)
+	if err != nil {
+		return Transaction{}, err
+	}
+	if original.Kind == TransactionReversal {
+		return Transaction{}, ErrNotReversible
+	}
+
+	entries := make([]Entry, 0, len(original.Entries))
+	for _, e := range original.Entries {
+		entries = append(entries, Entry{AccountID: e.AccountID, Amount: -e.Amount})
+	}
+
+	description := reversal.Reason
+	if description == "" {
+		description = "reversal of " + original.Description
+	}
+
+	return s.post(ctx, Transaction{
+		IdempotencyKey: ReversalKey(original.ID),
+		Kind:           TransactionReversal,
+		ReversesID:     uuid.NullUUID{UUID: original.ID, Valid: true},
+		Description:    description,
+		IssuedBy:       reversal.IssuedBy,
+		Entries:        entries,
+	}, true)
+}
+
+// GetTransaction fetches a transaction by id.
+func (s *service) GetTransaction(ctx context.Context, id uuid.UUID) (Transaction, error) {
+	ctx, span := tracer.Start(ctx, "ledger.GetTransaction")
+	defer span.End()
+
+	return s.repo.FindTransaction(ctx, id)
+}
+
+// FindByIdempotencyKey fetches a transaction by its idempotency key.
+func (s *service) FindByIdempotencyKey(ctx context.Context, key string) (Transaction, error) {
+	ctx, span := tracer.Start(ctx, "ledger.FindByIdempotencyKey")
+	defer span.End()
+
+	return s.repo.FindByIdempotencyKey(ctx, key)
+}
+
+// Balance fetches the currency held by a player, players without a wallet have nothing.
+func (s *service) Balance(ctx context.Context, userID uuid.UUID) (Balance, error) {
+	ctx, span := tracer.Start(ctx, "ledger.Balance")
+	defer span.End()
+
+	wallet, err := s.repo.FindWallet(ctx, userID)
+	if errors.Is(err, ErrAccountNotFound) {
+		return Balance{UserID: userID}, nil
+	}
+	if err != nil {
+		return Balance{}, err
+	}
+
+	amount, err := s.repo.Balance(ctx, wallet.ID)
+	if err != nil {
+		return Balance{}, err
+	}
+	return Balance{UserID: userID, Amount: amount}, nil
+}
+
+// Statement fetches the latest transactions of a player's wallet.
+func (s *service) Statement(ctx context.Context, userID uuid.UUID, limit int) ([]StatementLine, error) {
+	ctx, span := tracer.Start(ctx, "ledger.Statement")
+	defer span.End()
+
+	wallet, err := s.repo.FindWallet(ctx, userID)
+	if errors.Is(err, ErrAccountNotFound) {
+		return []StatementLine{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > maxStatement {
+		limit = maxStatement
+	}
+	return s.repo.Statement(ctx, wallet.ID, limit)
+}
+
+// post validates and stores the transaction. A retry with the same idempotency key
+// returns the stored transaction as long as it moves the same amounts.
+func (s *service) post(ctx context.Context, t Transaction, allowNegative bool) (Transaction, error) {
+	t.IdempotencyKey = strings.TrimSpace(t.IdempotencyKey)
+	if t.IdempotencyKey == "" {
+		return Transaction{}, ErrMissingIdempotencyKey
+	}
+	if err := validateEntries(t.Entries); err != nil {
+		return Transaction{}, err
+	}
+
+	t.ID = uuid.New()
+	stored, created, err := s.repo.Post(ctx, t, allowNegative)
+	if err != nil {
+		return Transaction{}, err
+	}
+	if !created && !sameEntries(stored.Entries, t.Entries) {
+		return Transaction{}, ErrIdempotencyKeyConflict
+	}
+	return stored, nil
+}
+
+// validateEntries checks that the entries move the currency between different accounts
+// and sum up to zero.
+func validateEntries(entries []Entry) error {
+	if len(entries) < 2 {
+		return ErrInvalidEntries
+	}
+
+	var sum int64
+	accounts := make(map[uuid.UUID]bool, len(entries))
+	for _, e := range entries {
+		if e.Amount == 0 {
+			return ErrInvalidAmount
+		}
+		if e.AccountID == uuid.Nil || accounts[e.AccountID] {
+			return ErrInvalidEntries
+		}
+		accounts[e.AccountID] = true
+		sum += e.Amount
+	}
+
+	if sum != 0 {
+		return ErrUnbalanced
+	}
+	return nil
+}
+
+// sameEntries reports whether both entry sets move the same amounts.
+func sameEntries(a, b []Entry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	a, b = sortedEntries(a), sortedEntries(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedEntries returns a copy of the entries sorted by account.
+func sortedEntries(entries []Entry) []Entry {
+	sorted := append([]Entry(nil), entries...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].AccountID.String() < sorted[j].AccountID.String()
+	})
+	return sorted
+}
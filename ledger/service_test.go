@@ -0,0 +1,255 @@
+package ledger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/shop"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) EnsureWallet(ctx context.Context, userID uuid.UUID) (Account, error) {
+	args := m.Called(userID)
+	return args.Get(0).(Account), args.Error(1)
+}
+
+func (m *MockRepo) FindWallet(ctx context.Context, userID uuid.UUID) (Account, error) {
+	args := m.Called(userID)
+	return args.Get(0).(Account), args.Error(1)
+}
+
+func (m *MockRepo) Post(ctx context.Context, t Transaction, allowNegative bool) (Transaction, bool, error) {
+	args := m.Called(t, allowNegative)
+	return args.Get(0).(Transaction), args.Bool(1), args.Error(2)
+}
+
+func (m *MockRepo) FindTransaction(ctx context.Context, id uuid.UUID) (Transaction, error) {
+	args := m.Called(id)
+	return args.Get(0).(Transaction), args.Error(1)
+}
+
+func (m *MockRepo) FindByIdempotencyKey(ctx context.Context, key string) (Transaction, error) {
+	args := m.Called(key)
+	return args.Get(0).(Transaction), args.Error(1)
+}
+
+func (m *MockRepo) Balance(ctx context.Context, accountID uuid.UUID) (int64, error) {
+	args := m.Called(accountID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) Statement(ctx context.Context, accountID uuid.UUID, limit int) ([]StatementLine, error) {
+	args := m.Called(accountID, limit)
+	return args.Get(0).([]StatementLine), args.Error(1)
+}
+
+// postStored makes the Post call store the posted transaction.
+func postStored(mockRepo *MockRepo, allowNegative bool) *mock.Call {
+	post := mockRepo.On("Post", mock.Anything, allowNegative)
+	return post.Run(func(args mock.Arguments) {
+		post.ReturnArguments = mock.Arguments{args.Get(0).(Transaction), true, nil}
+	})
+}
+
+func TestService_Post_Invalid(t *testing.T) {
+	wallet := uuid.New()
+
+	cases := []struct {
+		testName      string
+		transaction   Transaction
+		expectedError error
+	}{
+		{
+			testName: "unbalanced",
+			transaction: Transaction{IdempotencyKey: "key", Entries: []Entry{
+				{AccountID: ShopAccountID, Amount: -100},
+				{AccountID: wallet, Amount: 90},
+			}},
+			expectedError: ErrUnbalanced,
+		},
+		{
+			testName: "single entry",
+			transaction: Transaction{IdempotencyKey: "key", Entries: []Entry{
+				{AccountID: wallet, Amount: 100},
+			}},
+			expectedError: ErrInvalidEntries,
+		},
+		{
+			testName: "same account twice",
+			transaction: Transaction{IdempotencyKey: "key", Entries: []Entry{
+				{AccountID: wallet, Amount: -100},
+				{AccountID: wallet, Amount: 100},
+			}},
+			expectedError: ErrInvalidEntries,
+		},
+		{
+			testName: "zero amount",
+			transaction: Transaction{IdempotencyKey: "key", Entries: []Entry{
+				{AccountID: ShopAccountID, Amount: 0},
+				{AccountID: wallet, Amount: 0},
+			}},
+			expectedError: ErrInvalidAmount,
+		},
+		{
+			testName: "missing idempotency key",
+			transaction: Transaction{IdempotencyKey: " ", Entries: []Entry{
+				{AccountID: ShopAccountID, Amount: -100},
+				{AccountID: wallet, Amount: 100},
+			}},
+			expectedError: ErrMissingIdempotencyKey,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			svc := NewService(mockRepo)
+
+			_, err := svc.Post(context.Background(), tc.transaction)
+
+			assert.ErrorIs(t, err, tc.expectedError)
+			mockRepo.AssertNotCalled(t, "Post", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_Post_Idempotent(t *testing.T) {
+	wallet := uuid.New()
+	stored := Transaction{ID: uuid.New(), IdempotencyKey: "key", Entries: []Entry{
+		{AccountID: wallet, Amount: 100},
+		{AccountID: ShopAccountID, Amount: -100},
+	}}
+
+	cases := []struct {
+		testName      string
+		amount        int64
+		expectedError error
+	}{
+		{
+			testName: "same transaction",
+			amount:   100,
+		},
+		{
+			testName:      "different transaction",
+			amount:        200,
+			expectedError: ErrIdempotencyKeyConflict,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			svc := NewService(mockRepo)
+
+			mockRepo.On("Post", mock.Anything, false).Return(stored, false, nil)
+
+			posted, err := svc.Post(context.Background(), Transaction{IdempotencyKey: "key", Entries: []Entry{
+				{AccountID: ShopAccountID, Amount: -tc.amount},
+				{AccountID: wallet, Amount: tc.amount},
+			}})
+
+			assert.ErrorIs(t, err, tc.expectedError)
+			if tc.expectedError == nil {
+				assert.Equal(t, stored.ID, posted.ID)
+			}
+		})
+	}
+}
+
+func TestService_Adjust(t *testing.T) {
+	userID := uuid.New()
+	wallet := Account{ID: uuid.New(), Kind: AccountWallet, UserID: uuid.NullUUID{UUID: userID, Valid: true}}
+
+	mockRepo := new(MockRepo)
+	svc := NewService(mockRepo)
+
+	mockRepo.On("EnsureWallet", userID).Return(wallet, nil)
+	postStored(mockRepo, false)
+
+	posted, err := svc.Adjust(context.Background(), Adjustment{UserID: userID, Amount: -50, IdempotencyKey: "ticket-1", Reason: "duplicated item", IssuedBy: "admin"})
+	require.NoError(t, err)
+
+	assert.Equal(t, TransactionAdjustment, posted.Kind)
+	assert.ElementsMatch(t, []Entry{{AccountID: MintAccountID, Amount: 50}, {AccountID: wallet.ID, Amount: -50}}, posted.Entries)
+	assert.Equal(t, "admin", posted.IssuedBy)
+
+	_, err = svc.Adjust(context.Background(), Adjustment{UserID: userID, IdempotencyKey: "ticket-2"})
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+}
+
+func TestService_Reverse(t *testing.T) {
+	wallet := uuid.New()
+	original := Transaction{ID: uuid.New(), Kind: TransactionPurchase, Description: "coins", Entries: []Entry{
+		{AccountID: ShopAccountID, Amount: -100},
+		{AccountID: wallet, Amount: 100},
+	}}
+	reversal := Transaction{ID: uuid.New(), Kind: TransactionReversal, ReversesID: uuid.NullUUID{UUID: original.ID, Valid: true}}
+
+	mockRepo := new(MockRepo)
+	svc := NewService(mockRepo)
+
+	mockRepo.On("FindTransaction", original.ID).Return(original, nil)
+	mockRepo.On("FindTransaction", reversal.ID).Return(reversal, nil)
+	// Reversals may leave the wallet negative.
+	postStored(mockRepo, true)
+
+	posted, err := svc.Reverse(context.Background(), original.ID, Reversal{IssuedBy: "admin"})
+	require.NoError(t, err)
+
+	assert.Equal(t, TransactionReversal, posted.Kind)
+	assert.Equal(t, ReversalKey(original.ID), posted.IdempotencyKey)
+	assert.Equal(t, original.ID, posted.ReversesID.UUID)
+	assert.ElementsMatch(t, []Entry{{AccountID: ShopAccountID, Amount: 100}, {AccountID: wallet, Amount: -100}}, posted.Entries)
+
+	_, err = svc.Reverse(context.Background(), reversal.ID, Reversal{})
+	assert.ErrorIs(t, err, ErrNotReversible)
+}
+
+func TestService_Balance_NoWallet(t *testing.T) {
+	userID := uuid.New()
+
+	mockRepo := new(MockRepo)
+	svc := NewService(mockRepo)
+
+	mockRepo.On("FindWallet", userID).Return(Account{}, ErrAccountNotFound)
+
+	balance, err := svc.Balance(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Equal(t, Balance{UserID: userID}, balance)
+
+	lines, err := svc.Statement(context.Background(), userID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, lines)
+}
+
+func TestFulfiller(t *testing.T) {
+	userID := uuid.New()
+	wallet := Account{ID: uuid.New(), Kind: AccountWallet}
+	order := shop.Order{ID: uuid.New(), UserID: userID}
+	item := shop.OrderItem{ID: uuid.New(), Quantity: 3}
+	product := shop.Product{Name: "Coins", Kind: shop.ProductCurrency, CurrencyAmount: 500}
+
+	mockRepo := new(MockRepo)
+	fulfiller := NewFulfiller(NewService(mockRepo))
+
+	mockRepo.On("EnsureWallet", userID).Return(wallet, nil)
+	postStored(mockRepo, false)
+
+	require.NoError(t, fulfiller.Fulfill(context.Background(), order, item, product))
+
+	deposit := mockRepo.Calls[len(mockRepo.Calls)-1].Arguments.Get(0).(Transaction)
+	assert.Equal(t, "shop:order-item:"+item.ID.String(), deposit.IdempotencyKey)
+	assert.Equal(t, TransactionPurchase, deposit.Kind)
+	assert.ElementsMatch(t, []Entry{{AccountID: ShopAccountID, Amount: -1500}, {AccountID: wallet.ID, Amount: 1500}}, deposit.Entries)
+
+	// Items that were never deposited have nothing to revoke.
+	mockRepo.On("FindByIdempotencyKey", mock.Anything).Return(Transaction{}, ErrTransactionNotFound)
+	assert.NoError(t, fulfiller.Revoke(context.Background(), order, item, product))
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// This file contains the HTTP server metrics.
+
+// unmatchedRoute labels the requests no route matched, so random paths
+// don't create new series.
+const unmatchedRoute = "unmatched"
+
+type (
+	// HTTP collects the metrics of the HTTP requests per route pattern.
+	HTTP struct {
+		requests     *prometheus.CounterVec
+		duration     *prometheus.HistogramVec
+		responseSize *prometheus.HistogramVec
+		inFlight     prometheus.Gauge
+	}
+)
+
+// NewHTTP creates the HTTP metrics and registers them.
+func NewHTTP(reg prometheus.Registerer) *HTTP {
+	m := &HTTP{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of the HTTP requests served.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Duration of the HTTP requests.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_response_size_bytes",
+			Help:    "Size of the HTTP response bodies.",
+			Buckets: prometheus.ExponentialBuckets(128, 4, 8),
+		}, []string{"method", "route"}),
+		// The route isn't known until the request is routed.
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of the HTTP requests being served.",
+		}),
+	}
+	reg.MustRegister(m.requests, m.duration, m.responseSize, m.inFlight)
+	return m
+}
+
+// Middleware records the metrics of the requests. It has to run before the chi router
+// routes the request, so the route pattern is known once the request is served.
+func (m *HTTP) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			// Nothing has been written, net/http responds with 200 OK.
+			status = http.StatusOK
+		}
+
+		m.requests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		m.duration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+		m.responseSize.WithLabelValues(r.Method, route).Observe(float64(ww.BytesWritten()))
+	})
+}
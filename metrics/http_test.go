@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scrape returns the metrics of the registry in the text format.
+func scrape(t *testing.T, h http.Handler) string {
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	body, err := io.ReadAll(rr.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestHTTP_Middleware(t *testing.T) {
+	reg := NewRegistry()
+	m := NewHTTP(reg)
+
+	users := chi.NewRouter()
+	users.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "user")
+	})
+	users.Post("/signin", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Mount("/user", users)
+
+	for _, request := range []struct{ method, path string }{
+		{http.MethodGet, "/user/1"},
+		{http.MethodGet, "/user/2"},
+		{http.MethodPost, "/user/signin"},
+		{http.MethodGet, "/random/path/1"},
+		{http.MethodGet, "/random/path/2"},
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(request.method, request.path, nil))
+	}
+
+	out := scrape(t, Handler(reg))
+
+	// The requests are labeled with the patterns, not the paths.
+	assert.Contains(t, out, `http_requests_total{method="GET",route="/user/{id}",status="200"} 2`)
+	assert.Contains(t, out, `http_requests_total{method="POST",route="/user/signin",status="401"} 1`)
+	assert.Contains(t, out, `http_requests_total{method="GET",route="unmatched",status="404"} 2`)
+	assert.NotContains(t, out, "/user/1")
+	assert.Contains(t, out, `http_request_duration_seconds_count{method="GET",route="/user/{id}"} 2`)
+	assert.Contains(t, out, `http_response_size_bytes_sum{method="GET",route="/user/{id}"} 8`)
+	assert.Contains(t, out, "http_requests_in_flight 0")
+	assert.Contains(t, out, "go_goroutines")
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"io/fs"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/ledger"
+	"github.com/GTA5-RP-Aristocracy/site-back/promo"
+	"github.com/GTA5-RP-Aristocracy/site-back/shop"
+	"github.com/GTA5-RP-Aristocracy/site-back/user"
+	"github.com/GTA5-RP-Aristocracy/site-back/vip"
+	"github.com/GTA5-RP-Aristocracy/site-back/webhook"
+)
+
+// This file lists the migrations embedded in the binaries.
+
+// Sources returns the embedded migrations of all the packages.
+// New packages with migrations must be added here, as must be imported
+// the packages registering Go migrations.
+func Sources() []fs.FS {
+	return []fs.FS{
+		user.Migrations,
+		shop.Migrations,
+		webhook.Migrations,
+		vip.Migrations,
+		ledger.Migrations,
+		promo.Migrations,
+	}
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/db/migrate"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSources(t *testing.T) {
+	up, err := migrate.ReadMigrationFiles(time.Time{}, migrate.MigrationUp, Sources()...)
+	require.NoError(t, err)
+	assert.NotEmpty(t, up)
+
+	// The user table is referenced by the others, so it comes first.
+	assert.Equal(t, "user", up[0].Name)
+}
+
+// TestSchema checks that the checked in schema snapshot matches the migrations.
+// It migrates the empty database of TEST_DB_URL and dumps its schema,
+// a stale snapshot is regenerated with the migrate dump command.
+func TestSchema(t *testing.T) {
+	dsn := os.Getenv("TEST_DB_URL")
+	if dsn == "" {
+		t.Skip("TEST_DB_URL isn't set")
+	}
+
+	ctx := context.Background()
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = migrate.NewMigrator(db, Sources()...).Apply(ctx)
+	require.NoError(t, err)
+	schema, err := migrate.DumpSchema(ctx, db)
+	require.NoError(t, err)
+
+	expected, err := os.ReadFile("../db/schema.sql")
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), schema, "db/schema.sql is stale, run the migrate dump command")
+}
@@ -0,0 +1,18 @@
+package promo
+
+import "time"
+
+type (
+	// Config represents the configuration options for promo codes and referrals.
+	Config struct {
+		// ReferralLink is the site url the referral code is appended to, e.g. https://example.com/?ref=
+		ReferralLink string `env:"PROMO_REFERRAL_LINK" yaml:"referral_link"`
+		// ReferralPlaytime is the playtime that qualifies a referee, as does passing the whitelist.
+		ReferralPlaytime time.Duration `env:"PROMO_REFERRAL_PLAYTIME" envDefault:"10h" yaml:"referral_playtime"`
+		// ReferralCurrency is the currency given to the referrer.
+		ReferralCurrency int64 `env:"PROMO_REFERRAL_CURRENCY" envDefault:"1000" yaml:"referral_currency"`
+		// ReferralVIPTier and ReferralVIPDays is the VIP status given to the referrer, if any.
+		ReferralVIPTier string `env:"PROMO_REFERRAL_VIP_TIER" yaml:"referral_vip_tier"`
+		ReferralVIPDays int    `env:"PROMO_REFERRAL_VIP_DAYS" yaml:"referral_vip_days"`
+	}
+)
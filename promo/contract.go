@@ -0,0 +1,71 @@
+package promo
+
+import (
+	"context"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/shop"
+	"github.com/google/uuid"
+)
+
+// This file defines the promo related interfaces.
+
+type (
+	// Service represents the promo service interface.
+	// It implements shop.Discounter for the discount codes.
+	Service interface {
+		shop.Discounter
+
+		// CreateCode adds a new promo code.
+		CreateCode(ctx context.Context, code Code) (Code, error)
+		// UpdateCode changes an existing promo code.
+		UpdateCode(ctx context.Context, code Code) (Code, error)
+		// ListCodes fetches all promo codes.
+		ListCodes(ctx context.Context) ([]Code, error)
+		// Redeem gives the currency or VIP reward of the promo code to the user.
+		Redeem(ctx context.Context, userID uuid.UUID, code string) (Redemption, error)
+		// ReferralCode fetches the referral code of the user, creating it on first use.
+		ReferralCode(ctx context.Context, userID uuid.UUID) (ReferralCode, error)
+		// ClaimReferral records that the user was brought by the owner of the referral code.
+		ClaimReferral(ctx context.Context, refereeID uuid.UUID, code string) (Referral, error)
+		// Referrals fetches the players brought by the user.
+		Referrals(ctx context.Context, referrerID uuid.UUID) ([]Referral, error)
+		// ReportProgress rewards the referrer once the player qualifies.
+		// It reports whether the referrer has been rewarded by this call.
+		ReportProgress(ctx context.Context, progress Progress) (bool, error)
+	}
+
+	// Repository represents the promo repository interface.
+	Repository interface {
+		// CreateCode inserts a new promo code.
+		CreateCode(ctx context.Context, code Code) error
+		// UpdateCode updates a promo code, the usage counter is kept.
+		UpdateCode(ctx context.Context, code Code) error
+		// FindCodeByID returns a promo code by id.
+		FindCodeByID(ctx context.Context, id uuid.UUID) (Code, error)
+		// FindCodes returns all promo codes.
+		FindCodes(ctx context.Context) ([]Code, error)
+		// Redeem inserts the redemption built by fn and counts the use of the code.
+		// The code is locked while redeeming, fn gets it along with the number of
+		// the user's redemptions of the code.
+		Redeem(ctx context.Context, code string, userID uuid.UUID, fn func(c Code, userUses int) (Redemption, error)) (Redemption, error)
+		// DeleteRedemption removes a redemption and gives the use back to the code.
+		DeleteRedemption(ctx context.Context, id uuid.UUID) error
+		// FindRedemptionByOrder returns the redemption of the shop order.
+		FindRedemptionByOrder(ctx context.Context, orderID uuid.UUID) (Redemption, error)
+
+		// EnsureReferralCode returns the referral code of the user, storing the given one
+		// if the user has none. It returns ErrReferralNotFound if the code is taken.
+		EnsureReferralCode(ctx context.Context, userID uuid.UUID, code string) (string, error)
+		// FindReferrer returns the owner of the referral code.
+		FindReferrer(ctx context.Context, code string) (uuid.UUID, error)
+		// CreateReferral inserts a referral. It reports false if the referee has already been referred.
+		CreateReferral(ctx context.Context, referral Referral) (bool, error)
+		// FindReferralByReferee returns the referral of the referee.
+		FindReferralByReferee(ctx context.Context, refereeID uuid.UUID) (Referral, error)
+		// FindReferrals returns the referrals of the referrer.
+		FindReferrals(ctx context.Context, referrerID uuid.UUID) ([]Referral, error)
+		// UpdateReferralStatus moves the referral from one status to another.
+		// It reports false if the referral is not in the expected status anymore.
+		UpdateReferralStatus(ctx context.Context, id uuid.UUID, from, to ReferralStatus) (bool, error)
+	}
+)
@@ -0,0 +1,22 @@
+package promo
+
+// This file contains promo related errors.
+
+import "errors"
+
+// Define custom errors.
+var (
+	ErrCodeNotFound       = errors.New("promo: code not found")
+	ErrInvalidCode        = errors.New("promo: invalid code")
+	ErrRedemptionNotFound = errors.New("promo: redemption not found")
+	ErrCodeUnavailable    = errors.New("promo: code is not active")
+	ErrCodeExhausted      = errors.New("promo: code has been used up")
+	ErrUserLimit          = errors.New("promo: code has already been used by the account")
+	ErrDiscountOnly       = errors.New("promo: code applies to shop orders only")
+	ErrNotDiscount        = errors.New("promo: code doesn't give a discount")
+	ErrCurrencyMismatch   = errors.New("promo: code is for a different currency")
+	ErrReferralNotFound   = errors.New("promo: referral not found")
+	ErrSelfReferral       = errors.New("promo: players can't refer themselves")
+	ErrAlreadyReferred    = errors.New("promo: account has already been referred")
+	ErrCodeGeneration     = errors.New("promo: failed to generate a unique referral code")
+)
@@ -0,0 +1,231 @@
+package promo
+
+import (
+	"net/http"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/api"
+	"github.com/GTA5-RP-Aristocracy/site-back/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+)
+
+// This file contains promo related http handlers.
+
+const (
+	pathRoot             = "/promo"
+	pathRedeem           = "/redeem"
+	pathReferral         = "/referral"
+	pathReferralClaim    = "/referral/claim"
+	pathReferralProgress = "/referrals/progress"
+	pathAdmin            = "/admin"
+	pathCodes            = "/codes"
+	pathCode             = "/codes/{id}"
+)
+
+// errorMappings map the promo errors to the problem responses.
+var errorMappings = []api.Mapping{
+	{Err: ErrCodeNotFound, Status: http.StatusNotFound, Code: "code_not_found"},
+	{Err: ErrReferralNotFound, Status: http.StatusNotFound, Code: "referral_not_found"},
+	{Err: ErrInvalidCode, Status: http.StatusBadRequest, Code: "invalid_code"},
+	{Err: ErrDiscountOnly, Status: http.StatusBadRequest, Code: "discount_only"},
+	{Err: ErrSelfReferral, Status: http.StatusBadRequest, Code: "self_referral"},
+	{Err: ErrCodeUnavailable, Status: http.StatusConflict, Code: "code_unavailable"},
+	{Err: ErrCodeExhausted, Status: http.StatusConflict, Code: "code_exhausted"},
+	{Err: ErrUserLimit, Status: http.StatusConflict, Code: "user_limit"},
+	{Err: ErrAlreadyReferred, Status: http.StatusConflict, Code: "already_referred"},
+}
+
+type (
+	// Handler represents a set of http handlers for promo codes and referrals.
+	Handler struct {
+		service Service
+		auth    auth.Config
+	}
+
+	// codeRequest represents the request body with a promo or referral code.
+	codeRequest struct {
+		Code string `json:"code"`
+	}
+
+	// referralResponse represents the referral code of the user along with the referred players.
+	referralResponse struct {
+		ReferralCode
+		Referrals []Referral `json:"referrals"`
+	}
+
+	// progressResponse represents the outcome of a progress report.
+	progressResponse struct {
+		Rewarded bool `json:"rewarded"`
+	}
+)
+
+// NewHandler creates a new promo http handler.
+func NewHandler(service Service, authConfig auth.Config) *Handler {
+	return &Handler{service, authConfig}
+}
+
+// RegisterPromoRouter registers promo routes.
+func (h *Handler) RegisterPromoRouter(externalRouter chi.Router) {
+	r := chi.NewRouter()
+
+	// Player codes.
+	r.Group(func(r chi.Router) {
+		r.Use(auth.RequireUser(h.auth))
+		r.Post(pathRedeem, h.Redeem)
+		r.Get(pathReferral, h.Referral)
+		r.Post(pathReferralClaim, h.ClaimReferral)
+	})
+
+	// Game server reports.
+	r.With(auth.RequireToken(h.auth.ServerToken)).Post(pathReferralProgress, h.ReportProgress)
+
+	// Code management.
+	r.Route(pathAdmin, func(r chi.Router) {
+		r.Use(auth.RequireToken(h.auth.AdminToken))
+		r.Get(pathCodes, h.ListCodes)
+		r.Post(pathCodes, h.CreateCode)
+		r.Put(pathCode, h.UpdateCode)
+	})
+
+	externalRouter.Mount(pathRoot, r)
+}
+
+// Redeem handles the promo code redemption request of the current user.
+func (h *Handler) Redeem(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.UserID(r.Context())
+
+	var req codeRequest
+	if err := api.DecodeJSON(w, r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	redemption, err := h.service.Redeem(r.Context(), userID, req.Code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, redemption)
+}
+
+// Referral handles the referral code request of the current user.
+func (h *Handler) Referral(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.UserID(r.Context())
+
+	code, err := h.service.ReferralCode(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	referrals, err := h.service.Referrals(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, referralResponse{code, referrals})
+}
+
+// ClaimReferral handles the request of the current user to be referred by a friend.
+func (h *Handler) ClaimReferral(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.UserID(r.Context())
+
+	var req codeRequest
+	if err := api.DecodeJSON(w, r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	referral, err := h.service.ClaimReferral(r.Context(), userID, req.Code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, referral)
+}
+
+// ReportProgress handles the game server report of a player's progress.
+func (h *Handler) ReportProgress(w http.ResponseWriter, r *http.Request) {
+	var progress Progress
+	if err := api.DecodeJSON(w, r, &progress); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	rewarded, err := h.service.ReportProgress(r.Context(), progress)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, progressResponse{rewarded})
+}
+
+// ListCodes handles the staff request of all promo codes.
+func (h *Handler) ListCodes(w http.ResponseWriter, r *http.Request) {
+	codes, err := h.service.ListCodes(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, codes)
+}
+
+// CreateCode handles the promo code creation request.
+func (h *Handler) CreateCode(w http.ResponseWriter, r *http.Request) {
+	var code Code
+	if err := api.DecodeJSON(w, r, &code); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	code, err := h.service.CreateCode(r.Context(), code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, code)
+}
+
+// UpdateCode handles the promo code update request.
+func (h *Handler) UpdateCode(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, api.ErrInvalidID)
+		return
+	}
+
+	var code Code
+	if err := api.DecodeJSON(w, r, &code); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	code.ID = id
+
+	code, err = h.service.UpdateCode(r.Context(), code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, code)
+}
+
+// writeError writes the problem matching the error.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	api.WriteError(w, r, err, errorMappings...)
+}
+
+// writeJSON writes the response in JSON format.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
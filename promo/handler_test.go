@@ -0,0 +1,157 @@
+package promo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAuth signs the sessions of the tests.
+var testAuth = auth.Config{SessionSecret: "0123456789abcdef0123456789abcdef", SessionTTL: time.Hour}
+
+// newSession returns the signed session of the user.
+func newSession(t *testing.T, id uuid.UUID) string {
+	session, err := auth.NewSession(testAuth, id, time.Now())
+	require.NoError(t, err)
+	return session
+}
+
+type MockService struct {
+	Service
+	funcRedeem         func(userID uuid.UUID, code string) (Redemption, error)
+	funcReportProgress func(progress Progress) (bool, error)
+}
+
+func (m *MockService) Redeem(ctx context.Context, userID uuid.UUID, code string) (Redemption, error) {
+	return m.funcRedeem(userID, code)
+}
+
+func (m *MockService) ReportProgress(ctx context.Context, progress Progress) (bool, error) {
+	return m.funcReportProgress(progress)
+}
+
+func TestHandlerRedeem(t *testing.T) {
+	userID := uuid.New()
+
+	cases := []struct {
+		testName       string
+		requestBody    string
+		session        string
+		funcRedeem     func(userID uuid.UUID, code string) (Redemption, error)
+		expectedStatus int
+	}{
+		{
+			testName:    "ok",
+			requestBody: `{"code":"GIFT"}`,
+			session:     newSession(t, userID),
+			funcRedeem: func(id uuid.UUID, code string) (Redemption, error) {
+				assert.Equal(t, userID, id)
+				assert.Equal(t, "GIFT", code)
+				return Redemption{UserID: id, Reward: RewardCurrency, Amount: 100}, nil
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			testName:    "unknown code",
+			requestBody: `{"code":"NOPE"}`,
+			session:     newSession(t, userID),
+			funcRedeem: func(uuid.UUID, string) (Redemption, error) {
+				return Redemption{}, ErrCodeNotFound
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			testName:    "used by the account",
+			requestBody: `{"code":"GIFT"}`,
+			session:     newSession(t, userID),
+			funcRedeem: func(uuid.UUID, string) (Redemption, error) {
+				return Redemption{}, ErrUserLimit
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			testName:       "unauthenticated",
+			requestBody:    `{"code":"GIFT"}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "invalid body",
+			requestBody:    `{"code":`,
+			session:        newSession(t, userID),
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			router := chi.NewRouter()
+			handler := NewHandler(&MockService{funcRedeem: tc.funcRedeem}, testAuth)
+			handler.RegisterPromoRouter(router)
+
+			req := httptest.NewRequest(http.MethodPost, "/promo/redeem", strings.NewReader(tc.requestBody))
+			if tc.session != "" {
+				req.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: tc.session})
+			}
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestHandlerReportProgress(t *testing.T) {
+	cases := []struct {
+		testName         string
+		token            string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			testName:         "ok",
+			token:            "server",
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"rewarded":true}`,
+		},
+		{
+			testName:       "wrong token",
+			token:          "player",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			router := chi.NewRouter()
+			handler := NewHandler(&MockService{
+				funcReportProgress: func(progress Progress) (bool, error) {
+					assert.True(t, progress.Whitelisted)
+					return true, nil
+				},
+			}, auth.Config{ServerToken: "server"})
+			handler.RegisterPromoRouter(router)
+
+			body := `{"user_id":"123e4567-e89b-12d3-a456-426614174000","whitelisted":true}`
+			req := httptest.NewRequest(http.MethodPost, "/promo/referrals/progress", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedResponse != "" {
+				assert.JSONEq(t, tc.expectedResponse, rr.Body.String())
+			}
+		})
+	}
+}
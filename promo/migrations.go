@@ -0,0 +1,8 @@
+package promo
+
+import "embed"
+
+// Migrations holds the SQL migrations of the promo code and referral tables.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS promo_referral;
+DROP TABLE IF EXISTS promo_referral_code;
+DROP TABLE IF EXISTS promo_redemption;
+DROP TABLE IF EXISTS promo_code;
\ No newline at end of file
@@ -0,0 +1,50 @@
+CREATE TABLE IF NOT EXISTS promo_code (
+    id UUID PRIMARY KEY,
+    code VARCHAR(32) NOT NULL UNIQUE,
+    reward VARCHAR(32) NOT NULL,
+    percent_off INTEGER NOT NULL DEFAULT 0 CHECK (percent_off BETWEEN 0 AND 100),
+    amount_off BIGINT NOT NULL DEFAULT 0 CHECK (amount_off >= 0),
+    currency VARCHAR(3) NOT NULL DEFAULT '',
+    currency_amount BIGINT NOT NULL DEFAULT 0 CHECK (currency_amount >= 0),
+    vip_tier VARCHAR(32) NOT NULL DEFAULT '',
+    vip_days INTEGER NOT NULL DEFAULT 0 CHECK (vip_days >= 0),
+    max_uses INTEGER NOT NULL DEFAULT 0 CHECK (max_uses >= 0),
+    max_per_user INTEGER NOT NULL DEFAULT 0 CHECK (max_per_user >= 0),
+    uses INTEGER NOT NULL DEFAULT 0 CHECK (uses >= 0),
+    starts TIMESTAMP,
+    ends TIMESTAMP,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- The order is created after the discount has been redeemed, so it is not a foreign key.
+CREATE TABLE IF NOT EXISTS promo_redemption (
+    id UUID PRIMARY KEY,
+    code_id UUID NOT NULL REFERENCES promo_code (id),
+    user_id UUID NOT NULL REFERENCES user_storage (id),
+    reward VARCHAR(32) NOT NULL,
+    order_id UUID UNIQUE,
+    amount BIGINT NOT NULL DEFAULT 0,
+    created TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS promo_redemption_code_user_index ON promo_redemption (code_id, user_id);
+
+CREATE TABLE IF NOT EXISTS promo_referral_code (
+    user_id UUID PRIMARY KEY REFERENCES user_storage (id),
+    code VARCHAR(32) NOT NULL UNIQUE,
+    created TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS promo_referral (
+    id UUID PRIMARY KEY,
+    referrer_id UUID NOT NULL REFERENCES user_storage (id),
+    referee_id UUID NOT NULL UNIQUE REFERENCES user_storage (id),
+    status VARCHAR(32) NOT NULL,
+    created TIMESTAMP NOT NULL DEFAULT NOW(),
+    rewarded TIMESTAMP,
+    CHECK (referrer_id <> referee_id)
+);
+
+CREATE INDEX IF NOT EXISTS promo_referral_referrer_index ON promo_referral (referrer_id);
\ No newline at end of file
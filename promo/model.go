@@ -0,0 +1,96 @@
+package promo
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// This file defines the promo models, e.g. promo codes, redemptions and referrals.
+
+const (
+	// RewardDiscount reduces the total of a shop order.
+	RewardDiscount RewardKind = "discount"
+	// RewardCurrency deposits in-game currency to the wallet.
+	RewardCurrency RewardKind = "currency"
+	// RewardVIP grants VIP days.
+	RewardVIP RewardKind = "vip"
+
+	// ReferralPending is set until the referee qualifies.
+	ReferralPending ReferralStatus = "pending"
+	// ReferralRewarded is set once the referrer got the reward.
+	ReferralRewarded ReferralStatus = "rewarded"
+)
+
+type (
+	// RewardKind represents what a promo code gives.
+	RewardKind string
+
+	// ReferralStatus represents the state of a referral.
+	ReferralStatus string
+
+	// Code represents a promo code. Limits set to zero are unlimited.
+	Code struct {
+		ID     uuid.UUID  `json:"id"`
+		Code   string     `json:"code"`
+		Reward RewardKind `json:"reward"`
+		// Discounts are either a percentage or a fixed amount in minor units of the currency.
+		PercentOff     int    `json:"percent_off,omitempty"`
+		AmountOff      int64  `json:"amount_off,omitempty"`
+		Currency       string `json:"currency,omitempty"`
+		CurrencyAmount int64  `json:"currency_amount,omitempty"`
+		VIPTier        string `json:"vip_tier,omitempty"`
+		VIPDays        int    `json:"vip_days,omitempty"`
+		// MaxUses limits the redemptions of all accounts, MaxPerUser of a single one.
+		MaxUses    int        `json:"max_uses"`
+		MaxPerUser int        `json:"max_per_user"`
+		Uses       int        `json:"uses"`
+		Starts     *time.Time `json:"starts,omitempty"`
+		Ends       *time.Time `json:"ends,omitempty"`
+		Active     bool       `json:"active"`
+		Created    time.Time  `json:"created"`
+		Updated    time.Time  `json:"updated"`
+	}
+
+	// Redemption represents a single use of a promo code.
+	Redemption struct {
+		ID     uuid.UUID  `json:"id"`
+		CodeID uuid.UUID  `json:"code_id"`
+		UserID uuid.UUID  `json:"user_id"`
+		Reward RewardKind `json:"reward"`
+		// OrderID is set for the discount codes.
+		OrderID uuid.NullUUID `json:"order_id"`
+		// Amount is the discount, the currency amount or the VIP days given.
+		Amount  int64     `json:"amount"`
+		Created time.Time `json:"created"`
+	}
+
+	// ReferralCode represents the code a player shares to bring friends.
+	ReferralCode struct {
+		UserID uuid.UUID `json:"user_id"`
+		Code   string    `json:"code"`
+		Link   string    `json:"link,omitempty"`
+	}
+
+	// Referral represents a player brought by another one.
+	Referral struct {
+		ID         uuid.UUID      `json:"id"`
+		ReferrerID uuid.UUID      `json:"referrer_id"`
+		RefereeID  uuid.UUID      `json:"referee_id"`
+		Status     ReferralStatus `json:"status"`
+		Created    time.Time      `json:"created"`
+		Rewarded   *time.Time     `json:"rewarded,omitempty"`
+	}
+
+	// Progress represents the game progress of a player reported by the game server.
+	Progress struct {
+		UserID          uuid.UUID `json:"user_id"`
+		Whitelisted     bool      `json:"whitelisted"`
+		PlaytimeMinutes int       `json:"playtime_minutes"`
+	}
+)
+
+// ValidAt reports whether the code can be redeemed at the moment, ignoring the usage limits.
+func (c Code) ValidAt(now time.Time) bool {
+	return c.Active && (c.Starts == nil || !now.Before(*c.Starts)) && (c.Ends == nil || now.Before(*c.Ends))
+}
@@ -0,0 +1,270 @@
+package promo
+
+// This file contains promo repository related code.
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/db"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+const (
+	codeColumns       = "id, code, reward, percent_off, amount_off, currency, currency_amount, vip_tier, vip_days, max_uses, max_per_user, uses, starts, ends, active, created, updated"
+	redemptionColumns = "id, code_id, user_id, reward, order_id, amount, created"
+	referralColumns   = "id, referrer_id, referee_id, status, created, rewarded"
+)
+
+type (
+	// repository implements the Repository interface.
+	repository struct {
+		db *sql.DB
+		tx *db.TxManager
+	}
+
+	// scanner is implemented by both *sql.Row and *sql.Rows.
+	scanner interface {
+		Scan(dest ...any) error
+	}
+)
+
+// NewRepository creates a new promo repository.
+// It runs the queries in the transaction of the context, see db.TxManager.
+func NewRepository(sqlDB *sql.DB) Repository {
+	return &repository{sqlDB, db.NewTxManager(sqlDB)}
+}
+
+// querier returns the transaction of the context or the database.
+func (r *repository) querier(ctx context.Context) db.Querier {
+	return db.QuerierFrom(ctx, r.db)
+}
+
+// CreateCode inserts a new promo code.
+func (r *repository) CreateCode(ctx context.Context, c Code) error {
+	_, err := r.querier(ctx).ExecContext(ctx,
+		"INSERT INTO promo_code (id, code, reward, percent_off, amount_off, currency, currency_amount, vip_tier, vip_days, max_uses, max_per_user, starts, ends, active) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)",
+		c.ID, c.Code, c.Reward, c.PercentOff, c.AmountOff, c.Currency, c.CurrencyAmount, c.VIPTier, c.VIPDays, c.MaxUses, c.MaxPerUser, c.Starts, c.Ends, c.Active)
+	return err
+}
+
+// UpdateCode updates a promo code, the usage counter is kept.
+func (r *repository) UpdateCode(ctx context.Context, c Code) error {
+	res, err := r.querier(ctx).ExecContext(ctx,
+		"UPDATE promo_code SET code = $2, reward = $3, percent_off = $4, amount_off = $5, currency = $6, currency_amount = $7, vip_tier = $8, vip_days = $9, max_uses = $10, max_per_user = $11, starts = $12, ends = $13, active = $14, updated = NOW() WHERE id = $1",
+		c.ID, c.Code, c.Reward, c.PercentOff, c.AmountOff, c.Currency, c.CurrencyAmount, c.VIPTier, c.VIPDays, c.MaxUses, c.MaxPerUser, c.Starts, c.Ends, c.Active)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrCodeNotFound
+	}
+	return nil
+}
+
+// FindCodeByID returns a promo code by id.
+func (r *repository) FindCodeByID(ctx context.Context, id uuid.UUID) (Code, error) {
+	c, err := scanCode(r.querier(ctx).QueryRowContext(ctx, "SELECT "+codeColumns+" FROM promo_code WHERE id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Code{}, ErrCodeNotFound
+	}
+	return c, err
+}
+
+// FindCodes returns all promo codes.
+func (r *repository) FindCodes(ctx context.Context) ([]Code, error) {
+	rows, err := r.querier(ctx).QueryContext(ctx, "SELECT "+codeColumns+" FROM promo_code ORDER BY created DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := []Code{}
+	for rows.Next() {
+		c, err := scanCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+	}
+	return codes, rows.Err()
+}
+
+// Redeem inserts the redemption built by fn and counts the use of the code.
+func (r *repository) Redeem(ctx context.Context, code string, userID uuid.UUID, fn func(c Code, userUses int) (Redemption, error)) (Redemption, error) {
+	var redemption Redemption
+	err := r.tx.InTx(ctx, func(ctx context.Context) error {
+		q := r.querier(ctx)
+
+		// Concurrent redemptions of the code wait for each other here.
+		c, err := scanCode(q.QueryRowContext(ctx, "SELECT "+codeColumns+" FROM promo_code WHERE code = $1 FOR UPDATE", code))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCodeNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("lock code: %w", err)
+		}
+
+		var userUses int
+		err = q.QueryRowContext(ctx, "SELECT COUNT(*) FROM promo_redemption WHERE code_id = $1 AND user_id = $2", c.ID, userID).Scan(&userUses)
+		if err != nil {
+			return fmt.Errorf("count redemptions: %w", err)
+		}
+
+		redemption, err = fn(c, userUses)
+		if err != nil {
+			return err
+		}
+
+		err = q.QueryRowContext(ctx,
+			"INSERT INTO promo_redemption (id, code_id, user_id, reward, order_id, amount) VALUES ($1, $2, $3, $4, $5, $6) RETURNING created",
+			redemption.ID, redemption.CodeID, redemption.UserID, redemption.Reward, redemption.OrderID, redemption.Amount).
+			Scan(&redemption.Created)
+		if err != nil {
+			return fmt.Errorf("insert redemption: %w", err)
+		}
+
+		if _, err := q.ExecContext(ctx, "UPDATE promo_code SET uses = uses + 1 WHERE id = $1", c.ID); err != nil {
+			return fmt.Errorf("count use: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Redemption{}, err
+	}
+	return redemption, nil
+}
+
+// DeleteRedemption removes a redemption and gives the use back to the code.
+func (r *repository) DeleteRedemption(ctx context.Context, id uuid.UUID) error {
+	return r.tx.InTx(ctx, func(ctx context.Context) error {
+		q := r.querier(ctx)
+
+		var codeID uuid.UUID
+		err := q.QueryRowContext(ctx, "DELETE FROM promo_redemption WHERE id = $1 RETURNING code_id", id).Scan(&codeID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRedemptionNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("delete redemption: %w", err)
+		}
+
+		if _, err := q.ExecContext(ctx, "UPDATE promo_code SET uses = uses - 1 WHERE id = $1", codeID); err != nil {
+			return fmt.Errorf("release use: %w", err)
+		}
+		return nil
+	})
+}
+
+// FindRedemptionByOrder returns the redemption of the shop order.
+func (r *repository) FindRedemptionByOrder(ctx context.Context, orderID uuid.UUID) (Redemption, error) {
+	var red Redemption
+	err := r.querier(ctx).QueryRowContext(ctx, "SELECT "+redemptionColumns+" FROM promo_redemption WHERE order_id = $1", orderID).
+		Scan(&red.ID, &red.CodeID, &red.UserID, &red.Reward, &red.OrderID, &red.Amount, &red.Created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Redemption{}, ErrRedemptionNotFound
+	}
+	return red, err
+}
+
+// EnsureReferralCode returns the referral code of the user, storing the given one if the user has none.
+func (r *repository) EnsureReferralCode(ctx context.Context, userID uuid.UUID, code string) (string, error) {
+	// Either the user or the code may already exist, both are resolved by the select.
+	_, err := r.querier(ctx).ExecContext(ctx,
+		"INSERT INTO promo_referral_code (user_id, code) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		userID, code)
+	if err != nil {
+		return "", err
+	}
+
+	var stored string
+	err = r.querier(ctx).QueryRowContext(ctx, "SELECT code FROM promo_referral_code WHERE user_id = $1", userID).Scan(&stored)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrReferralNotFound
+	}
+	return stored, err
+}
+
+// FindReferrer returns the owner of the referral code.
+func (r *repository) FindReferrer(ctx context.Context, code string) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := r.querier(ctx).QueryRowContext(ctx, "SELECT user_id FROM promo_referral_code WHERE code = $1", code).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrReferralNotFound
+	}
+	return userID, err
+}
+
+// CreateReferral inserts a referral.
+func (r *repository) CreateReferral(ctx context.Context, ref Referral) (bool, error) {
+	res, err := r.querier(ctx).ExecContext(ctx,
+		"INSERT INTO promo_referral (id, referrer_id, referee_id, status, created) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (referee_id) DO NOTHING",
+		ref.ID, ref.ReferrerID, ref.RefereeID, ref.Status, ref.Created)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// FindReferralByReferee returns the referral of the referee.
+func (r *repository) FindReferralByReferee(ctx context.Context, refereeID uuid.UUID) (Referral, error) {
+	ref, err := scanReferral(r.querier(ctx).QueryRowContext(ctx, "SELECT "+referralColumns+" FROM promo_referral WHERE referee_id = $1", refereeID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Referral{}, ErrReferralNotFound
+	}
+	return ref, err
+}
+
+// FindReferrals returns the referrals of the referrer.
+func (r *repository) FindReferrals(ctx context.Context, referrerID uuid.UUID) ([]Referral, error) {
+	rows, err := r.querier(ctx).QueryContext(ctx, "SELECT "+referralColumns+" FROM promo_referral WHERE referrer_id = $1 ORDER BY created DESC", referrerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	referrals := []Referral{}
+	for rows.Next() {
+		ref, err := scanReferral(rows)
+		if err != nil {
+			return nil, err
+		}
+		referrals = append(referrals, ref)
+	}
+	return referrals, rows.Err()
+}
+
+// UpdateReferralStatus moves the referral from one status to another.
+func (r *repository) UpdateReferralStatus(ctx context.Context, id uuid.UUID, from, to ReferralStatus) (bool, error) {
+	res, err := r.querier(ctx).ExecContext(ctx,
+		"UPDATE promo_referral SET status = $3, rewarded = CASE WHEN $3 = 'rewarded' THEN NOW() END WHERE id = $1 AND status = $2",
+		id, from, to)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// scanCode scans a promo code row.
+func scanCode(row scanner) (Code, error) {
+	var c Code
+	err := row.Scan(&c.ID, &c.Code, &c.Reward, &c.PercentOff, &c.AmountOff, &c.Currency, &c.CurrencyAmount, &c.VIPTier, &c.VIPDays,
+		&c.MaxUses, &c.MaxPerUser, &c.Uses, &c.Starts, &c.Ends, &c.Active, &c.Created, &c.Updated)
+	return c, err
+}
+
+// scanReferral scans a referral row.
+func scanReferral(row scanner) (Referral, error) {
+	var ref Referral
+	err := row.Scan(&ref.ID, &ref.ReferrerID, &ref.RefereeID, &ref.Status, &ref.Created, &ref.Rewarded)
+	return ref, err
+}
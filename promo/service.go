@@ -0,0 +1,438 @@
+package promo
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/ledger"
+	"github.com/GTA5-RP-Aristocracy/site-back/shop"
+	"github.com/GTA5-RP-Aristocracy/site-back/vip"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// This file contains the promo service implementation.
+
+const (
+	// referralAlphabet leaves out the characters that are easy to confuse, e.g. 0 and O.
+	referralAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	// referralLength is the length of the generated referral codes.
+	referralLength = 8
+	// referralAttempts limits the attempts to generate a unique referral code.
+	referralAttempts = 3
+	// issuer is recorded as the issuer of the rewards.
+	issuer = "promo"
+)
+
+var (
+	// tracer traces the service methods.
+	tracer = otel.Tracer("github.com/GTA5-RP-Aristocracy/site-back/promo")
+
+	// codePattern matches the normalized promo codes.
+	codePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+	// rejections are the errors reported to the shop as rejected promo codes.
+	rejections = []error{ErrCodeNotFound, ErrCodeUnavailable, ErrCodeExhausted, ErrUserLimit, ErrNotDiscount, ErrCurrencyMismatch}
+)
+
+type (
+	// service implements the Service interface.
+	service struct {
+		repo   Repository
+		ledger ledger.Service
+		vip    vip.Service
+		config Config
+		now    func() time.Time
+	}
+)
+
+// NewService creates a new promo service, the rewards are given with the ledger and VIP services.
+func NewService(repo Repository, ledgerService ledger.Service, vipService vip.Service, config Config) Service {
+	return &service{
+		repo:   repo,
+		ledger: ledgerService,
+		vip:    vipService,
+		config: config,
+		now:    func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// CreateCode adds a new promo code.
+func (s *service) CreateCode(ctx context.Context, code Code) (Code, error) {
+	ctx, span := tracer.Start(ctx, "promo.CreateCode")
+	defer span.End()
+
+	code.ID = uuid.New()
+	if err := validateCode(&code); err != nil {
+		return Code{}, err
+	}
+
+	if err := s.repo.CreateCode(ctx, code); err != nil {
+		return Code{}, fmt.Errorf("create code: %w", err)
+	}
+	return s.repo.FindCodeByID(ctx, code.ID)
+}
+
+// UpdateCode changes an existing promo code.
+func (s *service) UpdateCode(ctx context.Context, code Code) (Code, error) {
+	ctx, span := tracer.Start(ctx, "promo.UpdateCode")
+	defer span.End()
+
+	if err := validateCode(&code); err != nil {
+		return Code{}, err
+	}
+
+	if err := s.repo.UpdateCode(ctx, code); err != nil {
+		return Code{}, fmt.Errorf("update code: %w", err)
+	}
+	return s.repo.FindCodeByID(ctx, code.ID)
+}
+
+// ListCodes fetches all promo codes.
+func (s *service) ListCodes(ctx context.Context) ([]Code, error) {
+	ctx, span := tracer.Start(ctx, "promo.ListCodes")
+	defer span.End()
+
+	return s.repo.FindCodes(ctx)
+}
+
+// Redeem gives the currency or VIP reward of the promo code to the user.
+// The redemption is stored first, so concurrent requests can't exceed the limits,
+// and it is taken back if the reward can't be given.
+func (s *service) Redeem(ctx context.Context, userID uuid.UUID, code string) (Redemption, error) {
+	ctx, span := tracer.Start(ctx, "promo.Redeem")
+	defer span.End()
+
+	var redeemed Code
+	redemption, err := s.repo.Redeem(ctx, normalizeCode(code), userID, func(c Code, userUses int) (Redemption, error) {
+		if err := s.checkLimits(c, userUses); err != nil {
+			return Redemption{}, err
+		}
+
+		redemption := Redemption{ID: uuid.New(), CodeID: c.ID, UserID: userID, Reward: c.Reward}
+		switch c.Reward {
+		case RewardCurrency:
+			redemption.Amount = c.CurrencyAmount
+		case RewardVIP:
+			redemption.Amount = int64(c.VIPDays)
+		default:
+			return Redemption{}, ErrDiscountOnly
+		}
+
+		redeemed = c
+		return redemption, nil
+	})
+	if err != nil {
+		return Redemption{}, err
+	}
+
+	if err := s.reward(ctx, redeemed, redemption); err != nil {
+		if deleteErr := s.repo.DeleteRedemption(ctx, redemption.ID); deleteErr != nil {
+			err = errors.Join(err, fmt.Errorf("delete redemption: %w", deleteErr))
+		}
+		return Redemption{}, fmt.Errorf("give reward: %w", err)
+	}
+	return redemption, nil
+}
+
+// Discount redeems the discount code for the shop order and returns the discount.
+func (s *service) Discount(ctx context.Context, code string, order shop.Order) (int64, error) {
+	ctx, span := tracer.Start(ctx, "promo.Discount")
+	defer span.End()
+
+	redemption, err := s.repo.Redeem(ctx, normalizeCode(code), order.UserID, func(c Code, userUses int) (Redemption, error) {
+		if err := s.checkLimits(c, userUses); err != nil {
+			return Redemption{}, err
+		}
+		if c.Reward != RewardDiscount {
+			return Redemption{}, ErrNotDiscount
+		}
+
+		discount := order.Total * int64(c.PercentOff) / 100
+		if c.AmountOff > 0 {
+			if !strings.EqualFold(c.Currency, order.Currency) {
+				return Redemption{}, ErrCurrencyMismatch
+			}
+			discount = c.AmountOff
+		}
+
+		return Redemption{
+			ID:      uuid.New(),
+			CodeID:  c.ID,
+			UserID:  order.UserID,
+			Reward:  c.Reward,
+			OrderID: uuid.NullUUID{UUID: order.ID, Valid: true},
+			Amount:  min(discount, order.Total),
+		}, nil
+	})
+	for _, rejection := range rejections {
+		if errors.Is(err, rejection) {
+			return 0, fmt.Errorf("%w: %w", shop.ErrPromoRejected, err)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return redemption.Amount, nil
+}
+
+// Release gives the use of the discount code back if the order couldn't be placed.
+func (s *service) Release(ctx context.Context, order shop.Order) error {
+	ctx, span := tracer.Start(ctx, "promo.Release")
+	defer span.End()
+
+	redemption, err := s.repo.FindRedemptionByOrder(ctx, order.ID)
+	if errors.Is(err, ErrRedemptionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteRedemption(ctx, redemption.ID)
+}
+
+// ReferralCode fetches the referral code of the user, creating it on first use.
+func (s *service) ReferralCode(ctx context.Context, userID uuid.UUID) (ReferralCode, error) {
+	ctx, span := tracer.Start(ctx, "promo.ReferralCode")
+	defer span.End()
+
+	for i := 0; i < referralAttempts; i++ {
+		generated, err := generateReferralCode()
+		if err != nil {
+			return ReferralCode{}, err
+		}
+
+		code, err := s.repo.EnsureReferralCode(ctx, userID, generated)
+		if errors.Is(err, ErrReferralNotFound) {
+			// Somebody else has the generated code.
+			continue
+		}
+		if err != nil {
+			return ReferralCode{}, err
+		}
+
+		referral := ReferralCode{UserID: userID, Code: code}
+		if s.config.ReferralLink != "" {
+			referral.Link = s.config.ReferralLink + code
+		}
+		return referral, nil
+	}
+	return ReferralCode{}, ErrCodeGeneration
+}
+
+// ClaimReferral records that the user was brought by the owner of the referral code.
+// A player is referred only once.
+func (s *service) ClaimReferral(ctx context.Context, refereeID uuid.UUID, code string) (Referral, error) {
+	ctx, span := tracer.Start(ctx, "promo.ClaimReferral")
+	defer span.End()
+
+	referrerID, err := s.repo.FindReferrer(ctx, normalizeCode(code))
+	if err != nil {
+		return Referral{}, err
+	}
+	if referrerID == refereeID {
+		return Referral{}, ErrSelfReferral
+	}
+
+	referral := Referral{
+		ID:         uuid.New(),
+		ReferrerID: referrerID,
+		RefereeID:  refereeID,
+		Status:     ReferralPending,
+		Created:    s.now(),
+	}
+	created, err := s.repo.CreateReferral(ctx, referral)
+	if err != nil {
+		return Referral{}, fmt.Errorf("create referral: %w", err)
+	}
+	if !created {
+		return Referral{}, ErrAlreadyReferred
+	}
+	return referral, nil
+}
+
+// Referrals fetches the players brought by the user.
+func (s *service) Referrals(ctx context.Context, referrerID uuid.UUID) ([]Referral, error) {
+	ctx, span := tracer.Start(ctx, "promo.Referrals")
+	defer span.End()
+
+	return s.repo.FindReferrals(ctx, referrerID)
+}
+
+// ReportProgress rewards the referrer once the player passes the whitelist or reaches
+// the playtime threshold. The referral is marked as rewarded before giving the reward,
+// so concurrent reports reward the referrer only once, and marked back if that fails.
+func (s *service) ReportProgress(ctx context.Context, progress Progress) (bool, error) {
+	ctx, span := tracer.Start(ctx, "promo.ReportProgress")
+	defer span.End()
+
+	referral, err := s.repo.FindReferralByReferee(ctx, progress.UserID)
+	if errors.Is(err, ErrReferralNotFound) {
+		// Most players come without a referral.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	playtime := time.Duration(progress.PlaytimeMinutes) * time.Minute
+	if referral.Status != ReferralPending || (!progress.Whitelisted && playtime < s.config.ReferralPlaytime) {
+		return false, nil
+	}
+
+	ok, err := s.repo.UpdateReferralStatus(ctx, referral.ID, ReferralPending, ReferralRewarded)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if err := s.rewardReferrer(ctx, referral); err != nil {
+		if _, updateErr := s.repo.UpdateReferralStatus(ctx, referral.ID, ReferralRewarded, ReferralPending); updateErr != nil {
+			err = errors.Join(err, fmt.Errorf("update referral: %w", updateErr))
+		}
+		return false, fmt.Errorf("reward referrer: %w", err)
+	}
+	return true, nil
+}
+
+// checkLimits checks the validity window and the usage limits of the code.
+func (s *service) checkLimits(c Code, userUses int) error {
+	switch {
+	case !c.ValidAt(s.now()):
+		return ErrCodeUnavailable
+	case c.MaxUses > 0 && c.Uses >= c.MaxUses:
+		return ErrCodeExhausted
+	case c.MaxPerUser > 0 && userUses >= c.MaxPerUser:
+		return ErrUserLimit
+	}
+	return nil
+}
+
+// reward gives the currency or VIP reward of the redeemed code.
+func (s *service) reward(ctx context.Context, c Code, redemption Redemption) error {
+	note := "promo code " + c.Code
+	if c.Reward == RewardVIP {
+		_, err := s.vip.Grant(ctx, vip.Grant{
+			UserID:   redemption.UserID,
+			Tier:     c.VIPTier,
+			Days:     c.VIPDays,
+			Source:   vip.SourceReward,
+			IssuedBy: issuer,
+			Note:     note,
+		})
+		return err
+	}
+	return s.deposit(ctx, redemption.UserID, c.CurrencyAmount, "promo:"+redemption.ID.String(), note)
+}
+
+// rewardReferrer gives the configured rewards to the referrer.
+// The currency goes first as its deposit is idempotent and may be repeated on retries.
+func (s *service) rewardReferrer(ctx context.Context, referral Referral) error {
+	note := "referral of " + referral.RefereeID.String()
+	if s.config.ReferralCurrency > 0 {
+		err := s.deposit(ctx, referral.ReferrerID, s.config.ReferralCurrency, "referral:"+referral.ID.String(), note)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.config.ReferralVIPTier != "" && s.config.ReferralVIPDays > 0 {
+		_, err := s.vip.Grant(ctx, vip.Grant{
+			UserID:   referral.ReferrerID,
+			Tier:     s.config.ReferralVIPTier,
+			Days:     s.config.ReferralVIPDays,
+			Source:   vip.SourceReward,
+			IssuedBy: issuer,
+			Note:     note,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deposit moves the reward currency from the mint to the wallet of the user.
+func (s *service) deposit(ctx context.Context, userID uuid.UUID, amount int64, key, note string) error {
+	wallet, err := s.ledger.Wallet(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("find wallet: %w", err)
+	}
+
+	_, err = s.ledger.Post(ctx, ledger.Transaction{
+		IdempotencyKey: key,
+		Kind:           ledger.TransactionReward,
+		Description:    note,
+		IssuedBy:       issuer,
+		Entries: []ledger.Entry{
+			{AccountID: ledger.MintAccountID, Amount: -amount},
+			{AccountID: wallet.ID, Amount: amount},
+		},
+	})
+	return err
+}
+
+// normalizeCode makes codes case insensitive.
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// generateReferralCode returns a random referral code.
+func generateReferralCode() (string, error) {
+	b := make([]byte, referralLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate referral code: %w", err)
+	}
+	for i := range b {
+		b[i] = referralAlphabet[int(b[i])%len(referralAlphabet)]
+	}
+	return string(b), nil
+}
+
+// validateCode normalizes the code and checks its reward specific fields.
+func validateCode(code *Code) error {
+	code.Code = normalizeCode(code.Code)
+	code.Currency = strings.ToUpper(strings.TrimSpace(code.Currency))
+	code.VIPTier = strings.ToLower(strings.TrimSpace(code.VIPTier))
+
+	var errs []error
+	if !codePattern.MatchString(code.Code) {
+		errs = append(errs, errors.New("code must be 3 to 32 letters, digits, dashes or underscores"))
+	}
+	if code.MaxUses < 0 || code.MaxPerUser < 0 {
+		errs = append(errs, errors.New("limits must not be negative"))
+	}
+	if code.Starts != nil && code.Ends != nil && !code.Ends.After(*code.Starts) {
+		errs = append(errs, errors.New("the code must end after it starts"))
+	}
+
+	switch code.Reward {
+	case RewardDiscount:
+		percent, amount := code.PercentOff > 0, code.AmountOff > 0
+		if percent == amount || code.PercentOff > 100 || code.PercentOff < 0 || code.AmountOff < 0 {
+			errs = append(errs, errors.New("discounts require either a percentage up to 100 or a positive amount"))
+		}
+		if amount && len(code.Currency) != 3 {
+			errs = append(errs, errors.New("amount discounts require a 3 letter currency code"))
+		}
+	case RewardCurrency:
+		if code.CurrencyAmount <= 0 {
+			errs = append(errs, errors.New("currency rewards require a positive amount"))
+		}
+	case RewardVIP:
+		if code.VIPTier == "" || code.VIPDays <= 0 {
+			errs = append(errs, errors.New("vip rewards require a tier and a positive number of days"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown reward %q", code.Reward))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidCode, errors.Join(errs...))
+	}
+	return nil
+}
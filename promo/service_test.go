@@ -0,0 +1,501 @@
+package promo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/ledger"
+	"github.com/GTA5-RP-Aristocracy/site-back/shop"
+	"github.com/GTA5-RP-Aristocracy/site-back/vip"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) CreateCode(ctx context.Context, code Code) error {
+	args := m.Called(code)
+	return args.Error(0)
+}
+
+func (m *MockRepo) UpdateCode(ctx context.Context, code Code) error {
+	args := m.Called(code)
+	return args.Error(0)
+}
+
+func (m *MockRepo) FindCodeByID(ctx context.Context, id uuid.UUID) (Code, error) {
+	args := m.Called(id)
+	return args.Get(0).(Code), args.Error(1)
+}
+
+func (m *MockRepo) FindCodes(ctx context.Context) ([]Code, error) {
+	args := m.Called()
+	return args.Get(0).([]Code), args.Error(1)
+}
+
+// Redeem calls fn with the code and the user uses given to On, like the repository does under the lock.
+func (m *MockRepo) Redeem(ctx context.Context, code string, userID uuid.UUID, fn func(c Code, userUses int) (Redemption, error)) (Redemption, error) {
+	args := m.Called(code, userID)
+	if err := args.Error(2); err != nil {
+		return Redemption{}, err
+	}
+	return fn(args.Get(0).(Code), args.Int(1))
+}
+
+func (m *MockRepo) DeleteRedemption(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepo) FindRedemptionByOrder(ctx context.Context, orderID uuid.UUID) (Redemption, error) {
+	args := m.Called(orderID)
+	return args.Get(0).(Redemption), args.Error(1)
+}
+
+func (m *MockRepo) EnsureReferralCode(ctx context.Context, userID uuid.UUID, code string) (string, error) {
+	args := m.Called(userID, code)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRepo) FindReferrer(ctx context.Context, code string) (uuid.UUID, error) {
+	args := m.Called(code)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockRepo) CreateReferral(ctx context.Context, referral Referral) (bool, error) {
+	args := m.Called(referral)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindReferralByReferee(ctx context.Context, refereeID uuid.UUID) (Referral, error) {
+	args := m.Called(refereeID)
+	return args.Get(0).(Referral), args.Error(1)
+}
+
+func (m *MockRepo) FindReferrals(ctx context.Context, referrerID uuid.UUID) ([]Referral, error) {
+	args := m.Called(referrerID)
+	return args.Get(0).([]Referral), args.Error(1)
+}
+
+func (m *MockRepo) UpdateReferralStatus(ctx context.Context, id uuid.UUID, from, to ReferralStatus) (bool, error) {
+	args := m.Called(id, from, to)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockLedger struct {
+	ledger.Service
+	mock.Mock
+}
+
+func (m *MockLedger) Wallet(ctx context.Context, userID uuid.UUID) (ledger.Account, error) {
+	args := m.Called(userID)
+	return args.Get(0).(ledger.Account), args.Error(1)
+}
+
+func (m *MockLedger) Post(ctx context.Context, t ledger.Transaction) (ledger.Transaction, error) {
+	args := m.Called(t)
+	return args.Get(0).(ledger.Transaction), args.Error(1)
+}
+
+type MockVIP struct {
+	vip.Service
+	mock.Mock
+}
+
+func (m *MockVIP) Grant(ctx context.Context, grant vip.Grant) (vip.Subscription, error) {
+	args := m.Called(grant)
+	return args.Get(0).(vip.Subscription), args.Error(1)
+}
+
+// newTestService creates a service with a fixed clock.
+func newTestService(repo Repository, ledgerService ledger.Service, vipService vip.Service, config Config) *service {
+	s := NewService(repo, ledgerService, vipService, config).(*service)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+func TestService_CreateCode_Invalid(t *testing.T) {
+	cases := []struct {
+		testName string
+		code     Code
+	}{
+		{
+			testName: "short code",
+			code:     Code{Code: "ab", Reward: RewardCurrency, CurrencyAmount: 100},
+		},
+		{
+			testName: "percent and amount",
+			code:     Code{Code: "SALE", Reward: RewardDiscount, PercentOff: 10, AmountOff: 100, Currency: "USD"},
+		},
+		{
+			testName: "percent over 100",
+			code:     Code{Code: "SALE", Reward: RewardDiscount, PercentOff: 150},
+		},
+		{
+			testName: "amount without currency",
+			code:     Code{Code: "SALE", Reward: RewardDiscount, AmountOff: 100},
+		},
+		{
+			testName: "vip without days",
+			code:     Code{Code: "VIP", Reward: RewardVIP, VIPTier: "gold"},
+		},
+		{
+			testName: "unknown reward",
+			code:     Code{Code: "GIFT", Reward: "car"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			svc := newTestService(mockRepo, nil, nil, Config{})
+
+			_, err := svc.CreateCode(context.Background(), tc.code)
+
+			assert.ErrorIs(t, err, ErrInvalidCode)
+			mockRepo.AssertNotCalled(t, "CreateCode", mock.Anything)
+		})
+	}
+}
+
+func TestService_Redeem_Limits(t *testing.T) {
+	past, future := testNow.Add(-time.Hour), testNow.Add(time.Hour)
+
+	cases := []struct {
+		testName      string
+		code          Code
+		userUses      int
+		expectedError error
+	}{
+		{
+			testName:      "inactive",
+			code:          Code{Reward: RewardCurrency, CurrencyAmount: 100},
+			expectedError: ErrCodeUnavailable,
+		},
+		{
+			testName:      "not started",
+			code:          Code{Reward: RewardCurrency, CurrencyAmount: 100, Active: true, Starts: &future},
+			expectedError: ErrCodeUnavailable,
+		},
+		{
+			testName:      "ended",
+			code:          Code{Reward: RewardCurrency, CurrencyAmount: 100, Active: true, Ends: &past},
+			expectedError: ErrCodeUnavailable,
+		},
+		{
+			testName:      "used up",
+			code:          Code{Reward: RewardCurrency, CurrencyAmount: 100, Active: true, MaxUses: 10, Uses: 10},
+			expectedError: ErrCodeExhausted,
+		},
+		{
+			testName:      "used by the account",
+			code:          Code{Reward: RewardCurrency, CurrencyAmount: 100, Active: true, MaxPerUser: 1},
+			userUses:      1,
+			expectedError: ErrUserLimit,
+		},
+		{
+			testName:      "discount",
+			code:          Code{Reward: RewardDiscount, PercentOff: 10, Active: true},
+			expectedError: ErrDiscountOnly,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			userID := uuid.New()
+			mockRepo := new(MockRepo)
+			mockRepo.On("Redeem", "GIFT", userID).Return(tc.code, tc.userUses, nil)
+			svc := newTestService(mockRepo, nil, nil, Config{})
+
+			_, err := svc.Redeem(context.Background(), userID, " gift ")
+
+			assert.ErrorIs(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestService_Redeem_Currency(t *testing.T) {
+	userID := uuid.New()
+	wallet := ledger.Account{ID: uuid.New(), Kind: ledger.AccountWallet}
+	code := Code{ID: uuid.New(), Code: "GIFT", Reward: RewardCurrency, CurrencyAmount: 500, Active: true}
+
+	mockRepo := new(MockRepo)
+	mockRepo.On("Redeem", "GIFT", userID).Return(code, 0, nil)
+	mockLedger := new(MockLedger)
+	mockLedger.On("Wallet", userID).Return(wallet, nil)
+	mockLedger.On("Post", mock.Anything).Return(ledger.Transaction{}, nil)
+	svc := newTestService(mockRepo, mockLedger, nil, Config{})
+
+	redemption, err := svc.Redeem(context.Background(), userID, "gift")
+
+	require.NoError(t, err)
+	assert.Equal(t, code.ID, redemption.CodeID)
+	assert.Equal(t, int64(500), redemption.Amount)
+
+	posted := mockLedger.Calls[1].Arguments.Get(0).(ledger.Transaction)
+	assert.Equal(t, ledger.TransactionReward, posted.Kind)
+	assert.Equal(t, "promo:"+redemption.ID.String(), posted.IdempotencyKey)
+	assert.Equal(t, []ledger.Entry{
+		{AccountID: ledger.MintAccountID, Amount: -500},
+		{AccountID: wallet.ID, Amount: 500},
+	}, posted.Entries)
+}
+
+func TestService_Redeem_RewardFailure(t *testing.T) {
+	userID := uuid.New()
+	code := Code{ID: uuid.New(), Code: "VIP", Reward: RewardVIP, VIPTier: "gold", VIPDays: 7, Active: true, MaxUses: 1}
+
+	mockRepo := new(MockRepo)
+	mockRepo.On("Redeem", "VIP", userID).Return(code, 0, nil)
+	mockRepo.On("DeleteRedemption", mock.Anything).Return(nil)
+	mockVIP := new(MockVIP)
+	mockVIP.On("Grant", mock.Anything).Return(vip.Subscription{}, vip.ErrUnknownTier)
+	svc := newTestService(mockRepo, nil, mockVIP, Config{})
+
+	_, err := svc.Redeem(context.Background(), userID, "vip")
+
+	assert.ErrorIs(t, err, vip.ErrUnknownTier)
+	// The use is given back so the code isn't burnt by the failure.
+	mockRepo.AssertCalled(t, "DeleteRedemption", mock.Anything)
+}
+
+func TestService_Discount(t *testing.T) {
+	order := shop.Order{ID: uuid.New(), UserID: uuid.New(), Total: 1000, Currency: "USD"}
+
+	cases := []struct {
+		testName         string
+		code             Code
+		expectedDiscount int64
+		expectedError    error
+	}{
+		{
+			testName:         "percent",
+			code:             Code{Reward: RewardDiscount, PercentOff: 15, Active: true},
+			expectedDiscount: 150,
+		},
+		{
+			testName:         "amount",
+			code:             Code{Reward: RewardDiscount, AmountOff: 300, Currency: "USD", Active: true},
+			expectedDiscount: 300,
+		},
+		{
+			testName:         "amount over total",
+			code:             Code{Reward: RewardDiscount, AmountOff: 5000, Currency: "USD", Active: true},
+			expectedDiscount: 1000,
+		},
+		{
+			testName:      "other currency",
+			code:          Code{Reward: RewardDiscount, AmountOff: 300, Currency: "EUR", Active: true},
+			expectedError: ErrCurrencyMismatch,
+		},
+		{
+			testName:      "not a discount",
+			code:          Code{Reward: RewardCurrency, CurrencyAmount: 100, Active: true},
+			expectedError: ErrNotDiscount,
+		},
+		{
+			testName:      "used up",
+			code:          Code{Reward: RewardDiscount, PercentOff: 15, Active: true, MaxUses: 1, Uses: 1},
+			expectedError: ErrCodeExhausted,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			mockRepo.On("Redeem", "SALE", order.UserID).Return(tc.code, 0, nil)
+			svc := newTestService(mockRepo, nil, nil, Config{})
+
+			discount, err := svc.Discount(context.Background(), "sale", order)
+
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+				assert.ErrorIs(t, err, shop.ErrPromoRejected)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedDiscount, discount)
+		})
+	}
+}
+
+func TestService_Discount_UnknownCode(t *testing.T) {
+	order := shop.Order{ID: uuid.New(), UserID: uuid.New(), Total: 1000, Currency: "USD"}
+
+	mockRepo := new(MockRepo)
+	mockRepo.On("Redeem", "NOPE", order.UserID).Return(Code{}, 0, ErrCodeNotFound)
+	svc := newTestService(mockRepo, nil, nil, Config{})
+
+	_, err := svc.Discount(context.Background(), "nope", order)
+
+	assert.ErrorIs(t, err, shop.ErrPromoRejected)
+}
+
+func TestService_Release(t *testing.T) {
+	order := shop.Order{ID: uuid.New()}
+	redemption := Redemption{ID: uuid.New()}
+
+	mockRepo := new(MockRepo)
+	mockRepo.On("FindRedemptionByOrder", order.ID).Return(redemption, nil)
+	mockRepo.On("DeleteRedemption", redemption.ID).Return(nil)
+	svc := newTestService(mockRepo, nil, nil, Config{})
+
+	require.NoError(t, svc.Release(context.Background(), order))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_ClaimReferral(t *testing.T) {
+	referrerID, refereeID := uuid.New(), uuid.New()
+
+	cases := []struct {
+		testName      string
+		refereeID     uuid.UUID
+		created       bool
+		expectedError error
+	}{
+		{
+			testName:  "ok",
+			refereeID: refereeID,
+			created:   true,
+		},
+		{
+			testName:      "self",
+			refereeID:     referrerID,
+			expectedError: ErrSelfReferral,
+		},
+		{
+			testName:      "already referred",
+			refereeID:     refereeID,
+			expectedError: ErrAlreadyReferred,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			mockRepo.On("FindReferrer", "FRIEND42").Return(referrerID, nil)
+			mockRepo.On("CreateReferral", mock.Anything).Return(tc.created, nil)
+			svc := newTestService(mockRepo, nil, nil, Config{})
+
+			referral, err := svc.ClaimReferral(context.Background(), tc.refereeID, "friend42")
+
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, referrerID, referral.ReferrerID)
+			assert.Equal(t, ReferralPending, referral.Status)
+		})
+	}
+}
+
+func TestService_ReportProgress(t *testing.T) {
+	referral := Referral{ID: uuid.New(), ReferrerID: uuid.New(), RefereeID: uuid.New(), Status: ReferralPending}
+	config := Config{ReferralPlaytime: 10 * time.Hour, ReferralCurrency: 1000}
+
+	cases := []struct {
+		testName         string
+		progress         Progress
+		status           ReferralStatus
+		updated          bool
+		expectedRewarded bool
+	}{
+		{
+			testName:         "whitelisted",
+			progress:         Progress{UserID: referral.RefereeID, Whitelisted: true},
+			status:           ReferralPending,
+			updated:          true,
+			expectedRewarded: true,
+		},
+		{
+			testName:         "playtime reached",
+			progress:         Progress{UserID: referral.RefereeID, PlaytimeMinutes: 600},
+			status:           ReferralPending,
+			updated:          true,
+			expectedRewarded: true,
+		},
+		{
+			testName: "not qualified",
+			progress: Progress{UserID: referral.RefereeID, PlaytimeMinutes: 599},
+			status:   ReferralPending,
+		},
+		{
+			testName: "already rewarded",
+			progress: Progress{UserID: referral.RefereeID, Whitelisted: true},
+			status:   ReferralRewarded,
+		},
+		{
+			testName: "rewarded concurrently",
+			progress: Progress{UserID: referral.RefereeID, Whitelisted: true},
+			status:   ReferralPending,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			stored := referral
+			stored.Status = tc.status
+
+			mockRepo := new(MockRepo)
+			mockRepo.On("FindReferralByReferee", referral.RefereeID).Return(stored, nil)
+			mockRepo.On("UpdateReferralStatus", referral.ID, ReferralPending, ReferralRewarded).Return(tc.updated, nil)
+			mockLedger := new(MockLedger)
+			mockLedger.On("Wallet", referral.ReferrerID).Return(ledger.Account{ID: uuid.New()}, nil)
+			mockLedger.On("Post", mock.Anything).Return(ledger.Transaction{}, nil)
+			svc := newTestService(mockRepo, mockLedger, nil, config)
+
+			rewarded, err := svc.ReportProgress(context.Background(), tc.progress)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedRewarded, rewarded)
+			if tc.expectedRewarded {
+				posted := mockLedger.Calls[1].Arguments.Get(0).(ledger.Transaction)
+				assert.Equal(t, "referral:"+referral.ID.String(), posted.IdempotencyKey)
+			} else {
+				mockLedger.AssertNotCalled(t, "Post", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestService_ReportProgress_NoReferral(t *testing.T) {
+	userID := uuid.New()
+
+	mockRepo := new(MockRepo)
+	mockRepo.On("FindReferralByReferee", userID).Return(Referral{}, ErrReferralNotFound)
+	svc := newTestService(mockRepo, nil, nil, Config{})
+
+	rewarded, err := svc.ReportProgress(context.Background(), Progress{UserID: userID, Whitelisted: true})
+
+	require.NoError(t, err)
+	assert.False(t, rewarded)
+}
+
+func TestService_ReportProgress_RewardFailure(t *testing.T) {
+	referral := Referral{ID: uuid.New(), ReferrerID: uuid.New(), RefereeID: uuid.New(), Status: ReferralPending}
+	errLedger := errors.New("ledger is down")
+
+	mockRepo := new(MockRepo)
+	mockRepo.On("FindReferralByReferee", referral.RefereeID).Return(referral, nil)
+	mockRepo.On("UpdateReferralStatus", referral.ID, ReferralPending, ReferralRewarded).Return(true, nil)
+	mockRepo.On("UpdateReferralStatus", referral.ID, ReferralRewarded, ReferralPending).Return(true, nil)
+	mockLedger := new(MockLedger)
+	mockLedger.On("Wallet", referral.ReferrerID).Return(ledger.Account{}, errLedger)
+	svc := newTestService(mockRepo, mockLedger, nil, Config{ReferralCurrency: 1000})
+
+	rewarded, err := svc.ReportProgress(context.Background(), Progress{UserID: referral.RefereeID, Whitelisted: true})
+
+	assert.ErrorIs(t, err, errLedger)
+	assert.False(t, rewarded)
+	// The referral is pending again so the next report retries the reward.
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,27 @@
+package server
+
+import "time"
+
+type (
+	// Config represents the configuration options for the HTTP server.
+	Config struct {
+		// Addr is the TCP address to listen on.
+		Addr string `env:"HTTP_ADDR" envDefault:":8080" yaml:"addr"`
+		// ReadTimeout limits reading the whole request, ReadHeaderTimeout the headers only.
+		ReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" envDefault:"30s" yaml:"read_timeout"`
+		ReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" envDefault:"5s" yaml:"read_header_timeout"`
+		// WriteTimeout limits writing the response, it has to be longer than the handler timeout.
+		WriteTimeout time.Duration `env:"HTTP_WRITE_TIMEOUT" envDefault:"75s" yaml:"write_timeout"`
+		// IdleTimeout closes the keep-alive connections waiting for the next request.
+		IdleTimeout time.Duration `env:"HTTP_IDLE_TIMEOUT" envDefault:"120s" yaml:"idle_timeout"`
+		// HandlerTimeout cancels the context of the requests running longer.
+		HandlerTimeout time.Duration `env:"HTTP_HANDLER_TIMEOUT" envDefault:"60s" yaml:"handler_timeout"`
+		// MaxHeaderBytes limits the size of the request headers.
+		MaxHeaderBytes int `env:"HTTP_MAX_HEADER_BYTES" envDefault:"1048576" yaml:"max_header_bytes"`
+		// ShutdownTimeout is how long the in-flight requests are drained on shutdown.
+		ShutdownTimeout time.Duration `env:"HTTP_SHUTDOWN_TIMEOUT" envDefault:"30s" yaml:"shutdown_timeout"`
+		// ShutdownDelay keeps serving after the server reports not ready,
+		// so the load balancer stops routing to it before the listener closes.
+		ShutdownDelay time.Duration `env:"HTTP_SHUTDOWN_DELAY" yaml:"shutdown_delay"`
+	}
+)
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// This file contains the HTTP server with graceful shutdown.
+
+type (
+	// Server serves HTTP until its context is cancelled, then drains the in-flight requests.
+	Server struct {
+		server *http.Server
+		config Config
+		logger zerolog.Logger
+		ready  atomic.Bool
+	}
+)
+
+// New creates a new HTTP server of the handler.
+func New(config Config, handler http.Handler, logger zerolog.Logger) *Server {
+	return &Server{
+		server: &http.Server{
+			Addr:              config.Addr,
+			Handler:           handler,
+			ReadTimeout:       config.ReadTimeout,
+			ReadHeaderTimeout: config.ReadHeaderTimeout,
+			WriteTimeout:      config.WriteTimeout,
+			IdleTimeout:       config.IdleTimeout,
+			MaxHeaderBytes:    config.MaxHeaderBytes,
+		},
+		config: config,
+		logger: logger,
+	}
+}
+
+// Run listens on the configured address and serves until the context is cancelled,
+// e.g. by SIGINT or SIGTERM. It returns nil if the server shut down gracefully.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve is like Run with the listener.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.server.Serve(listener)
+	}()
+	s.ready.Store(true)
+
+	select {
+	case err := <-serveErr:
+		s.ready.Store(false)
+		return fmt.Errorf("serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	return s.shutdown(serveErr)
+}
+
+// shutdown stops accepting connections and waits for the in-flight requests to finish.
+// The requests still running after the shutdown timeout are cut off.
+func (s *Server) shutdown(serveErr <-chan error) error {
+	s.ready.Store(false)
+	s.logger.Info().Dur("timeout", s.config.ShutdownTimeout).Msg("shutting down the web server")
+
+	time.Sleep(s.config.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.server.Close()
+		return fmt.Errorf("drain requests: %w", err)
+	}
+
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve: %w", err)
+	}
+	return nil
+}
+
+// Ready reports whether the server accepts requests, it turns false as soon as the shutdown starts.
+func (s *Server) Ready() bool {
+	return s.ready.Load()
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConfig returns the configuration with short timeouts.
+func testConfig() Config {
+	return Config{
+		ReadTimeout:     time.Second,
+		WriteTimeout:    time.Second,
+		ShutdownTimeout: time.Second,
+	}
+}
+
+// serve runs the server on a random port and returns its address and result.
+func serve(t *testing.T, ctx context.Context, s *Server) (string, <-chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- s.Serve(ctx, listener)
+	}()
+	return "http://" + listener.Addr().String(), result
+}
+
+func TestServer_Drain(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := New(testConfig(), handler, zerolog.Nop())
+	addr, result := serve(t, ctx, s)
+
+	response := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(addr)
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- string(body)
+	}()
+	<-started
+	assert.True(t, s.Ready())
+
+	// The shutdown waits for the request in flight.
+	cancel()
+	assert.Eventually(t, func() bool { return !s.Ready() }, time.Second, time.Millisecond)
+	select {
+	case err := <-result:
+		t.Fatalf("the server stopped with a request in flight: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The new connections are refused meanwhile.
+	_, err := http.Get(addr)
+	assert.Error(t, err)
+
+	close(release)
+	assert.Equal(t, "done", <-response)
+	assert.NoError(t, <-result)
+}
+
+func TestServer_DrainTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	config := testConfig()
+	config.ShutdownTimeout = 50 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, result := serve(t, ctx, New(config, handler, zerolog.Nop()))
+
+	go http.Get(addr)
+	<-started
+	cancel()
+
+	assert.ErrorIs(t, <-result, context.DeadlineExceeded)
+}
@@ -0,0 +1,13 @@
+package shop
+
+type (
+	// Config represents the configuration options for the shop.
+	Config struct {
+		// FakeProvider takes the payments with the fake payment provider, in the dev env only.
+		// Without a payment provider the store and its webhooks aren't served.
+		FakeProvider bool `env:"SHOP_FAKE_PROVIDER" envDefault:"false" yaml:"fake_provider"`
+		// FakeAutoCapture makes the fake payment provider confirm payments right away.
+		// Disable it to confirm payments with simulated webhooks instead.
+		FakeAutoCapture bool `env:"SHOP_FAKE_AUTO_CAPTURE" envDefault:"false" yaml:"fake_auto_capture"`
+	}
+)
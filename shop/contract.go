@@ -0,0 +1,97 @@
+package shop
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// This file defines the shop related interfaces.
+
+type (
+	// Service represents the shop service interface.
+	Service interface {
+		// ListProducts fetches all active catalog products.
+		ListProducts(ctx context.Context) ([]Product, error)
+		// GetProduct fetches a product by id.
+		GetProduct(ctx context.Context, id uuid.UUID) (Product, error)
+		// CreateProduct adds a new product to the catalog.
+		CreateProduct(ctx context.Context, product Product) (Product, error)
+		// UpdateProduct changes an existing catalog product.
+		UpdateProduct(ctx context.Context, product Product) (Product, error)
+		// PlaceOrder creates an order for the user and starts its payment.
+		// The promo code is optional, it discounts the order total.
+		PlaceOrder(ctx context.Context, userID uuid.UUID, lines []OrderLine, promoCode string) (Order, Payment, error)
+		// GetOrder fetches an order with its items by id.
+		GetOrder(ctx context.Context, id uuid.UUID) (Order, error)
+		// CancelOrder cancels an order that hasn't been paid yet.
+		CancelOrder(ctx context.Context, id uuid.UUID) error
+		// MarkPaid marks the order as paid and creates its entitlements.
+		MarkPaid(ctx context.Context, id uuid.UUID) error
+		// ApplyPayment applies a payment event reported by the payment provider.
+		// It reports whether the event changed the order, duplicates and stale events are ignored.
+		ApplyPayment(ctx context.Context, event PaymentEvent) (bool, error)
+		// PendingEntitlements fetches entitlements the game server hasn't delivered yet.
+		PendingEntitlements(ctx context.Context, limit int) ([]Entitlement, error)
+		// AckEntitlement marks an entitlement as delivered by the game server.
+		AckEntitlement(ctx context.Context, id uuid.UUID) (Entitlement, error)
+	}
+
+	// Repository represents the shop repository interface.
+	Repository interface {
+		// CreateProduct inserts a new product into the repository.
+		CreateProduct(ctx context.Context, product Product) error
+		// UpdateProduct updates a product in the repository.
+		UpdateProduct(ctx context.Context, product Product) error
+		// FindProductByID returns a product by id.
+		FindProductByID(ctx context.Context, id uuid.UUID) (Product, error)
+		// FindProducts returns catalog products, optionally only the active ones.
+		FindProducts(ctx context.Context, activeOnly bool) ([]Product, error)
+		// CreateOrder inserts an order with its items.
+		CreateOrder(ctx context.Context, order Order) error
+		// FindOrderByID returns an order with its items by id.
+		FindOrderByID(ctx context.Context, id uuid.UUID) (Order, error)
+		// SetOrderPayment stores the payment reference of an order.
+		SetOrderPayment(ctx context.Context, id uuid.UUID, provider, reference string) error
+		// UpdateOrderStatus moves the order from one status to another.
+		// It returns ErrInvalidTransition if the order is not in the expected status anymore.
+		UpdateOrderStatus(ctx context.Context, id uuid.UUID, from, to OrderStatus) error
+		// PayOrder moves the order to the paid status and inserts its entitlements atomically.
+		// Orders without entitlements are delivered right away.
+		PayOrder(ctx context.Context, id uuid.UUID, from OrderStatus, entitlements []Entitlement) error
+		// RefundOrder moves the order to the refunded status and revokes its undelivered entitlements.
+		RefundOrder(ctx context.Context, id uuid.UUID, from OrderStatus) error
+		// FindPendingEntitlements returns the oldest undelivered entitlements.
+		FindPendingEntitlements(ctx context.Context, limit int) ([]Entitlement, error)
+		// DeliverEntitlement marks an entitlement as delivered and completes
+		// the order once all of its entitlements are delivered.
+		DeliverEntitlement(ctx context.Context, id uuid.UUID) (Entitlement, error)
+	}
+
+	// Fulfiller delivers the products of a kind the backend tracks itself, e.g. VIP subscriptions.
+	// Such products don't get entitlements for the game server. Both methods may be called
+	// again for the same order item and must be idempotent.
+	Fulfiller interface {
+		// Fulfill delivers the order item.
+		Fulfill(ctx context.Context, order Order, item OrderItem, product Product) error
+		// Revoke takes the delivered order item back, e.g. after a refund.
+		Revoke(ctx context.Context, order Order, item OrderItem, product Product) error
+	}
+
+	// PaymentProvider represents a payment processor the orders are paid with.
+	PaymentProvider interface {
+		// Name returns the unique provider name.
+		Name() string
+		// CreatePayment registers a payment for the order at the provider.
+		CreatePayment(ctx context.Context, order Order) (Payment, error)
+	}
+
+	// Discounter applies promo codes to orders.
+	Discounter interface {
+		// Discount redeems the promo code for the priced order and returns the discount
+		// in minor units. Rejected codes are reported with ErrPromoRejected.
+		Discount(ctx context.Context, code string, order Order) (int64, error)
+		// Release cancels the redemption of an order that couldn't be placed.
+		Release(ctx context.Context, order Order) error
+	}
+)
@@ -0,0 +1,20 @@
+package shop
+
+// This file contains shop related errors.
+
+import "errors"
+
+// Define custom errors.
+var (
+	ErrProductNotFound     = errors.New("shop: product not found")
+	ErrOrderNotFound       = errors.New("shop: order not found")
+	ErrEntitlementNotFound = errors.New("shop: entitlement not found")
+	ErrInvalidProduct      = errors.New("shop: invalid product")
+	ErrProductInactive     = errors.New("shop: product is not available")
+	ErrEmptyOrder          = errors.New("shop: order has no items")
+	ErrInvalidQuantity     = errors.New("shop: invalid quantity")
+	ErrCurrencyMismatch    = errors.New("shop: products have different currencies")
+	ErrInvalidTransition   = errors.New("shop: invalid order state transition")
+	ErrPaymentMismatch     = errors.New("shop: payment doesn't match the order")
+	ErrPromoRejected       = errors.New("shop: promo code can't be applied")
+)
@@ -0,0 +1,287 @@
+package shop
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/api"
+	"github.com/GTA5-RP-Aristocracy/site-back/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+)
+
+// This file contains shop related http handlers.
+
+const (
+	pathRoot         = "/shop"
+	pathProducts     = "/products"
+	pathProduct      = "/products/{id}"
+	pathOrders       = "/orders"
+	pathOrder        = "/orders/{id}"
+	pathOrderCancel  = "/orders/{id}/cancel"
+	pathEntitlements = "/entitlements"
+	pathEntitlement  = "/entitlements/{id}/ack"
+	pathAdmin        = "/admin"
+)
+
+// errorMappings map the shop errors to the problem responses.
+var errorMappings = []api.Mapping{
+	{Err: ErrProductNotFound, Status: http.StatusNotFound, Code: "product_not_found"},
+	{Err: ErrOrderNotFound, Status: http.StatusNotFound, Code: "order_not_found"},
+	{Err: ErrEntitlementNotFound, Status: http.StatusNotFound, Code: "entitlement_not_found"},
+	{Err: ErrInvalidProduct, Status: http.StatusBadRequest, Code: "invalid_product"},
+	{Err: ErrProductInactive, Status: http.StatusBadRequest, Code: "product_inactive"},
+	{Err: ErrEmptyOrder, Status: http.StatusBadRequest, Code: "empty_order"},
+	{Err: ErrInvalidQuantity, Status: http.StatusBadRequest, Code: "invalid_quantity"},
+	{Err: ErrCurrencyMismatch, Status: http.StatusBadRequest, Code: "currency_mismatch"},
+	{Err: ErrPromoRejected, Status: http.StatusBadRequest, Code: "promo_rejected"},
+	{Err: ErrInvalidTransition, Status: http.StatusConflict, Code: "invalid_transition"},
+}
+
+type (
+	// Handler represents a set of http handlers for the donation store.
+	Handler struct {
+		service Service
+		auth    auth.Config
+	}
+
+	// placeOrderRequest represents the order placement request body.
+	placeOrderRequest struct {
+		Items     []OrderLine `json:"items"`
+		PromoCode string      `json:"promo_code"`
+	}
+
+	// placeOrderResponse represents the order placement response body.
+	placeOrderResponse struct {
+		Order   Order   `json:"order"`
+		Payment Payment `json:"payment"`
+	}
+)
+
+// NewHandler creates a new shop http handler.
+func NewHandler(service Service, authConfig auth.Config) *Handler {
+	return &Handler{service, authConfig}
+}
+
+// RegisterShopRouter registers shop routes.
+func (h *Handler) RegisterShopRouter(externalRouter chi.Router) {
+	r := chi.NewRouter()
+
+	// Public catalog.
+	r.Get(pathProducts, h.ListProducts)
+	r.Get(pathProduct, h.GetProduct)
+
+	// Player orders.
+	r.Group(func(r chi.Router) {
+		r.Use(auth.RequireUser(h.auth))
+		r.Post(pathOrders, h.PlaceOrder)
+		r.Get(pathOrder, h.GetOrder)
+		r.Post(pathOrderCancel, h.CancelOrder)
+	})
+
+	// Game server delivery.
+	r.Group(func(r chi.Router) {
+		r.Use(auth.RequireToken(h.auth.ServerToken))
+		r.Get(pathEntitlements, h.PendingEntitlements)
+		r.Post(pathEntitlement, h.AckEntitlement)
+	})
+
+	// Catalog management.
+	r.Route(pathAdmin, func(r chi.Router) {
+		r.Use(auth.RequireToken(h.auth.AdminToken))
+		r.Post(pathProducts, h.CreateProduct)
+		r.Put(pathProduct, h.UpdateProduct)
+	})
+
+	externalRouter.Mount(pathRoot, r)
+}
+
+// ListProducts handles the catalog request.
+func (h *Handler) ListProducts(w http.ResponseWriter, r *http.Request) {
+	products, err := h.service.ListProducts(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, products)
+}
+
+// GetProduct handles the product request.
+func (h *Handler) GetProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.service.GetProduct(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, product)
+}
+
+// CreateProduct handles the product creation request.
+func (h *Handler) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	var product Product
+	if err := api.DecodeJSON(w, r, &product); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	product, err := h.service.CreateProduct(r.Context(), product)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, product)
+}
+
+// UpdateProduct handles the product update request.
+func (h *Handler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	var product Product
+	if err := api.DecodeJSON(w, r, &product); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	product.ID = id
+
+	product, err := h.service.UpdateProduct(r.Context(), product)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, product)
+}
+
+// PlaceOrder handles the order placement request.
+func (h *Handler) PlaceOrder(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.UserID(r.Context())
+
+	var req placeOrderRequest
+	if err := api.DecodeJSON(w, r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	order, payment, err := h.service.PlaceOrder(r.Context(), userID, req.Items, req.PromoCode)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, placeOrderResponse{order, payment})
+}
+
+// GetOrder handles the order request of the order owner.
+func (h *Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.ownOrder(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, order)
+}
+
+// CancelOrder handles the order cancellation request of the order owner.
+func (h *Handler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.ownOrder(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.CancelOrder(r.Context(), order.ID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PendingEntitlements handles the game server entitlements poll.
+func (h *Handler) PendingEntitlements(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	entitlements, err := h.service.PendingEntitlements(r.Context(), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if entitlements == nil {
+		entitlements = []Entitlement{}
+	}
+
+	writeJSON(w, http.StatusOK, entitlements)
+}
+
+// AckEntitlement handles the game server delivery acknowledgement.
+func (h *Handler) AckEntitlement(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	entitlement, err := h.service.AckEntitlement(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entitlement)
+}
+
+// ownOrder fetches the order from the url and checks that it belongs to the current user.
+// Orders of other users are reported as not found.
+func (h *Handler) ownOrder(w http.ResponseWriter, r *http.Request) (Order, bool) {
+	id, ok := parseID(w, r)
+	if !ok {
+		return Order{}, false
+	}
+
+	order, err := h.service.GetOrder(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return Order{}, false
+	}
+
+	userID, _ := auth.UserID(r.Context())
+	if order.UserID != userID {
+		writeError(w, r, ErrOrderNotFound)
+		return Order{}, false
+	}
+	return order, true
+}
+
+// parseID parses the id url parameter.
+func parseID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, api.ErrInvalidID)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writeError writes the problem matching the error.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	api.WriteError(w, r, err, errorMappings...)
+}
+
+// writeJSON writes the response in JSON format.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
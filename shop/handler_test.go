@@ -0,0 +1,249 @@
+package shop
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAuth signs the sessions of the tests.
+var testAuth = auth.Config{SessionSecret: "0123456789abcdef0123456789abcdef", SessionTTL: time.Hour}
+
+// newSession returns the signed session of the user.
+func newSession(t *testing.T, id uuid.UUID) string {
+	session, err := auth.NewSession(testAuth, id, time.Now())
+	require.NoError(t, err)
+	return session
+}
+
+type MockService struct {
+	Service
+	funcPlaceOrder          func(userID uuid.UUID, lines []OrderLine) (Order, Payment, error)
+	funcGetOrder            func(id uuid.UUID) (Order, error)
+	funcPendingEntitlements func(limit int) ([]Entitlement, error)
+	funcAckEntitlement      func(id uuid.UUID) (Entitlement, error)
+}
+
+func (m *MockService) PlaceOrder(ctx context.Context, userID uuid.UUID, lines []OrderLine, promoCode string) (Order, Payment, error) {
+	return m.funcPlaceOrder(userID, lines)
+}
+
+func (m *MockService) GetOrder(ctx context.Context, id uuid.UUID) (Order, error) {
+	return m.funcGetOrder(id)
+}
+
+func (m *MockService) PendingEntitlements(ctx context.Context, limit int) ([]Entitlement, error) {
+	return m.funcPendingEntitlements(limit)
+}
+
+func (m *MockService) AckEntitlement(ctx context.Context, id uuid.UUID) (Entitlement, error) {
+	return m.funcAckEntitlement(id)
+}
+
+func TestHandlerPlaceOrder(t *testing.T) {
+	userID := uuid.New()
+
+	cases := []struct {
+		testName       string
+		requestBody    string
+		session        string
+		funcPlaceOrder func(userID uuid.UUID, lines []OrderLine) (Order, Payment, error)
+		expectedStatus int
+	}{
+		{
+			testName:    "ok",
+			requestBody: `{"items":[{"product_id":"123e4567-e89b-12d3-a456-426614174000","quantity":1}]}`,
+			session:     newSession(t, userID),
+			funcPlaceOrder: func(id uuid.UUID, lines []OrderLine) (Order, Payment, error) {
+				assert.Equal(t, userID, id)
+				assert.Len(t, lines, 1)
+				return Order{UserID: id}, Payment{}, nil
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			testName:       "unauthenticated",
+			requestBody:    `{"items":[]}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "invalid body",
+			requestBody:    `{"items":`,
+			session:        newSession(t, userID),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			testName:    "inactive product",
+			requestBody: `{"items":[{"product_id":"123e4567-e89b-12d3-a456-426614174000","quantity":1}]}`,
+			session:     newSession(t, userID),
+			funcPlaceOrder: func(id uuid.UUID, lines []OrderLine) (Order, Payment, error) {
+				return Order{}, Payment{}, ErrProductInactive
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			testName:    "internal error",
+			requestBody: `{"items":[{"product_id":"123e4567-e89b-12d3-a456-426614174000","quantity":1}]}`,
+			session:     newSession(t, userID),
+			funcPlaceOrder: func(id uuid.UUID, lines []OrderLine) (Order, Payment, error) {
+				return Order{}, Payment{}, errors.New("internal error")
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			router := chi.NewRouter()
+			handler := NewHandler(&MockService{funcPlaceOrder: tc.funcPlaceOrder}, testAuth)
+			handler.RegisterShopRouter(router)
+
+			req := httptest.NewRequest(http.MethodPost, "/shop/orders", strings.NewReader(tc.requestBody))
+			if tc.session != "" {
+				req.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: tc.session})
+			}
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestHandlerGetOrder(t *testing.T) {
+	owner := uuid.New()
+	orderID := uuid.New()
+
+	cases := []struct {
+		testName       string
+		session        uuid.UUID
+		path           string
+		expectedStatus int
+	}{
+		{
+			testName:       "owner",
+			session:        owner,
+			path:           "/shop/orders/" + orderID.String(),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			testName:       "other user",
+			session:        uuid.New(),
+			path:           "/shop/orders/" + orderID.String(),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			testName:       "invalid id",
+			session:        owner,
+			path:           "/shop/orders/invalid",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			router := chi.NewRouter()
+			handler := NewHandler(&MockService{
+				funcGetOrder: func(id uuid.UUID) (Order, error) {
+					return Order{ID: id, UserID: owner}, nil
+				},
+			}, testAuth)
+			handler.RegisterShopRouter(router)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: newSession(t, tc.session)})
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestHandlerEntitlements(t *testing.T) {
+	entitlementID := uuid.New()
+
+	cases := []struct {
+		testName       string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{
+			testName:       "poll",
+			method:         http.MethodGet,
+			path:           "/shop/entitlements?limit=10",
+			token:          "server",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			testName:       "poll without token",
+			method:         http.MethodGet,
+			path:           "/shop/entitlements",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "poll with admin token",
+			method:         http.MethodGet,
+			path:           "/shop/entitlements",
+			token:          "admin",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "ack",
+			method:         http.MethodPost,
+			path:           "/shop/entitlements/" + entitlementID.String() + "/ack",
+			token:          "server",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			testName:       "ack unknown",
+			method:         http.MethodPost,
+			path:           "/shop/entitlements/" + uuid.NewString() + "/ack",
+			token:          "server",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			router := chi.NewRouter()
+			handler := NewHandler(&MockService{
+				funcPendingEntitlements: func(limit int) ([]Entitlement, error) {
+					assert.Equal(t, 10, limit)
+					return nil, nil
+				},
+				funcAckEntitlement: func(id uuid.UUID) (Entitlement, error) {
+					if id != entitlementID {
+						return Entitlement{}, ErrEntitlementNotFound
+					}
+					return Entitlement{ID: id, Status: EntitlementDelivered}, nil
+				},
+			}, auth.Config{ServerToken: "server", AdminToken: "admin"})
+			handler.RegisterShopRouter(router)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package shop
+
+import "embed"
+
+// Migrations holds the SQL migrations of the donation store tables.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
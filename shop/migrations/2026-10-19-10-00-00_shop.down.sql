@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS shop_entitlement;
+DROP TABLE IF EXISTS shop_order_item;
+DROP TABLE IF EXISTS shop_order;
+DROP TABLE IF EXISTS shop_product;
\ No newline at end of file
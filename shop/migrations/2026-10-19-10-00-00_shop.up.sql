@@ -0,0 +1,59 @@
+CREATE TABLE IF NOT EXISTS shop_product (
+    id UUID PRIMARY KEY,
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    kind VARCHAR(32) NOT NULL,
+    price BIGINT NOT NULL CHECK (price >= 0),
+    currency VARCHAR(3) NOT NULL,
+    vip_tier VARCHAR(32) NOT NULL DEFAULT '',
+    vip_days INTEGER NOT NULL DEFAULT 0,
+    currency_amount BIGINT NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS shop_order (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES user_storage (id),
+    status VARCHAR(32) NOT NULL,
+    total BIGINT NOT NULL CHECK (total >= 0),
+    currency VARCHAR(3) NOT NULL,
+    payment_provider VARCHAR(64) NOT NULL DEFAULT '',
+    payment_ref VARCHAR(255) NOT NULL DEFAULT '',
+    created TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS shop_order_user_index ON shop_order (user_id);
+
+CREATE TABLE IF NOT EXISTS shop_order_item (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES shop_order (id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES shop_product (id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    unit_price BIGINT NOT NULL CHECK (unit_price >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS shop_order_item_order_index ON shop_order_item (order_id);
+
+CREATE TABLE IF NOT EXISTS shop_entitlement (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES shop_order (id),
+    order_item_id UUID NOT NULL REFERENCES shop_order_item (id),
+    user_id UUID NOT NULL REFERENCES user_storage (id),
+    product_id UUID NOT NULL REFERENCES shop_product (id),
+    kind VARCHAR(32) NOT NULL,
+    sku VARCHAR(64) NOT NULL,
+    quantity INTEGER NOT NULL,
+    vip_tier VARCHAR(32) NOT NULL DEFAULT '',
+    vip_days INTEGER NOT NULL DEFAULT 0,
+    currency_amount BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(32) NOT NULL,
+    created TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivered TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS shop_entitlement_order_index ON shop_entitlement (order_id);
+CREATE INDEX IF NOT EXISTS shop_entitlement_pending_index ON shop_entitlement (created) WHERE status = 'pending';
\ No newline at end of file
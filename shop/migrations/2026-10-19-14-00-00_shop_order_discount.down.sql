@@ -0,0 +1,2 @@
+ALTER TABLE shop_order DROP COLUMN IF EXISTS promo_code;
+ALTER TABLE shop_order DROP COLUMN IF EXISTS discount;
\ No newline at end of file
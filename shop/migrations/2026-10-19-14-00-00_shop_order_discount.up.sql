@@ -0,0 +1,2 @@
+ALTER TABLE shop_order ADD COLUMN IF NOT EXISTS discount BIGINT NOT NULL DEFAULT 0 CHECK (discount >= 0);
+ALTER TABLE shop_order ADD COLUMN IF NOT EXISTS promo_code VARCHAR(32) NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,164 @@
+package shop
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// This file defines the shop models, e.g. products, orders and entitlements.
+
+const (
+	// ProductItem is a one-off in-game item.
+	ProductItem ProductKind = "item"
+	// ProductVIP is a VIP tier granted for a number of days.
+	ProductVIP ProductKind = "vip"
+	// ProductCurrency is a pack of in-game currency.
+	ProductCurrency ProductKind = "currency"
+
+	OrderPending   OrderStatus = "pending"
+	OrderPaid      OrderStatus = "paid"
+	OrderDelivered OrderStatus = "delivered"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderFailed    OrderStatus = "failed"
+	OrderRefunded  OrderStatus = "refunded"
+
+	EntitlementPending   EntitlementStatus = "pending"
+	EntitlementDelivered EntitlementStatus = "delivered"
+	EntitlementRevoked   EntitlementStatus = "revoked"
+
+	PaymentPending   PaymentStatus = "pending"
+	PaymentSucceeded PaymentStatus = "succeeded"
+	PaymentFailed    PaymentStatus = "failed"
+	PaymentRefunded  PaymentStatus = "refunded"
+)
+
+type (
+	// ProductKind represents the kind of a catalog product.
+	ProductKind string
+
+	// OrderStatus represents the state of an order.
+	OrderStatus string
+
+	// EntitlementStatus represents the delivery state of an entitlement.
+	EntitlementStatus string
+
+	// PaymentStatus represents the state of a payment at the provider.
+	PaymentStatus string
+
+	// Product represents a catalog product.
+	// Prices are stored in minor currency units, e.g. kopecks or cents.
+	Product struct {
+		ID             uuid.UUID   `json:"id"`
+		SKU            string      `json:"sku"`
+		Name           string      `json:"name"`
+		Description    string      `json:"description"`
+		Kind           ProductKind `json:"kind"`
+		Price          int64       `json:"price"`
+		Currency       string      `json:"currency"`
+		VIPTier        string      `json:"vip_tier,omitempty"`
+		VIPDays        int         `json:"vip_days,omitempty"`
+		CurrencyAmount int64       `json:"currency_amount,omitempty"`
+		Active         bool        `json:"active"`
+		Created        time.Time   `json:"created"`
+		Updated        time.Time   `json:"updated"`
+	}
+
+	// Order represents a purchase of one or more products by a user.
+	Order struct {
+		ID              uuid.UUID   `json:"id"`
+		UserID          uuid.UUID   `json:"user_id"`
+		Status          OrderStatus `json:"status"`
+		Total           int64       `json:"total"`
+		Currency        string      `json:"currency"`
+		Discount        int64       `json:"discount"`
+		PromoCode       string      `json:"promo_code,omitempty"`
+		PaymentProvider string      `json:"payment_provider"`
+		PaymentRef      string      `json:"payment_ref"`
+		Items           []OrderItem `json:"items"`
+		Created         time.Time   `json:"created"`
+		Updated         time.Time   `json:"updated"`
+	}
+
+	// OrderItem represents a single order line with the price at the time of purchase.
+	OrderItem struct {
+		ID        uuid.UUID `json:"id"`
+		OrderID   uuid.UUID `json:"order_id"`
+		ProductID uuid.UUID `json:"product_id"`
+		Quantity  int       `json:"quantity"`
+		UnitPrice int64     `json:"unit_price"`
+	}
+
+	// OrderLine represents a requested product and its quantity.
+	OrderLine struct {
+		ProductID uuid.UUID `json:"product_id"`
+		Quantity  int       `json:"quantity"`
+	}
+
+	// Entitlement represents a purchased good the game server has to deliver to a player.
+	// It copies the product payload so later catalog changes don't affect paid orders.
+	Entitlement struct {
+		ID             uuid.UUID         `json:"id"`
+		OrderID        uuid.UUID         `json:"order_id"`
+		OrderItemID    uuid.UUID         `json:"order_item_id"`
+		UserID         uuid.UUID         `json:"user_id"`
+		ProductID      uuid.UUID         `json:"product_id"`
+		Kind           ProductKind       `json:"kind"`
+		SKU            string            `json:"sku"`
+		Quantity       int               `json:"quantity"`
+		VIPTier        string            `json:"vip_tier,omitempty"`
+		VIPDays        int               `json:"vip_days,omitempty"`
+		CurrencyAmount int64             `json:"currency_amount,omitempty"`
+		Status         EntitlementStatus `json:"status"`
+		Created        time.Time         `json:"created"`
+		Delivered      *time.Time        `json:"delivered,omitempty"`
+	}
+
+	// Payment represents a payment created at the payment provider for an order.
+	Payment struct {
+		Provider    string        `json:"provider"`
+		Reference   string        `json:"reference"`
+		CheckoutURL string        `json:"checkout_url"`
+		Status      PaymentStatus `json:"status"`
+	}
+
+	// PaymentEvent represents a payment state change reported by the payment provider.
+	PaymentEvent struct {
+		OrderID   uuid.UUID
+		Provider  string
+		Reference string
+		Status    PaymentStatus
+		Amount    int64
+		Currency  string
+	}
+)
+
+// orderTransitions lists the allowed order state transitions.
+// Payments may be confirmed after a cancellation and refunds may arrive
+// before the payment confirmation, because providers deliver events out of order.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending:   {OrderPaid, OrderCancelled, OrderFailed, OrderRefunded},
+	OrderFailed:    {OrderPaid, OrderCancelled, OrderRefunded},
+	OrderCancelled: {OrderPaid, OrderRefunded},
+	OrderPaid:      {OrderDelivered, OrderRefunded},
+	OrderDelivered: {OrderRefunded},
+}
+
+// CanTransitionTo reports whether the order may move from s to next.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, status := range orderTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Valid reports whether the product kind is known.
+func (k ProductKind) Valid() bool {
+	switch k {
+	case ProductItem, ProductVIP, ProductCurrency:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package shop
+
+import (
+	"context"
+	"fmt"
+)
+
+// This file contains payment provider implementations.
+
+const (
+	// FakeProviderName is the name of the fake payment provider.
+	FakeProviderName = "fake"
+)
+
+type (
+	// FakeProvider is a payment provider for local testing that doesn't charge anybody.
+	FakeProvider struct {
+		// AutoCapture makes every payment succeed immediately.
+		AutoCapture bool
+	}
+)
+
+// NewFakeProvider creates a new fake payment provider.
+func NewFakeProvider(autoCapture bool) *FakeProvider {
+	return &FakeProvider{AutoCapture: autoCapture}
+}
+
+// Name returns the provider name.
+func (p *FakeProvider) Name() string {
+	return FakeProviderName
+}
+
+// CreatePayment creates a fake payment for the order.
+func (p *FakeProvider) CreatePayment(_ context.Context, order Order) (Payment, error) {
+	reference := "fake_" + order.ID.String()
+
+	status := PaymentPending
+	if p.AutoCapture {
+		status = PaymentSucceeded
+	}
+
+	return Payment{
+		Provider:    FakeProviderName,
+		Reference:   reference,
+		CheckoutURL: fmt.Sprintf("https://pay.invalid/checkout/%s", reference),
+		Status:      status,
+	}, nil
+}
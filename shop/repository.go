@@ -0,0 +1,303 @@
+package shop
+
+// This file contains shop repository related code.
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/db"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+const (
+	productColumns     = "id, sku, name, description, kind, price, currency, vip_tier, vip_days, currency_amount, active, created, updated"
+	orderColumns       = "id, user_id, status, total, currency, discount, promo_code, payment_provider, payment_ref, created, updated"
+	orderItemColumns   = "id, order_id, product_id, quantity, unit_price"
+	entitlementColumns = "id, order_id, order_item_id, user_id, product_id, kind, sku, quantity, vip_tier, vip_days, currency_amount, status, created, delivered"
+)
+
+type (
+	// repository implements the Repository interface.
+	repository struct {
+		db *sql.DB
+		tx *db.TxManager
+	}
+
+	// scanner is implemented by both *sql.Row and *sql.Rows.
+	scanner interface {
+		Scan(dest ...any) error
+	}
+)
+
+// NewRepository creates a new shop repository.
+// It runs the queries in the transaction of the context, see db.TxManager.
+func NewRepository(sqlDB *sql.DB) Repository {
+	return &repository{sqlDB, db.NewTxManager(sqlDB)}
+}
+
+// querier returns the transaction of the context or the database.
+func (r *repository) querier(ctx context.Context) db.Querier {
+	return db.QuerierFrom(ctx, r.db)
+}
+
+// CreateProduct inserts a new product into the repository.
+func (r *repository) CreateProduct(ctx context.Context, p Product) error {
+	_, err := r.querier(ctx).ExecContext(ctx,
+		"INSERT INTO shop_product (id, sku, name, description, kind, price, currency, vip_tier, vip_days, currency_amount, active) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		p.ID, p.SKU, p.Name, p.Description, p.Kind, p.Price, p.Currency, p.VIPTier, p.VIPDays, p.CurrencyAmount, p.Active)
+	return err
+}
+
+// UpdateProduct updates a product in the repository.
+func (r *repository) UpdateProduct(ctx context.Context, p Product) error {
+	res, err := r.querier(ctx).ExecContext(ctx,
+		"UPDATE shop_product SET sku = $2, name = $3, description = $4, kind = $5, price = $6, currency = $7, vip_tier = $8, vip_days = $9, currency_amount = $10, active = $11, updated = NOW() WHERE id = $1",
+		p.ID, p.SKU, p.Name, p.Description, p.Kind, p.Price, p.Currency, p.VIPTier, p.VIPDays, p.CurrencyAmount, p.Active)
+	if err != nil {
+		return err
+	}
+	return expectRow(res, ErrProductNotFound)
+}
+
+// FindProductByID returns a product by id.
+func (r *repository) FindProductByID(ctx context.Context, id uuid.UUID) (Product, error) {
+	product, err := scanProduct(r.querier(ctx).QueryRowContext(ctx, "SELECT "+productColumns+" FROM shop_product WHERE id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Product{}, ErrProductNotFound
+	}
+	return product, err
+}
+
+// FindProducts returns catalog products, optionally only the active ones.
+func (r *repository) FindProducts(ctx context.Context, activeOnly bool) ([]Product, error) {
+	rows, err := r.querier(ctx).QueryContext(ctx, "SELECT "+productColumns+" FROM shop_product WHERE active OR NOT $1 ORDER BY kind, price", activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []Product
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	return products, rows.Err()
+}
+
+// CreateOrder inserts an order with its items.
+func (r *repository) CreateOrder(ctx context.Context, order Order) error {
+	return r.tx.InTx(ctx, func(ctx context.Context) error {
+		q := r.querier(ctx)
+
+		_, err := q.ExecContext(ctx,
+			"INSERT INTO shop_order (id, user_id, status, total, currency, discount, promo_code) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			order.ID, order.UserID, order.Status, order.Total, order.Currency, order.Discount, order.PromoCode)
+		if err != nil {
+			return fmt.Errorf("insert order: %w", err)
+		}
+
+		for _, item := range order.Items {
+			_, err := q.ExecContext(ctx,
+				"INSERT INTO shop_order_item (id, order_id, product_id, quantity, unit_price) VALUES ($1, $2, $3, $4, $5)",
+				item.ID, order.ID, item.ProductID, item.Quantity, item.UnitPrice)
+			if err != nil {
+				return fmt.Errorf("insert order item: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// FindOrderByID returns an order with its items by id.
+func (r *repository) FindOrderByID(ctx context.Context, id uuid.UUID) (Order, error) {
+	var order Order
+	err := r.querier(ctx).QueryRowContext(ctx, "SELECT "+orderColumns+" FROM shop_order WHERE id = $1", id).
+		Scan(&order.ID, &order.UserID, &order.Status, &order.Total, &order.Currency, &order.Discount, &order.PromoCode, &order.PaymentProvider, &order.PaymentRef, &order.Created, &order.Updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Order{}, ErrOrderNotFound
+	}
+	if err != nil {
+		return Order{}, err
+	}
+
+	rows, err := r.querier(ctx).QueryContext(ctx, "SELECT "+orderItemColumns+" FROM shop_order_item WHERE order_id = $1 ORDER BY id", id)
+	if err != nil {
+		return Order{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item OrderItem
+		if err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.Quantity, &item.UnitPrice); err != nil {
+			return Order{}, err
+		}
+		order.Items = append(order.Items, item)
+	}
+	return order, rows.Err()
+}
+
+// SetOrderPayment stores the payment reference of an order.
+func (r *repository) SetOrderPayment(ctx context.Context, id uuid.UUID, provider, reference string) error {
+	res, err := r.querier(ctx).ExecContext(ctx,
+		"UPDATE shop_order SET payment_provider = $2, payment_ref = $3, updated = NOW() WHERE id = $1",
+		id, provider, reference)
+	if err != nil {
+		return err
+	}
+	return expectRow(res, ErrOrderNotFound)
+}
+
+// UpdateOrderStatus moves the order from one status to another.
+func (r *repository) UpdateOrderStatus(ctx context.Context, id uuid.UUID, from, to OrderStatus) error {
+	res, err := r.querier(ctx).ExecContext(ctx,
+		"UPDATE shop_order SET status = $3, updated = NOW() WHERE id = $1 AND status = $2",
+		id, from, to)
+	if err != nil {
+		return err
+	}
+	return expectRow(res, ErrInvalidTransition)
+}
+
+// PayOrder moves the order to the paid status and inserts its entitlements atomically.
+func (r *repository) PayOrder(ctx context.Context, id uuid.UUID, from OrderStatus, entitlements []Entitlement) error {
+	to := OrderPaid
+	if len(entitlements) == 0 {
+		to = OrderDelivered
+	}
+
+	return r.tx.InTx(ctx, func(ctx context.Context) error {
+		q := r.querier(ctx)
+
+		res, err := q.ExecContext(ctx,
+			"UPDATE shop_order SET status = $3, updated = NOW() WHERE id = $1 AND status = $2",
+			id, from, to)
+		if err != nil {
+			return fmt.Errorf("update order: %w", err)
+		}
+		if err := expectRow(res, ErrInvalidTransition); err != nil {
+			return err
+		}
+
+		for _, e := range entitlements {
+			_, err := q.ExecContext(ctx,
+				"INSERT INTO shop_entitlement (id, order_id, order_item_id, user_id, product_id, kind, sku, quantity, vip_tier, vip_days, currency_amount, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)",
+				e.ID, e.OrderID, e.OrderItemID, e.UserID, e.ProductID, e.Kind, e.SKU, e.Quantity, e.VIPTier, e.VIPDays, e.CurrencyAmount, e.Status)
+			if err != nil {
+				return fmt.Errorf("insert entitlement: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// RefundOrder moves the order to the refunded status and revokes its undelivered entitlements.
+func (r *repository) RefundOrder(ctx context.Context, id uuid.UUID, from OrderStatus) error {
+	return r.tx.InTx(ctx, func(ctx context.Context) error {
+		q := r.querier(ctx)
+
+		res, err := q.ExecContext(ctx,
+			"UPDATE shop_order SET status = $3, updated = NOW() WHERE id = $1 AND status = $2",
+			id, from, OrderRefunded)
+		if err != nil {
+			return fmt.Errorf("update order: %w", err)
+		}
+		if err := expectRow(res, ErrInvalidTransition); err != nil {
+			return err
+		}
+
+		_, err = q.ExecContext(ctx,
+			"UPDATE shop_entitlement SET status = $2 WHERE order_id = $1 AND status = $3",
+			id, EntitlementRevoked, EntitlementPending)
+		if err != nil {
+			return fmt.Errorf("revoke entitlements: %w", err)
+		}
+		return nil
+	})
+}
+
+// FindPendingEntitlements returns the oldest undelivered entitlements.
+func (r *repository) FindPendingEntitlements(ctx context.Context, limit int) ([]Entitlement, error) {
+	rows, err := r.querier(ctx).QueryContext(ctx,
+		"SELECT "+entitlementColumns+" FROM shop_entitlement WHERE status = $1 ORDER BY created LIMIT $2",
+		EntitlementPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entitlements []Entitlement
+	for rows.Next() {
+		entitlement, err := scanEntitlement(rows)
+		if err != nil {
+			return nil, err
+		}
+		entitlements = append(entitlements, entitlement)
+	}
+	return entitlements, rows.Err()
+}
+
+// DeliverEntitlement marks an entitlement as delivered and completes
+// the order once all of its entitlements are delivered.
+func (r *repository) DeliverEntitlement(ctx context.Context, id uuid.UUID) (Entitlement, error) {
+	var entitlement Entitlement
+	err := r.tx.InTx(ctx, func(ctx context.Context) error {
+		q := r.querier(ctx)
+
+		_, err := q.ExecContext(ctx,
+			"UPDATE shop_entitlement SET status = $2, delivered = NOW() WHERE id = $1 AND status = $3",
+			id, EntitlementDelivered, EntitlementPending)
+		if err != nil {
+			return fmt.Errorf("update entitlement: %w", err)
+		}
+
+		entitlement, err = scanEntitlement(q.QueryRowContext(ctx, "SELECT "+entitlementColumns+" FROM shop_entitlement WHERE id = $1", id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEntitlementNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("find entitlement: %w", err)
+		}
+
+		_, err = q.ExecContext(ctx,
+			"UPDATE shop_order SET status = $2, updated = NOW() WHERE id = $1 AND status = $3 AND NOT EXISTS (SELECT 1 FROM shop_entitlement WHERE order_id = $1 AND status = $4)",
+			entitlement.OrderID, OrderDelivered, OrderPaid, EntitlementPending)
+		if err != nil {
+			return fmt.Errorf("complete order: %w", err)
+		}
+		return nil
+	})
+	return entitlement, err
+}
+
+// scanProduct scans a product row.
+func scanProduct(row scanner) (Product, error) {
+	var p Product
+	err := row.Scan(&p.ID, &p.SKU, &p.Name, &p.Description, &p.Kind, &p.Price, &p.Currency, &p.VIPTier, &p.VIPDays, &p.CurrencyAmount, &p.Active, &p.Created, &p.Updated)
+	return p, err
+}
+
+// scanEntitlement scans an entitlement row.
+func scanEntitlement(row scanner) (Entitlement, error) {
+	var e Entitlement
+	err := row.Scan(&e.ID, &e.OrderID, &e.OrderItemID, &e.UserID, &e.ProductID, &e.Kind, &e.SKU, &e.Quantity, &e.VIPTier, &e.VIPDays, &e.CurrencyAmount, &e.Status, &e.Created, &e.Delivered)
+	return e, err
+}
+
+// expectRow returns errNoRow if the statement didn't affect any row.
+func expectRow(res sql.Result, errNoRow error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errNoRow
+	}
+	return nil
+}
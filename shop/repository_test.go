@@ -0,0 +1,95 @@
+package shop
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/db"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingConnector opens the connections recording the statements and the transactions.
+type recordingConnector struct {
+	mu         sync.Mutex
+	statements []string
+}
+
+func (c *recordingConnector) record(statement string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statements = append(c.statements, statement)
+}
+
+func (c *recordingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &recordingConn{c}, nil
+}
+
+func (c *recordingConnector) Driver() driver.Driver { return nil }
+
+// recordingConn records the statements, which all affect a single row.
+type recordingConn struct {
+	connector *recordingConnector
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare isn't supported")
+}
+
+func (c *recordingConn) Close() error { return nil }
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *recordingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.connector.record("BEGIN")
+	return c, nil
+}
+
+func (c *recordingConn) Commit() error {
+	c.connector.record("COMMIT")
+	return nil
+}
+
+func (c *recordingConn) Rollback() error {
+	c.connector.record("ROLLBACK")
+	return nil
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.connector.record(strings.Fields(query)[0] + " " + strings.Fields(query)[1])
+	return driver.RowsAffected(1), nil
+}
+
+func TestRepository_CreateOrder_UnitOfWork(t *testing.T) {
+	connector := &recordingConnector{}
+	sqlDB := sql.OpenDB(connector)
+	t.Cleanup(func() { sqlDB.Close() })
+	repo := NewRepository(sqlDB)
+
+	order := Order{ID: uuid.New(), UserID: uuid.New(), Status: OrderPending, Items: []OrderItem{{ID: uuid.New()}}}
+	errLater := errors.New("later step failed")
+
+	// The order joins the transaction of the caller, so it is rolled back along with it.
+	err := db.NewTxManager(sqlDB).InTx(context.Background(), func(ctx context.Context) error {
+		require.NoError(t, repo.CreateOrder(ctx, order))
+		return errLater
+	})
+
+	assert.ErrorIs(t, err, errLater)
+	assert.Equal(t, []string{
+		"BEGIN",
+		"SAVEPOINT sp_1",
+		"INSERT INTO",
+		"INSERT INTO",
+		"RELEASE SAVEPOINT",
+		"ROLLBACK",
+	}, connector.statements)
+}
@@ -0,0 +1,432 @@
+package shop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// This file contains the shop service implementation.
+
+// tracer traces the service methods.
+var tracer = otel.Tracer("github.com/GTA5-RP-Aristocracy/site-back/shop")
+
+const (
+	// maxQuantity limits the quantity of a single order line.
+	maxQuantity = 99
+	// maxEntitlements limits the number of entitlements returned in a single poll.
+	maxEntitlements = 100
+)
+
+type (
+	// service implements the Service interface.
+	service struct {
+		repo       Repository
+		provider   PaymentProvider
+		fulfillers map[ProductKind]Fulfiller
+		discounter Discounter
+	}
+)
+
+// NewService creates a new shop service.
+// Products of the kinds with a fulfiller are delivered by it instead of the game server.
+// Without a discounter orders with a promo code are rejected.
+func NewService(repo Repository, provider PaymentProvider, fulfillers map[ProductKind]Fulfiller, discounter Discounter) Service {
+	return &service{repo, provider, fulfillers, discounter}
+}
+
+// ListProducts fetches all active catalog products.
+func (s *service) ListProducts(ctx context.Context) ([]Product, error) {
+	ctx, span := tracer.Start(ctx, "shop.ListProducts")
+	defer span.End()
+
+	return s.repo.FindProducts(ctx, true)
+}
+
+// GetProduct fetches a product by id.
+func (s *service) GetProduct(ctx context.Context, id uuid.UUID) (Product, error) {
+	ctx, span := tracer.Start(ctx, "shop.GetProduct")
+	defer span.End()
+
+	return s.repo.FindProductByID(ctx, id)
+}
+
+// CreateProduct adds a new product to the catalog.
+func (s *service) CreateProduct(ctx context.Context, product Product) (Product, error) {
+	ctx, span := tracer.Start(ctx, "shop.CreateProduct")
+	defer span.End()
+
+	product.ID = uuid.New()
+	if err := validateProduct(&product); err != nil {
+		return Product{}, err
+	}
+
+	if err := s.repo.CreateProduct(ctx, product); err != nil {
+		return Product{}, fmt.Errorf("create product: %w", err)
+	}
+	return s.repo.FindProductByID(ctx, product.ID)
+}
+
+// UpdateProduct changes an existing catalog product.
+func (s *service) UpdateProduct(ctx context.Context, product Product) (Product, error) {
+	ctx, span := tracer.Start(ctx, "shop.UpdateProduct")
+	defer span.End()
+
+	if err := validateProduct(&product); err != nil {
+		return Product{}, err
+	}
+
+	if err := s.repo.UpdateProduct(ctx, product); err != nil {
+		return Product{}, fmt.Errorf("update product: %w", err)
+	}
+	return s.repo.FindProductByID(ctx, product.ID)
+}
+
+// PlaceOrder creates an order for the user and starts its payment.
+// Orders fully covered by a promo code are paid right away without the payment provider.
+func (s *service) PlaceOrder(ctx context.Context, userID uuid.UUID, lines []OrderLine, promoCode string) (Order, Payment, error) {
+	ctx, span := tracer.Start(ctx, "shop.PlaceOrder")
+	defer span.End()
+
+	lines, err := mergeLines(lines)
+	if err != nil {
+		return Order{}, Payment{}, err
+	}
+
+	order := Order{
+		ID:     uuid.New(),
+		UserID: userID,
+		Status: OrderPending,
+	}
+
+	// Price the order using the current catalog.
+	for _, line := range lines {
+		product, err := s.repo.FindProductByID(ctx, line.ProductID)
+		if err != nil {
+			return Order{}, Payment{}, err
+		}
+		if !product.Active {
+			return Order{}, Payment{}, ErrProductInactive
+		}
+		if order.Currency == "" {
+			order.Currency = product.Currency
+		}
+		if order.Currency != product.Currency {
+			return Order{}, Payment{}, ErrCurrencyMismatch
+		}
+
+		order.Items = append(order.Items, OrderItem{
+			ID:        uuid.New(),
+			OrderID:   order.ID,
+			ProductID: product.ID,
+			Quantity:  line.Quantity,
+			UnitPrice: product.Price,
+		})
+		order.Total += product.Price * int64(line.Quantity)
+	}
+
+	if promoCode = strings.TrimSpace(promoCode); promoCode != "" {
+		if s.discounter == nil {
+			return Order{}, Payment{}, ErrPromoRejected
+		}
+		discount, err := s.discounter.Discount(ctx, promoCode, order)
+		if err != nil {
+			return Order{}, Payment{}, err
+		}
+		order.Discount = min(discount, order.Total)
+		order.Total -= order.Discount
+		order.PromoCode = promoCode
+	}
+
+	if err := s.repo.CreateOrder(ctx, order); err != nil {
+		return Order{}, Payment{}, fmt.Errorf("create order: %w", errors.Join(err, s.releasePromo(ctx, order)))
+	}
+
+	// Register the payment at the provider, unless there is nothing to pay.
+	payment := Payment{Status: PaymentSucceeded}
+	if order.Total > 0 {
+		// The order can't be paid without the payment, so its promo code is released.
+		payment, err = s.provider.CreatePayment(ctx, order)
+		if err != nil {
+			return Order{}, Payment{}, fmt.Errorf("create payment: %w", errors.Join(err, s.releasePromo(ctx, order)))
+		}
+		if err := s.repo.SetOrderPayment(ctx, order.ID, payment.Provider, payment.Reference); err != nil {
+			return Order{}, Payment{}, fmt.Errorf("set order payment: %w", errors.Join(err, s.releasePromo(ctx, order)))
+		}
+	}
+
+	// Some providers, e.g. the fake one, capture the payment right away.
+	if payment.Status == PaymentSucceeded {
+		if err := s.MarkPaid(ctx, order.ID); err != nil {
+			return Order{}, Payment{}, err
+		}
+	}
+
+	order, err = s.repo.FindOrderByID(ctx, order.ID)
+	if err != nil {
+		return Order{}, Payment{}, fmt.Errorf("find order: %w", err)
+	}
+	return order, payment, nil
+}
+
+// GetOrder fetches an order with its items by id.
+func (s *service) GetOrder(ctx context.Context, id uuid.UUID) (Order, error) {
+	ctx, span := tracer.Start(ctx, "shop.GetOrder")
+	defer span.End()
+
+	return s.repo.FindOrderByID(ctx, id)
+}
+
+// CancelOrder cancels an order that hasn't been paid yet.
+func (s *service) CancelOrder(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "shop.CancelOrder")
+	defer span.End()
+
+	order, err := s.repo.FindOrderByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !order.Status.CanTransitionTo(OrderCancelled) {
+		return ErrInvalidTransition
+	}
+
+	if err := s.repo.UpdateOrderStatus(ctx, id, order.Status, OrderCancelled); err != nil {
+		return err
+	}
+	return s.releasePromo(ctx, order)
+}
+
+// releasePromo releases the promo code redemption of the order which won't be paid,
+// so the code may be used again. A late payment of the order keeps its discount.
+func (s *service) releasePromo(ctx context.Context, order Order) error {
+	if order.PromoCode == "" || s.discounter == nil {
+		return nil
+	}
+	if err := s.discounter.Release(ctx, order); err != nil {
+		return fmt.Errorf("release promo code: %w", err)
+	}
+	return nil
+}
+
+// MarkPaid marks the order as paid and creates its entitlements.
+func (s *service) MarkPaid(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "shop.MarkPaid")
+	defer span.End()
+
+	order, err := s.repo.FindOrderByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !order.Status.CanTransitionTo(OrderPaid) {
+		return ErrInvalidTransition
+	}
+
+	// Fulfill before storing the payment, so a failed fulfillment is retried
+	// along with the payment event. Fulfillers are idempotent.
+	var entitlements []Entitlement
+	for _, item := range order.Items {
+		product, err := s.repo.FindProductByID(ctx, item.ProductID)
+		if err != nil {
+			return fmt.Errorf("find product: %w", err)
+		}
+
+		fulfiller, ok := s.fulfillers[product.Kind]
+		if !ok {
+			entitlements = append(entitlements, newEntitlement(order, item, product))
+			continue
+		}
+		if err := fulfiller.Fulfill(ctx, order, item, product); err != nil {
+			return fmt.Errorf("fulfill %s: %w", product.Kind, err)
+		}
+	}
+
+	return s.repo.PayOrder(ctx, id, order.Status, entitlements)
+}
+
+// refund takes back the goods fulfilled by the backend and marks the order as refunded.
+func (s *service) refund(ctx context.Context, order Order) error {
+	for _, item := range order.Items {
+		product, err := s.repo.FindProductByID(ctx, item.ProductID)
+		if err != nil {
+			return fmt.Errorf("find product: %w", err)
+		}
+
+		if fulfiller, ok := s.fulfillers[product.Kind]; ok {
+			if err := fulfiller.Revoke(ctx, order, item, product); err != nil {
+				return fmt.Errorf("revoke %s: %w", product.Kind, err)
+			}
+		}
+	}
+
+	return s.repo.RefundOrder(ctx, order.ID, order.Status)
+}
+
+// ApplyPayment applies a payment event reported by the payment provider.
+// Providers retry and reorder events, so the event is applied only if it moves
+// the order forward, e.g. a confirmation of an already refunded order is ignored.
+func (s *service) ApplyPayment(ctx context.Context, event PaymentEvent) (bool, error) {
+	ctx, span := tracer.Start(ctx, "shop.ApplyPayment")
+	defer span.End()
+
+	order, err := s.repo.FindOrderByID(ctx, event.OrderID)
+	if err != nil {
+		return false, err
+	}
+	if order.PaymentProvider != event.Provider || order.PaymentRef != event.Reference {
+		return false, fmt.Errorf("%w: unknown payment %s/%s", ErrPaymentMismatch, event.Provider, event.Reference)
+	}
+
+	var next OrderStatus
+	switch event.Status {
+	case PaymentSucceeded:
+		if event.Amount != order.Total || !strings.EqualFold(event.Currency, order.Currency) {
+			return false, fmt.Errorf("%w: paid %d %s, expected %d %s",
+				ErrPaymentMismatch, event.Amount, event.Currency, order.Total, order.Currency)
+		}
+		next = OrderPaid
+	case PaymentFailed:
+		next = OrderFailed
+	case PaymentRefunded:
+		next = OrderRefunded
+	default:
+		return false, nil
+	}
+
+	// Duplicates and stale events don't change the order.
+	if !order.Status.CanTransitionTo(next) {
+		return false, nil
+	}
+	// A failed attempt must not undo a later success or a cancellation.
+	if next == OrderFailed && order.Status != OrderPending {
+		return false, nil
+	}
+
+	switch next {
+	case OrderPaid:
+		err = s.MarkPaid(ctx, order.ID)
+	case OrderRefunded:
+		err = s.refund(ctx, order)
+	default:
+		// The failed order won't be paid, so its promo code is released.
+		err = s.repo.UpdateOrderStatus(ctx, order.ID, order.Status, next)
+		if err == nil {
+			err = s.releasePromo(ctx, order)
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PendingEntitlements fetches entitlements the game server hasn't delivered yet.
+func (s *service) PendingEntitlements(ctx context.Context, limit int) ([]Entitlement, error) {
+	ctx, span := tracer.Start(ctx, "shop.PendingEntitlements")
+	defer span.End()
+
+	if limit <= 0 || limit > maxEntitlements {
+		limit = maxEntitlements
+	}
+	return s.repo.FindPendingEntitlements(ctx, limit)
+}
+
+// AckEntitlement marks an entitlement as delivered by the game server.
+// Acknowledging an already delivered entitlement is not an error.
+func (s *service) AckEntitlement(ctx context.Context, id uuid.UUID) (Entitlement, error) {
+	ctx, span := tracer.Start(ctx, "shop.AckEntitlement")
+	defer span.End()
+
+	return s.repo.DeliverEntitlement(ctx, id)
+}
+
+// newEntitlement creates a pending entitlement for the order item.
+func newEntitlement(order Order, item OrderItem, product Product) Entitlement {
+	return Entitlement{
+		ID:             uuid.New(),
+		OrderID:        order.ID,
+		OrderItemID:    item.ID,
+		UserID:         order.UserID,
+		ProductID:      product.ID,
+		Kind:           product.Kind,
+		SKU:            product.SKU,
+		Quantity:       item.Quantity,
+		VIPTier:        product.VIPTier,
+		VIPDays:        product.VIPDays,
+		CurrencyAmount: product.CurrencyAmount,
+		Status:         EntitlementPending,
+	}
+}
+
+// mergeLines validates the order lines and merges the lines of the same product.
+func mergeLines(lines []OrderLine) ([]OrderLine, error) {
+	if len(lines) == 0 {
+		return nil, ErrEmptyOrder
+	}
+
+	var merged []OrderLine
+	index := make(map[uuid.UUID]int, len(lines))
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return nil, ErrInvalidQuantity
+		}
+
+		i, ok := index[line.ProductID]
+		if !ok {
+			index[line.ProductID] = len(merged)
+			merged = append(merged, line)
+			continue
+		}
+		merged[i].Quantity += line.Quantity
+	}
+
+	for _, line := range merged {
+		if line.Quantity > maxQuantity {
+			return nil, ErrInvalidQuantity
+		}
+	}
+	return merged, nil
+}
+
+// validateProduct normalizes the product and checks its kind specific fields.
+func validateProduct(product *Product) error {
+	product.SKU = strings.TrimSpace(product.SKU)
+	product.Name = strings.TrimSpace(product.Name)
+	product.Currency = strings.ToUpper(strings.TrimSpace(product.Currency))
+
+	var errs []error
+	if product.SKU == "" {
+		errs = append(errs, errors.New("sku is required"))
+	}
+	if product.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if len(product.Currency) != 3 {
+		errs = append(errs, errors.New("currency must be a 3 letter code"))
+	}
+	if product.Price < 0 {
+		errs = append(errs, errors.New("price must not be negative"))
+	}
+
+	switch product.Kind {
+	case ProductItem:
+	case ProductVIP:
+		if product.VIPTier == "" || product.VIPDays <= 0 {
+			errs = append(errs, errors.New("vip products require a tier and a positive number of days"))
+		}
+	case ProductCurrency:
+		if product.CurrencyAmount <= 0 {
+			errs = append(errs, errors.New("currency products require a positive amount"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown product kind %q", product.Kind))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidProduct, errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,661 @@
+package shop
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) CreateProduct(ctx context.Context, product Product) error {
+	args := m.Called(product)
+	return args.Error(0)
+}
+
+func (m *MockRepo) UpdateProduct(ctx context.Context, product Product) error {
+	args := m.Called(product)
+	return args.Error(0)
+}
+
+func (m *MockRepo) FindProductByID(ctx context.Context, id uuid.UUID) (Product, error) {
+	args := m.Called(id)
+	return args.Get(0).(Product), args.Error(1)
+}
+
+func (m *MockRepo) FindProducts(ctx context.Context, activeOnly bool) ([]Product, error) {
+	args := m.Called(activeOnly)
+	return args.Get(0).([]Product), args.Error(1)
+}
+
+func (m *MockRepo) CreateOrder(ctx context.Context, order Order) error {
+	args := m.Called(order)
+	return args.Error(0)
+}
+
+func (m *MockRepo) FindOrderByID(ctx context.Context, id uuid.UUID) (Order, error) {
+	args := m.Called(id)
+	return args.Get(0).(Order), args.Error(1)
+}
+
+func (m *MockRepo) SetOrderPayment(ctx context.Context, id uuid.UUID, provider, reference string) error {
+	args := m.Called(id, provider, reference)
+	return args.Error(0)
+}
+
+func (m *MockRepo) UpdateOrderStatus(ctx context.Context, id uuid.UUID, from, to OrderStatus) error {
+	args := m.Called(id, from, to)
+	return args.Error(0)
+}
+
+func (m *MockRepo) PayOrder(ctx context.Context, id uuid.UUID, from OrderStatus, entitlements []Entitlement) error {
+	args := m.Called(id, from, entitlements)
+	return args.Error(0)
+}
+
+func (m *MockRepo) RefundOrder(ctx context.Context, id uuid.UUID, from OrderStatus) error {
+	args := m.Called(id, from)
+	return args.Error(0)
+}
+
+func (m *MockRepo) FindPendingEntitlements(ctx context.Context, limit int) ([]Entitlement, error) {
+	args := m.Called(limit)
+	return args.Get(0).([]Entitlement), args.Error(1)
+}
+
+func (m *MockRepo) DeliverEntitlement(ctx context.Context, id uuid.UUID) (Entitlement, error) {
+	args := m.Called(id)
+	return args.Get(0).(Entitlement), args.Error(1)
+}
+
+func TestService_PlaceOrder(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	vip := Product{ID: uuid.New(), SKU: "vip-gold-30", Kind: ProductVIP, Price: 50000, Currency: "RUB", VIPTier: "gold", VIPDays: 30, Active: true}
+	coins := Product{ID: uuid.New(), SKU: "coins-1000", Kind: ProductCurrency, Price: 10000, Currency: "RUB", CurrencyAmount: 1000, Active: true}
+
+	mockRepo := new(MockRepo)
+	svc := NewService(mockRepo, NewFakeProvider(true), nil, nil)
+
+	var created Order
+	mockRepo.On("FindProductByID", vip.ID).Return(vip, nil)
+	mockRepo.On("FindProductByID", coins.ID).Return(coins, nil)
+	mockRepo.On("CreateOrder", mock.Anything).Run(func(args mock.Arguments) {
+		created = args.Get(0).(Order)
+	}).Return(nil)
+	mockRepo.On("SetOrderPayment", mock.Anything, FakeProviderName, mock.Anything).Return(nil)
+	findOrder := mockRepo.On("FindOrderByID", mock.Anything)
+	findOrder.Run(func(args mock.Arguments) {
+		findOrder.ReturnArguments = mock.Arguments{created, nil}
+	})
+	mockRepo.On("PayOrder", mock.Anything, OrderPending, mock.MatchedBy(func(e []Entitlement) bool {
+		return len(e) == 2 && e[0].Kind == ProductVIP && e[1].CurrencyAmount == 1000
+	})).Return(nil)
+
+	order, payment, err := svc.PlaceOrder(ctx, userID, []OrderLine{
+		{ProductID: vip.ID, Quantity: 1},
+		{ProductID: coins.ID, Quantity: 2},
+		{ProductID: coins.ID, Quantity: 1},
+	}, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, userID, order.UserID)
+	assert.Equal(t, "RUB", order.Currency)
+	assert.Equal(t, int64(50000+3*10000), order.Total)
+	assert.Len(t, order.Items, 2)
+	assert.Equal(t, PaymentSucceeded, payment.Status)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_PlaceOrder_Errors(t *testing.T) {
+	ctx := context.Background()
+	rub := Product{ID: uuid.New(), Kind: ProductItem, Price: 100, Currency: "RUB", Active: true}
+	usd := Product{ID: uuid.New(), Kind: ProductItem, Price: 100, Currency: "USD", Active: true}
+	inactive := Product{ID: uuid.New(), Kind: ProductItem, Price: 100, Currency: "RUB"}
+	missing := uuid.New()
+
+	cases := []struct {
+		testName      string
+		lines         []OrderLine
+		expectedError error
+	}{
+		{
+			testName:      "empty",
+			expectedError: ErrEmptyOrder,
+		},
+		{
+			testName:      "zero quantity",
+			lines:         []OrderLine{{ProductID: rub.ID, Quantity: 0}},
+			expectedError: ErrInvalidQuantity,
+		},
+		{
+			testName:      "merged quantity too large",
+			lines:         []OrderLine{{ProductID: rub.ID, Quantity: maxQuantity}, {ProductID: rub.ID, Quantity: 1}},
+			expectedError: ErrInvalidQuantity,
+		},
+		{
+			testName:      "inactive product",
+			lines:         []OrderLine{{ProductID: inactive.ID, Quantity: 1}},
+			expectedError: ErrProductInactive,
+		},
+		{
+			testName:      "currency mismatch",
+			lines:         []OrderLine{{ProductID: rub.ID, Quantity: 1}, {ProductID: usd.ID, Quantity: 1}},
+			expectedError: ErrCurrencyMismatch,
+		},
+		{
+			testName:      "unknown product",
+			lines:         []OrderLine{{ProductID: missing, Quantity: 1}},
+			expectedError: ErrProductNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			svc := NewService(mockRepo, NewFakeProvider(true), nil, nil)
+			mockRepo.On("FindProductByID", rub.ID).Return(rub, nil)
+			mockRepo.On("FindProductByID", usd.ID).Return(usd, nil)
+			mockRepo.On("FindProductByID", inactive.ID).Return(inactive, nil)
+			mockRepo.On("FindProductByID", missing).Return(Product{}, ErrProductNotFound)
+
+			_, _, err := svc.PlaceOrder(ctx, uuid.New(), tc.lines, "")
+
+			assert.ErrorIs(t, err, tc.expectedError)
+			mockRepo.AssertNotCalled(t, "CreateOrder", mock.Anything)
+		})
+	}
+}
+
+func TestService_PlaceOrder_PendingPayment(t *testing.T) {
+	ctx := context.Background()
+	product := Product{ID: uuid.New(), Kind: ProductItem, Price: 100, Currency: "RUB", Active: true}
+
+	mockRepo := new(MockRepo)
+	svc := NewService(mockRepo, NewFakeProvider(false), nil, nil)
+
+	mockRepo.On("FindProductByID", product.ID).Return(product, nil)
+	mockRepo.On("CreateOrder", mock.Anything).Return(nil)
+	mockRepo.On("SetOrderPayment", mock.Anything, FakeProviderName, mock.Anything).Return(nil)
+	mockRepo.On("FindOrderByID", mock.Anything).Return(Order{Status: OrderPending}, nil)
+
+	order, payment, err := svc.PlaceOrder(ctx, uuid.New(), []OrderLine{{ProductID: product.ID, Quantity: 1}}, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, OrderPending, order.Status)
+	assert.Equal(t, PaymentPending, payment.Status)
+	assert.NotEmpty(t, payment.CheckoutURL)
+	mockRepo.AssertNotCalled(t, "PayOrder", mock.Anything, mock.Anything, mock.Anything)
+}
+
+type MockDiscounter struct {
+	mock.Mock
+}
+
+func (m *MockDiscounter) Discount(ctx context.Context, code string, order Order) (int64, error) {
+	args := m.Called(code, order)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockDiscounter) Release(ctx context.Context, order Order) error {
+	args := m.Called(order)
+	return args.Error(0)
+}
+
+func TestService_PlaceOrder_PromoCode(t *testing.T) {
+	product := Product{ID: uuid.New(), Kind: ProductItem, Price: 1000, Currency: "RUB", Active: true}
+
+	cases := []struct {
+		testName         string
+		discount         int64
+		discountErr      error
+		expectedTotal    int64
+		expectedError    error
+		expectedCreating bool
+		expectedPayment  bool
+	}{
+		{
+			testName:         "partial discount",
+			discount:         300,
+			expectedTotal:    700,
+			expectedCreating: true,
+			expectedPayment:  true,
+		},
+		{
+			testName:         "free order",
+			discount:         5000,
+			expectedTotal:    0,
+			expectedCreating: true,
+		},
+		{
+			testName:      "rejected code",
+			discountErr:   ErrPromoRejected,
+			expectedError: ErrPromoRejected,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctx := context.Background()
+			mockRepo := new(MockRepo)
+			mockDiscounter := new(MockDiscounter)
+			svc := NewService(mockRepo, NewFakeProvider(false), nil, mockDiscounter)
+
+			var created Order
+			mockRepo.On("FindProductByID", product.ID).Return(product, nil)
+			mockDiscounter.On("Discount", "SUMMER", mock.Anything).Return(tc.discount, tc.discountErr)
+			mockRepo.On("CreateOrder", mock.Anything).Run(func(args mock.Arguments) {
+				created = args.Get(0).(Order)
+			}).Return(nil)
+			mockRepo.On("SetOrderPayment", mock.Anything, FakeProviderName, mock.Anything).Return(nil)
+			mockRepo.On("PayOrder", mock.Anything, OrderPending, mock.Anything).Return(nil)
+			findOrder := mockRepo.On("FindOrderByID", mock.Anything)
+			findOrder.Run(func(args mock.Arguments) {
+				findOrder.ReturnArguments = mock.Arguments{created, nil}
+			})
+
+			order, _, err := svc.PlaceOrder(ctx, uuid.New(), []OrderLine{{ProductID: product.ID, Quantity: 1}}, " SUMMER ")
+
+			assert.ErrorIs(t, err, tc.expectedError)
+			if !tc.expectedCreating {
+				mockRepo.AssertNotCalled(t, "CreateOrder", mock.Anything)
+				return
+			}
+			assert.Equal(t, tc.expectedTotal, order.Total)
+			assert.Equal(t, product.Price-tc.expectedTotal, order.Discount)
+			assert.Equal(t, "SUMMER", order.PromoCode)
+			if tc.expectedPayment {
+				mockRepo.AssertNumberOfCalls(t, "SetOrderPayment", 1)
+				mockRepo.AssertNotCalled(t, "PayOrder", mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				mockRepo.AssertNotCalled(t, "SetOrderPayment", mock.Anything, mock.Anything, mock.Anything)
+				mockRepo.AssertNumberOfCalls(t, "PayOrder", 1)
+			}
+		})
+	}
+}
+
+// failingProvider fails to create the payments.
+type failingProvider struct {
+	FakeProvider
+	err error
+}
+
+func (p *failingProvider) CreatePayment(context.Context, Order) (Payment, error) {
+	return Payment{}, p.err
+}
+
+func TestService_PlaceOrder_ReleasePromo(t *testing.T) {
+	product := Product{ID: uuid.New(), Kind: ProductItem, Price: 1000, Currency: "RUB", Active: true}
+	errRepo := errors.New("repo error")
+	errProvider := errors.New("provider error")
+
+	cases := []struct {
+		testName         string
+		createOrderErr   error
+		providerErr      error
+		setPaymentErr    error
+		expectedError    error
+		expectedReleased bool
+	}{
+		{
+			testName:         "create order fails",
+			createOrderErr:   errRepo,
+			expectedError:    errRepo,
+			expectedReleased: true,
+		},
+		{
+			testName:         "create payment fails",
+			providerErr:      errProvider,
+			expectedError:    errProvider,
+			expectedReleased: true,
+		},
+		{
+			testName:         "set order payment fails",
+			setPaymentErr:    errRepo,
+			expectedError:    errRepo,
+			expectedReleased: true,
+		},
+		{
+			testName: "pending payment",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			mockDiscounter := new(MockDiscounter)
+			var provider PaymentProvider = NewFakeProvider(false)
+			if tc.providerErr != nil {
+				provider = &failingProvider{err: tc.providerErr}
+			}
+			svc := NewService(mockRepo, provider, nil, mockDiscounter)
+
+			mockRepo.On("FindProductByID", product.ID).Return(product, nil)
+			mockDiscounter.On("Discount", "SUMMER", mock.Anything).Return(int64(300), nil)
+			mockDiscounter.On("Release", mock.Anything).Return(nil)
+			mockRepo.On("CreateOrder", mock.Anything).Return(tc.createOrderErr)
+			mockRepo.On("SetOrderPayment", mock.Anything, FakeProviderName, mock.Anything).Return(tc.setPaymentErr)
+			mockRepo.On("FindOrderByID", mock.Anything).Return(Order{Status: OrderPending}, nil)
+
+			_, _, err := svc.PlaceOrder(context.Background(), uuid.New(), []OrderLine{{ProductID: product.ID, Quantity: 1}}, "SUMMER")
+
+			assert.ErrorIs(t, err, tc.expectedError)
+			if tc.expectedReleased {
+				mockDiscounter.AssertNumberOfCalls(t, "Release", 1)
+				released := mockDiscounter.Calls[len(mockDiscounter.Calls)-1].Arguments.Get(0).(Order)
+				assert.Equal(t, "SUMMER", released.PromoCode)
+			} else {
+				mockDiscounter.AssertNotCalled(t, "Release", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestService_PlaceOrder_PromoWithoutDiscounter(t *testing.T) {
+	product := Product{ID: uuid.New(), Kind: ProductItem, Price: 1000, Currency: "RUB", Active: true}
+
+	mockRepo := new(MockRepo)
+	svc := NewService(mockRepo, NewFakeProvider(false), nil, nil)
+
+	mockRepo.On("FindProductByID", product.ID).Return(product, nil)
+
+	_, _, err := svc.PlaceOrder(context.Background(), uuid.New(), []OrderLine{{ProductID: product.ID, Quantity: 1}}, "SUMMER")
+
+	assert.ErrorIs(t, err, ErrPromoRejected)
+	mockRepo.AssertNotCalled(t, "CreateOrder", mock.Anything)
+}
+
+func TestService_MarkPaid_InvalidTransition(t *testing.T) {
+	orderID := uuid.New()
+	mockRepo := new(MockRepo)
+	svc := NewService(mockRepo, NewFakeProvider(true), nil, nil)
+
+	mockRepo.On("FindOrderByID", orderID).Return(Order{ID: orderID, Status: OrderRefunded}, nil)
+
+	err := svc.MarkPaid(context.Background(), orderID)
+
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	mockRepo.AssertNotCalled(t, "PayOrder", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_CancelOrder(t *testing.T) {
+	cases := []struct {
+		testName      string
+		status        OrderStatus
+		expectedError error
+	}{
+		{
+			testName: "pending",
+			status:   OrderPending,
+		},
+		{
+			testName: "failed",
+			status:   OrderFailed,
+		},
+		{
+			testName:      "paid",
+			status:        OrderPaid,
+			expectedError: ErrInvalidTransition,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			orderID := uuid.New()
+			mockRepo := new(MockRepo)
+			mockDiscounter := new(MockDiscounter)
+			svc := NewService(mockRepo, NewFakeProvider(true), nil, mockDiscounter)
+
+			order := Order{ID: orderID, Status: tc.status, PromoCode: "SUMMER"}
+			mockRepo.On("FindOrderByID", orderID).Return(order, nil)
+			mockRepo.On("UpdateOrderStatus", orderID, tc.status, OrderCancelled).Return(nil)
+			mockDiscounter.On("Release", order).Return(nil)
+
+			err := svc.CancelOrder(context.Background(), orderID)
+
+			assert.ErrorIs(t, err, tc.expectedError)
+			// The cancelled order releases its promo code.
+			if tc.expectedError == nil {
+				mockDiscounter.AssertNumberOfCalls(t, "Release", 1)
+			} else {
+				mockDiscounter.AssertNotCalled(t, "Release", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestService_CreateProduct_Invalid(t *testing.T) {
+	cases := []struct {
+		testName string
+		product  Product
+	}{
+		{
+			testName: "missing sku",
+			product:  Product{Name: "Car", Kind: ProductItem, Currency: "RUB"},
+		},
+		{
+			testName: "unknown kind",
+			product:  Product{SKU: "car", Name: "Car", Kind: "car", Currency: "RUB"},
+		},
+		{
+			testName: "vip without days",
+			product:  Product{SKU: "vip", Name: "VIP", Kind: ProductVIP, Currency: "RUB", VIPTier: "gold"},
+		},
+		{
+			testName: "currency without amount",
+			product:  Product{SKU: "coins", Name: "Coins", Kind: ProductCurrency, Currency: "RUB"},
+		},
+		{
+			testName: "negative price",
+			product:  Product{SKU: "car", Name: "Car", Kind: ProductItem, Currency: "RUB", Price: -1},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			svc := NewService(mockRepo, NewFakeProvider(true), nil, nil)
+
+			_, err := svc.CreateProduct(context.Background(), tc.product)
+
+			assert.ErrorIs(t, err, ErrInvalidProduct)
+			mockRepo.AssertNotCalled(t, "CreateProduct", mock.Anything)
+		})
+	}
+}
+
+func TestOrderStatus_CanTransitionTo(t *testing.T) {
+	assert.True(t, OrderPending.CanTransitionTo(OrderPaid))
+	assert.True(t, OrderFailed.CanTransitionTo(OrderPaid))
+	assert.True(t, OrderPaid.CanTransitionTo(OrderDelivered))
+	assert.False(t, OrderPaid.CanTransitionTo(OrderPending))
+	assert.True(t, OrderCancelled.CanTransitionTo(OrderPaid))
+	assert.False(t, OrderCancelled.CanTransitionTo(OrderDelivered))
+	assert.False(t, OrderRefunded.CanTransitionTo(OrderDelivered))
+}
+
+func TestService_ApplyPayment(t *testing.T) {
+	cases := []struct {
+		testName        string
+		status          OrderStatus
+		event           PaymentStatus
+		amount          int64
+		expectedApplied bool
+		expectedError   error
+		expectedCall    string
+	}{
+		{
+			testName:        "succeeded",
+			status:          OrderPending,
+			event:           PaymentSucceeded,
+			amount:          100,
+			expectedApplied: true,
+			expectedCall:    "PayOrder",
+		},
+		{
+			testName: "duplicate success",
+			status:   OrderPaid,
+			event:    PaymentSucceeded,
+			amount:   100,
+		},
+		{
+			testName:        "success after cancellation",
+			status:          OrderCancelled,
+			event:           PaymentSucceeded,
+			amount:          100,
+			expectedApplied: true,
+			expectedCall:    "PayOrder",
+		},
+		{
+			testName:      "wrong amount",
+			status:        OrderPending,
+			event:         PaymentSucceeded,
+			amount:        99,
+			expectedError: ErrPaymentMismatch,
+		},
+		{
+			testName:        "failed",
+			status:          OrderPending,
+			event:           PaymentFailed,
+			expectedApplied: true,
+			expectedCall:    "UpdateOrderStatus",
+		},
+		{
+			testName: "failure after success",
+			status:   OrderDelivered,
+			event:    PaymentFailed,
+		},
+		{
+			testName: "failure after cancellation",
+			status:   OrderCancelled,
+			event:    PaymentFailed,
+		},
+		{
+			testName:        "refunded",
+			status:          OrderDelivered,
+			event:           PaymentRefunded,
+			expectedApplied: true,
+			expectedCall:    "RefundOrder",
+		},
+		{
+			testName:        "refund before success",
+			status:          OrderPending,
+			event:           PaymentRefunded,
+			expectedApplied: true,
+			expectedCall:    "RefundOrder",
+		},
+		{
+			testName: "success after refund",
+			status:   OrderRefunded,
+			event:    PaymentSucceeded,
+			amount:   100,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			order := Order{ID: uuid.New(), Status: tc.status, Total: 100, Currency: "RUB", PaymentProvider: "fake", PaymentRef: "ref"}
+			mockRepo := new(MockRepo)
+			svc := NewService(mockRepo, NewFakeProvider(false), nil, nil)
+
+			mockRepo.On("FindOrderByID", order.ID).Return(order, nil)
+			mockRepo.On("PayOrder", order.ID, tc.status, mock.Anything).Return(nil)
+			mockRepo.On("UpdateOrderStatus", order.ID, tc.status, OrderFailed).Return(nil)
+			mockRepo.On("RefundOrder", order.ID, tc.status).Return(nil)
+
+			applied, err := svc.ApplyPayment(context.Background(), PaymentEvent{
+				OrderID:   order.ID,
+				Provider:  "fake",
+				Reference: "ref",
+				Status:    tc.event,
+				Amount:    tc.amount,
+				Currency:  "rub",
+			})
+
+			assert.ErrorIs(t, err, tc.expectedError)
+			assert.Equal(t, tc.expectedApplied, applied)
+			for _, method := range []string{"PayOrder", "UpdateOrderStatus", "RefundOrder"} {
+				if method == tc.expectedCall {
+					continue
+				}
+				for _, call := range mockRepo.Calls {
+					assert.NotEqual(t, method, call.Method)
+				}
+			}
+		})
+	}
+}
+
+func TestService_ApplyPayment_ReleasePromo(t *testing.T) {
+	cases := []struct {
+		testName         string
+		event            PaymentStatus
+		expectedReleased bool
+	}{
+		{
+			testName:         "failed",
+			event:            PaymentFailed,
+			expectedReleased: true,
+		},
+		{
+			testName: "succeeded",
+			event:    PaymentSucceeded,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			order := Order{ID: uuid.New(), Status: OrderPending, Total: 100, Currency: "RUB", PaymentProvider: "fake", PaymentRef: "ref", PromoCode: "SUMMER"}
+			mockRepo := new(MockRepo)
+			mockDiscounter := new(MockDiscounter)
+			svc := NewService(mockRepo, NewFakeProvider(false), nil, mockDiscounter)
+
+			mockRepo.On("FindOrderByID", order.ID).Return(order, nil)
+			mockRepo.On("PayOrder", order.ID, OrderPending, mock.Anything).Return(nil)
+			mockRepo.On("UpdateOrderStatus", order.ID, OrderPending, OrderFailed).Return(nil)
+			mockDiscounter.On("Release", order).Return(nil)
+
+			applied, err := svc.ApplyPayment(context.Background(), PaymentEvent{
+				OrderID:   order.ID,
+				Provider:  "fake",
+				Reference: "ref",
+				Status:    tc.event,
+				Amount:    100,
+				Currency:  "RUB",
+			})
+
+			require.NoError(t, err)
+			assert.True(t, applied)
+			if tc.expectedReleased {
+				mockDiscounter.AssertNumberOfCalls(t, "Release", 1)
+			} else {
+				mockDiscounter.AssertNotCalled(t, "Release", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestService_ApplyPayment_UnknownPayment(t *testing.T) {
+	order := Order{ID: uuid.New(), Status: OrderPending, PaymentProvider: "fake", PaymentRef: "ref"}
+	mockRepo := new(MockRepo)
+	svc := NewService(mockRepo, NewFakeProvider(false), nil, nil)
+
+	mockRepo.On("FindOrderByID", order.ID).Return(order, nil)
+
+	_, err := svc.ApplyPayment(context.Background(), PaymentEvent{
+		OrderID:   order.ID,
+		Provider:  "fake",
+		Reference: "other",
+		Status:    PaymentSucceeded,
+	})
+
+	assert.ErrorIs(t, err, ErrPaymentMismatch)
+}
@@ -0,0 +1,20 @@
+package tracing
+
+type (
+	// Config represents the configuration options for the tracing.
+	Config struct {
+		// Exporter is one of none, stdout and otlp. The trace context of the callers
+		// is propagated even when the spans aren't exported.
+		Exporter string `env:"TRACING_EXPORTER" envDefault:"none" yaml:"exporter"`
+		// Endpoint is the host:port of the OTLP HTTP collector,
+		// OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4318 by default.
+		Endpoint string `env:"TRACING_OTLP_ENDPOINT" yaml:"otlp_endpoint"`
+		// Insecure sends the spans to the collector over plain HTTP.
+		Insecure bool `env:"TRACING_OTLP_INSECURE" yaml:"otlp_insecure"`
+		// ServiceName names the service the spans come from.
+		ServiceName string `env:"TRACING_SERVICE_NAME" envDefault:"site-back" yaml:"service_name"`
+		// SampleRatio is the share of the traces started here which are recorded,
+		// the traces of the callers follow their sampling decision.
+		SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1" yaml:"sample_ratio"`
+	}
+)
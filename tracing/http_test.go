@@ -0,0 +1,122 @@
+package tracing
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// traceparent is the trace context of a caller.
+	traceparent   = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	callerTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	callerSpanID  = "00f067aa0ba902b7"
+)
+
+// newRecorder installs a tracer provider recording the spans, until the test ends.
+func newRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+	return recorder
+}
+
+// newRouter creates a router serving a user and a failing route.
+func newRouter(middlewares ...func(http.Handler) http.Handler) http.Handler {
+	r := chi.NewRouter()
+	r.Use(middlewares...)
+	r.Get("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	r.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	return r
+}
+
+func TestMiddleware(t *testing.T) {
+	cases := []struct {
+		testName       string
+		path           string
+		expectedName   string
+		expectedStatus int
+		expectedCode   codes.Code
+	}{
+		{
+			testName:       "Route",
+			path:           "/user/42",
+			expectedName:   "GET /user/{id}",
+			expectedStatus: http.StatusOK,
+			expectedCode:   codes.Unset,
+		},
+		{
+			testName:       "ServerError",
+			path:           "/fail",
+			expectedName:   "GET /fail",
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   codes.Error,
+		},
+		{
+			testName:       "Unmatched",
+			path:           "/missing",
+			expectedName:   "GET",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   codes.Unset,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			recorder := newRecorder(t)
+
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			req.Header.Set("traceparent", traceparent)
+			rr := httptest.NewRecorder()
+			newRouter(Middleware).ServeHTTP(rr, req)
+			require.Equal(t, c.expectedStatus, rr.Code)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			span := spans[0]
+			assert.Equal(t, c.expectedName, span.Name())
+			assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+			assert.Equal(t, c.expectedCode, span.Status().Code)
+			assert.Contains(t, span.Attributes(), semconv.HTTPResponseStatusCode(c.expectedStatus))
+
+			// The span continues the trace of the caller.
+			assert.Equal(t, callerTraceID, span.SpanContext().TraceID().String())
+			assert.Equal(t, callerSpanID, span.Parent().SpanID().String())
+		})
+	}
+}
+
+func TestMiddleware_LogTraceID(t *testing.T) {
+	newRecorder(t)
+
+	var out bytes.Buffer
+	logger := httplog.NewLogger("test", httplog.Options{JSON: true, Concise: true, Writer: &out})
+
+	req := httptest.NewRequest(http.MethodGet, "/user/42", nil)
+	req.Header.Set("traceparent", traceparent)
+	newRouter(httplog.RequestLogger(logger), Middleware).ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Contains(t, out.String(), `"trace_id":"`+callerTraceID+`"`)
+}
@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup(t *testing.T) {
+	cases := []struct {
+		testName      string
+		exporter      string
+		expectedError bool
+	}{
+		{testName: "None", exporter: ExporterNone},
+		{testName: "Stdout", exporter: ExporterStdout},
+		{testName: "Unknown", exporter: "jaeger", expectedError: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+			t.Cleanup(func() {
+				otel.SetTracerProvider(provider)
+				otel.SetTextMapPropagator(propagator)
+			})
+
+			shutdown, err := Setup(context.Background(), Config{Exporter: c.exporter, ServiceName: "test", SampleRatio: 1}, "v0.0.1", "test")
+			if c.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.NoError(t, shutdown(context.Background()))
+
+			// The W3C trace context is propagated with any exporter.
+			assert.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent")
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package user
+
+type (
+	// Config represents the configuration options for the user accounts.
+	Config struct {
+		// NameMinLength and NameMaxLength bound the length of the names, in characters.
+		NameMinLength int `env:"USER_NAME_MIN_LENGTH" envDefault:"3" yaml:"name_min_length"`
+		NameMaxLength int `env:"USER_NAME_MAX_LENGTH" envDefault:"32" yaml:"name_max_length"`
+		// ReservedNames can't be taken by the players, whatever the case.
+		ReservedNames []string `env:"USER_RESERVED_NAMES" envDefault:"admin,administrator,moderator,support,staff,system,root" yaml:"reserved_names"`
+		// PasswordMinLength and PasswordMaxLength bound the length of the passwords, in characters.
+		PasswordMinLength int `env:"USER_PASSWORD_MIN_LENGTH" envDefault:"10" yaml:"password_min_length"`
+		PasswordMaxLength int `env:"USER_PASSWORD_MAX_LENGTH" envDefault:"128" yaml:"password_max_length"`
+		// BreachedPasswordsFile lists the breached passwords one per line,
+		// they are rejected along with the built in list.
+		BreachedPasswordsFile string `env:"USER_BREACHED_PASSWORDS_FILE" yaml:"breached_passwords_file"`
+	}
+)
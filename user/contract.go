@@ -1,6 +1,10 @@
 package user
 
-import "github.com/google/uuid"
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
 
 // This file defines the user related interfaces.
 
@@ -8,13 +12,13 @@ type (
 	// Service represents the user service interface.
 	Service interface {
 		// Signup creates a new user account.
-		Signup(email, name, password string) error
+		Signup(ctx context.Context, email, name, password string) error
 		// Signin checks the email and password and returns a user.
-		Signin(email, password string) (User, error)
+		Signin(ctx context.Context, email, password string) (User, error)
 		// Get fetches a user by id.
-		Get(id uuid.UUID) (User, error)
+		Get(ctx context.Context, id uuid.UUID) (User, error)
 		// List fetches all users.
-		List() ([]User, error)
+		List(ctx context.Context) ([]User, error)
 	
 		
 	}
@@ -22,13 +26,13 @@ type (
 	// Repository represents the user repository interface.
 	Repository interface {
 		// Create inserts a new user into the repository.
-		Create(user User) error
+		Create(ctx context.Context, user User) error
 		// FindByEmail returns a user by email.
-		FindByEmail(email string) (User, error)
+		FindByEmail(ctx context.Context, email string) (User, error)
 		// FindByID returns a user by id.
-		FindByID(id uuid.UUID) (User, error)
+		FindByID(ctx context.Context, id uuid.UUID) (User, error)
 		// FindAll returns all users.
-		FindAll() ([]User, error)
+		FindAll(ctx context.Context) ([]User, error)
 		
 	}
 )
@@ -6,6 +6,10 @@ import "errors"
 
 // Define custom errors.
 var (
-	ErrEmailExists = errors.New("user: email already exists")
-	ErrNotFound    = errors.New("user: not found")
+	ErrEmailExists        = errors.New("user: email already exists")
+	ErrNotFound           = errors.New("user: not found")
+	ErrInvalidCredentials = errors.New("user: invalid credentials")
+
+	// Deprecated: the service reports the missing users with ErrNotFound.
+	ErrUserNotFound = ErrNotFound
 )
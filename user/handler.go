@@ -4,6 +4,8 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/GTA5-RP-Aristocracy/site-back/api"
+	"github.com/GTA5-RP-Aristocracy/site-back/auth"
 	"github.com/go-chi/chi/v5"
 	"github.com/goccy/go-json"
 	"github.com/google/uuid"
@@ -18,16 +20,39 @@ const (
 	pathSignin = "/signin"
 )
 
+// errorMappings map the user errors to the problem responses.
+var errorMappings = []api.Mapping{
+	{Err: ErrEmailExists, Status: http.StatusConflict, Code: "email_exists"},
+	{Err: ErrNotFound, Status: http.StatusNotFound, Code: "user_not_found"},
+	{Err: ErrInvalidCredentials, Status: http.StatusUnauthorized, Code: "invalid_credentials"},
+}
+
 type (
 	// Handler represents a set of http handlers for managing users.
 	Handler struct {
 		service Service
+		policy  *Policy
+		auth    auth.Config
+	}
+
+	// signupRequest represents the signup JSON or form.
+	signupRequest struct {
+		Email    string `json:"email"`
+		Name     string `json:"name"`
+		Password string `json:"password"`
+	}
+
+	// signinRequest represents the signin JSON or form.
+	signinRequest struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
 	}
 )
 
-// NewHandler creates a new user http handler.
-func NewHandler(service Service) *Handler {
-	return &Handler{service}
+// NewHandler creates a new user http handler validating the requests with the policy
+// and signing the sessions with the auth config.
+func NewHandler(service Service, policy *Policy, authConfig auth.Config) *Handler {
+	return &Handler{service, policy, authConfig}
 }
 
 // RegisterUserRouter registers user routes.
@@ -35,20 +60,26 @@ func (h *Handler) RegisterUserRouter(externalRouter chi.Router) {
 	r := chi.NewRouter()
 	r.Post(pathSignup, h.Signup)
 	r.Post(pathSignin, h.Signin)
-	r.Get(pathList, h.List)
+	r.With(auth.RequireToken(h.auth.AdminToken)).Get(pathList, h.List)
 	externalRouter.Mount(pathRoot, r)
 }
 
 // Signup handles user signup request.
 func (h *Handler) Signup(w http.ResponseWriter, r *http.Request) {
 	// Parse the request.
-	email := r.FormValue("email")
-	name := r.FormValue("name")
-	password := r.FormValue("password")
+	var req signupRequest
+	if err := api.Decode(w, r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.policy.validateSignup(req); err != nil {
+		writeError(w, r, err)
+		return
+	}
 
 	// Create a new user.
-	if err := h.service.Signup(email, name, password); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.Signup(r.Context(), req.Email, req.Name, req.Password); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -58,9 +89,9 @@ func (h *Handler) Signup(w http.ResponseWriter, r *http.Request) {
 // List handles user list request.
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	// Fetch all users.
-	users, err := h.service.List()
+	users, err := h.service.List(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -68,6 +99,11 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, users)
 }
 
+// writeError writes the problem matching the error.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	api.WriteError(w, r, err, errorMappings...)
+}
+
 // writeJSON writes the response in JSON format.
 func writeJSON(w http.ResponseWriter, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -76,31 +112,29 @@ func writeJSON(w http.ResponseWriter, v interface{}) {
 	}
 }
 
-// TODO move to separate file
-var ErrInvalidCredentials = errors.New("invalid credentials")
-
 type UserResponse struct {
 	Email string
 	Name  string
 }
 
 func (h *Handler) Signin(w http.ResponseWriter, r *http.Request) {
-	email := r.FormValue("email")
-	password := r.FormValue("password")
-
-	if email == "" || password == "" {
-		http.Error(w, "Email and password are required", http.StatusBadRequest)
+	var req signinRequest
+	if err := api.Decode(w, r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := validateSignin(req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	user, err := h.service.Signin(email, password)
+	user, err := h.service.Signin(r.Context(), req.Email, req.Password)
 	if err != nil {
-		if errors.Is(err, ErrInvalidCredentials) {
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
-			return
+		// The unknown emails aren't told apart from the wrong passwords.
+		if errors.Is(err, ErrNotFound) {
+			err = ErrInvalidCredentials
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-
+		writeError(w, r, err)
 		return
 	}
 
@@ -110,10 +144,10 @@ func (h *Handler) Signin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// set cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:  "session",
-		Value: user.ID.String(),
-	})
+	if err := auth.SetSession(w, h.auth, user.ID); err != nil {
+		writeError(w, r, err)
+		return
+	}
 
 	w.Header().Set("content-type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -122,32 +156,18 @@ func (h *Handler) Signin(w http.ResponseWriter, r *http.Request) {
 	w.Write(jsonResponse)
 }
 
-// TODO move to separate file
-var ErrUserNotFound = errors.New("user not found")
-
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	uuidStr := r.URL.Query().Get("uuid")
 
-	if uuidStr == "" {
-		http.Error(w, "User UUID is required", http.StatusBadRequest)
-		return
-	}
-
 	parsUUID, err := uuid.Parse(uuidStr)
 	if err != nil {
-		http.Error(w, "Invalid UUID format", http.StatusBadRequest)
+		writeError(w, r, api.ErrInvalidID)
 		return
 	}
 
-	user, err := h.service.Get(parsUUID)
-
+	user, err := h.service.Get(r.Context(), parsUUID)
 	if err != nil {
-		if err == ErrUserNotFound {
-			http.Error(w, "User not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-
+		writeError(w, r, err)
 		return
 	}
 	w.Header().Set("content-type", "application/json")
@@ -155,7 +175,7 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 
 	jsonResponse, err := json.Marshal(user)
 	if err != nil {
-		http.Error(w, "Failed to serialize user data", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -1,13 +1,21 @@
 package user
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+	"github.com/GTA5-RP-Aristocracy/site-back/api"
+	"github.com/GTA5-RP-Aristocracy/site-back/auth"
 	"github.com/go-chi/chi/v5"
+	"github.com/goccy/go-json"
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 
@@ -21,8 +29,11 @@ type MockService struct{
 
 
 
+// testAuth signs the sessions of the tests.
+var testAuth = auth.Config{SessionSecret: "0123456789abcdef0123456789abcdef", SessionTTL: time.Hour}
+
 // List
-func (m *MockService) List()([]User,error){
+func (m *MockService) List(ctx context.Context)([]User,error){
 	if m.funcList  !=nil{
 		return m.funcList()	
 	}
@@ -30,17 +41,17 @@ func (m *MockService) List()([]User,error){
 }
 
 // Get
-func(m *MockService) Get(id uuid.UUID)(User,error){
+func(m *MockService) Get(ctx context.Context, id uuid.UUID)(User,error){
 	return m.funcGet(id)
 }
 
 // Signin
-func(m *MockService) Signin(email,password string)(User,error){
+func(m *MockService) Signin(ctx context.Context, email,password string)(User,error){
 	return m.funcSignin(email,password)
 }
 
 // Signup
-func(m *MockService) Signup(email,name,password string)(error){
+func(m *MockService) Signup(ctx context.Context, email,name,password string)(error){
 	if m.funcSignup != nil{
 		return m.funcSignup(email,name,password)
 	}
@@ -60,7 +71,7 @@ func TestHandlerSignup(t *testing.T){
 	}{
 		{
 			testName: "Successful signup",
-			requestBody: "email=test@test.com&name=testName&password=test123",
+			requestBody: "email=test@test.com&name=testName&password=correct-horse-42",
 			funcSignup: func(email,name,password string) error{
 				return nil
 			},
@@ -68,12 +79,25 @@ func TestHandlerSignup(t *testing.T){
 		},
 		{
 			testName: "Signup error",
-			requestBody: "email=test1@test.com&name=TestName1&password=password1234",
+			requestBody: "email=test1@test.com&name=TestName1&password=correct-horse-42",
 			funcSignup: func(email,name,password string)error{
 				return errors.New("internal error")
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
+		{
+			testName: "Email exists",
+			requestBody: "email=test1@test.com&name=TestName1&password=correct-horse-42",
+			funcSignup: func(email,name,password string)error{
+				return ErrEmailExists
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			testName: "Invalid fields",
+			requestBody: "email=test1&name=a&password=123",
+			expectedStatus: http.StatusBadRequest,
+		},
 		
 		
 	}
@@ -84,7 +108,7 @@ func TestHandlerSignup(t *testing.T){
 			if err != nil{
 				t.Fatal(err)
 			}
-			req.Header.Set("content-type","appication/json")
+			req.Header.Set("content-type","application/x-www-form-urlencoded")
 
 			
 			rr := httptest.NewRecorder()
@@ -95,7 +119,7 @@ func TestHandlerSignup(t *testing.T){
 			}
 
 			
-			handler := &Handler{service: mockService}
+			handler := &Handler{service: mockService, policy: newTestPolicy(t)}
 
 			handler.Signup(rr,req)
 
@@ -187,6 +211,14 @@ func TestSignin(t *testing.T){
 			},
 			expectedStatus: http.StatusUnauthorized,
 		},
+		{
+			testName: "Unknown email Signin",
+			requestBody: "email=unknown@email.com&password=1231231231",
+			funcSignin: func(email,password string)(User,error){
+				return User{},ErrNotFound
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
 		{
 			testName: "Internal Server error Signin",
 			requestBody: "email=123@email.com&password=1231231231",
@@ -221,7 +253,7 @@ func TestSignin(t *testing.T){
 				funcSignin: tc.funcSignin,
 			}
 
-			handler := &Handler{service: &mockService}
+			handler := &Handler{service: &mockService, auth: testAuth}
 
 			handler.Signin(rr,req)
 			
@@ -331,13 +363,13 @@ func TestRegisterUserRout(t *testing.T){
 			nameTest: "Signup Handler",
 			nameMethod: http.MethodPost,
 			nameRouts: "/user/signup",
-			expectedStatus: http.StatusCreated,
+			expectedStatus: http.StatusBadRequest,
 		},
 		{
 			nameTest: "Path Handler",
 			nameMethod: http.MethodGet,
 			nameRouts:  "/user/list",
-			expectedStatus: http.StatusOK,
+			expectedStatus: http.StatusUnauthorized,
 		},
 	}
 	
@@ -350,6 +382,7 @@ func TestRegisterUserRout(t *testing.T){
 
 			handler :=&Handler{
 						service: mockService,
+						policy: newTestPolicy(t),
 					}
 
 			handler.RegisterUserRouter(router)
@@ -367,4 +400,204 @@ func TestRegisterUserRout(t *testing.T){
 
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestHandler_Problem(t *testing.T) {
+	cases := []struct {
+		testName       string
+		err            error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			testName:       "EmailExists",
+			err:            fmt.Errorf("error get email:%w", ErrEmailExists),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "email_exists",
+		},
+		{
+			testName:       "Internal",
+			err:            errors.New("error get email:pq: connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   api.CodeInternal,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockService := MockService{funcSignup: func(email, name, password string) error { return tc.err }}
+			handler := &Handler{service: &mockService, policy: newTestPolicy(t)}
+
+			req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader("email=test@test.com&name=test&password=correct-horse-42"))
+			req.Header.Set("content-type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+			handler.Signup(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Equal(t, api.ContentTypeProblem, rr.Header().Get("Content-Type"))
+			var problem api.Problem
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+			assert.Equal(t, tc.expectedCode, problem.Code)
+			// The internals aren't leaked to the client.
+			assert.NotContains(t, rr.Body.String(), "pq:")
+		})
+	}
+}
+
+func TestHandler_Decode(t *testing.T) {
+	cases := []struct {
+		testName       string
+		path           string
+		contentType    string
+		requestBody    string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			testName:       "JSON Signup",
+			path:           "/signup",
+			contentType:    "application/json",
+			requestBody:    `{"email":"test@test.com","name":"testName","password":"correct-horse-42"}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			testName:       "JSON Signin",
+			path:           "/signin",
+			contentType:    "application/json",
+			requestBody:    `{"email":"test@test.com","password":"correct-horse-42"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			testName:       "Unknown field",
+			path:           "/signup",
+			contentType:    "application/json",
+			requestBody:    `{"email":"test@test.com","name":"testName","password":"correct-horse-42","role":"admin"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_body",
+		},
+		{
+			testName:       "Unknown form field",
+			path:           "/signin",
+			contentType:    "application/x-www-form-urlencoded",
+			requestBody:    "email=test@test.com&password=correct-horse-42&remember=1",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_body",
+		},
+		{
+			testName:       "Unsupported media type",
+			path:           "/signin",
+			contentType:    "text/plain",
+			requestBody:    "test@test.com correct-horse-42",
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   "unsupported_media_type",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockService := MockService{funcSignin: func(email, password string) (User, error) {
+				return User{Email: email, Name: "testName"}, nil
+			}}
+			router := chi.NewRouter()
+			NewHandler(&mockService, newTestPolicy(t), testAuth).RegisterUserRouter(router)
+
+			req := httptest.NewRequest(http.MethodPost, "/user"+tc.path, strings.NewReader(tc.requestBody))
+			req.Header.Set("Content-Type", tc.contentType)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedCode != "" {
+				var problem api.Problem
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+				assert.Equal(t, tc.expectedCode, problem.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_SigninSession(t *testing.T) {
+	userID := uuid.New()
+	mockService := MockService{funcSignin: func(email, password string) (User, error) {
+		return User{ID: userID, Email: email, Name: "testName"}, nil
+	}}
+	handler := &Handler{service: &mockService, auth: testAuth}
+
+	req := httptest.NewRequest(http.MethodPost, "/signin", strings.NewReader("email=test@test.com&password=correct-horse-42"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	handler.Signin(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// The session is signed rather than the bare user id.
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.NotEqual(t, userID.String(), cookies[0].Value)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	auth.RequireUser(testAuth)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := auth.UserID(r.Context())
+		assert.Equal(t, userID, id)
+	})).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// Without the secret the sessions can't be issued.
+	handler.auth = auth.Config{}
+	req = httptest.NewRequest(http.MethodPost, "/signin", strings.NewReader("email=test@test.com&password=correct-horse-42"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	handler.Signin(rr, req)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Empty(t, rr.Result().Cookies())
+}
+
+func TestHandler_ListAdmin(t *testing.T) {
+	cases := []struct {
+		testName       string
+		token          string
+		expectedStatus int
+	}{
+		{
+			testName:       "admin",
+			token:          "admin",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			testName:       "no token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "wrong token",
+			token:          "user",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			router := chi.NewRouter()
+			mockService := MockService{
+				funcList: func() ([]User, error) {
+					return []User{{ID: uuid.New(), Email: "test@test.com", Password: "salt$hash"}}, nil
+				},
+			}
+			handler := &Handler{service: &mockService, auth: auth.Config{AdminToken: "admin"}}
+			handler.RegisterUserRouter(router)
+
+			req := httptest.NewRequest(http.MethodGet, "/user/list", nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			// The password hashes never leave the server.
+			assert.NotContains(t, rr.Body.String(), "hash")
+			assert.NotContains(t, rr.Body.String(), "password")
+		})
+	}
+}
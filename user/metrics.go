@@ -0,0 +1,43 @@
+package user
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// This file contains the user service metrics.
+
+const (
+	resultSuccess     = "success"
+	resultFailure     = "failure"
+	resultEmailExists = "email_exists"
+	resultError       = "error"
+)
+
+type (
+	// Metrics represents the user service metrics.
+	Metrics struct {
+		signups          *prometheus.CounterVec
+		signins          *prometheus.CounterVec
+		hashVerification prometheus.Histogram
+	}
+)
+
+// NewMetrics creates the user service metrics and registers them.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		signups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "user_signups_total",
+			Help: "Number of the signups by result: success, email_exists or error.",
+		}, []string{"result"}),
+		signins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "user_signins_total",
+			Help: "Number of the signins by result: success, failure for the wrong credentials or error.",
+		}, []string{"result"}),
+		hashVerification: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "user_password_verification_duration_seconds",
+			Help: "Duration of the password hash verification.",
+			// Argon2 takes tens of milliseconds by design.
+			Buckets: prometheus.ExponentialBuckets(0.005, 2, 10),
+		}),
+	}
+	reg.MustRegister(m.signups, m.signins, m.hashVerification)
+	return m
+}
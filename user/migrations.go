@@ -0,0 +1,8 @@
+package user
+
+import "embed"
+
+// Migrations holds the SQL migrations of the user tables.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
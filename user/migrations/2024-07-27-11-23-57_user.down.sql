@@ -1,5 +1 @@
-BEGIN;
-
-DROP TABLE IF EXISTS user_storage;
-
-END;
\ No newline at end of file
+DROP TABLE IF EXISTS user_storage;
\ No newline at end of file
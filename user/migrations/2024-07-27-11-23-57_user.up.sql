@@ -1,5 +1,3 @@
-BEGIN;
-
 CREATE TABLE IF NOT EXISTS user_storage (
     id UUID PRIMARY KEY,
     email VARCHAR(255) NOT NULL,
@@ -8,6 +6,4 @@ CREATE TABLE IF NOT EXISTS user_storage (
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE INDEX IF NOT EXISTS user_storage_email_index ON user_storage (email);
-
-END;
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS user_storage_email_index ON user_storage (email);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS user_storage_email_unique;
+
+CREATE INDEX IF NOT EXISTS user_storage_email_index ON user_storage (email);
\ No newline at end of file
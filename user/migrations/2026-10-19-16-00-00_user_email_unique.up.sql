@@ -0,0 +1,7 @@
+-- Emails differing only in case belong to the same user. The migration fails
+-- if such duplicates exist already, they have to be merged by hand first.
+UPDATE user_storage SET email = lower(trim(email)) WHERE email <> lower(trim(email));
+
+DROP INDEX IF EXISTS user_storage_email_index;
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_storage_email_unique ON user_storage (lower(email));
\ No newline at end of file
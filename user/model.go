@@ -10,13 +10,13 @@ import (
 
 type (
 	// User represents a user account in the system.
+	// Its password hash is never sent to the clients.
 	User struct {
 		ID       uuid.UUID `json:"id"`
 		Email    string    `json:"email"`
 		Name     string    `json:"name"`
-		Password string    `json:"password"`
+		Password string    `json:"-"`
 		Created  time.Time `json:"created"`
 		Updated  time.Time `json:"updated"`
-		
 	}
 )
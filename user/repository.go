@@ -3,13 +3,18 @@ package user
 // This file contains user repository related code.
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 
+	"github.com/GTA5-RP-Aristocracy/site-back/db"
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
 )
 
+// emailIndex is the unique index of the emails.
+const emailIndex = "user_storage_email_unique"
+
 type (
 	// Repository represents the user repository.
 	repository struct {
@@ -18,39 +23,51 @@ type (
 )
 
 // NewRepository creates a new user repository.
-func NewRepository(db *sql.DB) Repository {
-	return &repository{db}
+// It runs the queries in the transaction of the context, see db.TxManager.
+func NewRepository(sqlDB *sql.DB) Repository {
+	return &repository{sqlDB}
+}
+
+// querier returns the transaction of the context or the database.
+func (r *repository) querier(ctx context.Context) db.Querier {
+	return db.QuerierFrom(ctx, r.db)
 }
 
 // Create inserts a new user into the repository.
-func (r *repository) Create(user User) error {
-	_, err := r.db.Exec("INSERT INTO user_storage (id,email, name, password) VALUES ($1, $2, $3, $4)", user.ID, user.Email, user.Name, user.Password)
+// It returns ErrEmailExists if the email is taken, whatever its case.
+func (r *repository) Create(ctx context.Context, user User) error {
+	_, err := r.querier(ctx).ExecContext(ctx, "INSERT INTO user_storage (id,email, name, password) VALUES ($1, $2, $3, $4)", user.ID, user.Email, user.Name, user.Password)
+	if db.IsUniqueViolation(err, emailIndex) {
+		return ErrEmailExists
+	}
 	return err
 }
 
 // FindByEmail returns a user by email.
-func (r *repository) FindByEmail(email string) (User, error) {
+func (r *repository) FindByEmail(ctx context.Context, email string) (User, error) {
 	var user User
-	err := r.db.QueryRow("SELECT id, email, name, password, created, updated FROM user_storage WHERE email = $1", email).
+	err := r.querier(ctx).QueryRowContext(ctx, "SELECT id, email, name, password, created, updated FROM user_storage WHERE lower(email) = lower($1)", email).
 		Scan(&user.ID, &user.Email, &user.Name, &user.Password, &user.Created, &user.Updated)
-	if errors.Is(err,sql.ErrNoRows){
-		return User{},ErrNotFound
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrNotFound
 	}
-
 	return user, err
 }
 
 // FindByID returns a user by id.
-func (r *repository) FindByID(id uuid.UUID) (User, error) {
+func (r *repository) FindByID(ctx context.Context, id uuid.UUID) (User, error) {
 	var user User
-	err := r.db.QueryRow("SELECT id, email, name, password, created, updated FROM user_storage WHERE id = $1", id).
+	err := r.querier(ctx).QueryRowContext(ctx, "SELECT id, email, name, password, created, updated FROM user_storage WHERE id = $1", id).
 		Scan(&user.ID, &user.Email, &user.Name, &user.Password, &user.Created, &user.Updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrNotFound
+	}
 	return user, err
 }
 
 // FindAll returns all users.
-func (r *repository) FindAll() ([]User, error) {
-	rows, err := r.db.Query("SELECT id, email, name, password, created, updated FROM user_storage")
+func (r *repository) FindAll(ctx context.Context) ([]User, error) {
+	rows, err := r.querier(ctx).QueryContext(ctx, "SELECT id, email, name, password, created, updated FROM user_storage")
 	if err != nil {
 		return nil, err
 	}
@@ -64,5 +81,5 @@ func (r *repository) FindAll() ([]User, error) {
 		}
 		users = append(users, user)
 	}
-	return users, nil
+	return users, rows.Err()
 }
@@ -0,0 +1,243 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/auth"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingDriver is a database driver whose queries block until their context is done,
+// it reports the started and the cancelled queries on the channels.
+type blockingDriver struct {
+	started   chan string
+	cancelled chan string
+}
+
+type blockingConn struct {
+	driver *blockingDriver
+}
+
+func (d *blockingDriver) Open(name string) (driver.Conn, error) {
+	return &blockingConn{d}, nil
+}
+
+func (c *blockingConn) Prepare(query string) (driver.Stmt, error) {
+	panic("prepare isn't used with the context aware methods")
+}
+
+func (c *blockingConn) Close() error { return nil }
+
+func (c *blockingConn) Begin() (driver.Tx, error) {
+	panic("transactions aren't used by the repository")
+}
+
+func (c *blockingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.driver.started <- query
+	<-ctx.Done()
+	c.driver.cancelled <- query
+	return nil, ctx.Err()
+}
+
+func (c *blockingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.driver.started <- query
+	<-ctx.Done()
+	c.driver.cancelled <- query
+	return nil, ctx.Err()
+}
+
+var testDriver = &blockingDriver{started: make(chan string, 1), cancelled: make(chan string, 1)}
+
+func init() {
+	sql.Register("user-blocking", testDriver)
+}
+
+func TestRepository_Cancel(t *testing.T) {
+	db, err := sql.Open("user-blocking", "")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	repo := NewRepository(db)
+
+	cases := []struct {
+		testName string
+		call     func(ctx context.Context) error
+	}{
+		{
+			testName: "Create",
+			call: func(ctx context.Context) error {
+				return repo.Create(ctx, User{ID: uuid.New(), Email: "test@test.com"})
+			},
+		},
+		{
+			testName: "FindByEmail",
+			call: func(ctx context.Context) error {
+				_, err := repo.FindByEmail(ctx, "test@test.com")
+				return err
+			},
+		},
+		{
+			testName: "FindByID",
+			call: func(ctx context.Context) error {
+				_, err := repo.FindByID(ctx, uuid.New())
+				return err
+			},
+		},
+		{
+			testName: "FindAll",
+			call: func(ctx context.Context) error {
+				_, err := repo.FindAll(ctx)
+				return err
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			err := tc.call(ctx)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+			<-testDriver.started
+			select {
+			case query := <-testDriver.cancelled:
+				assert.Contains(t, query, "user_storage")
+			case <-time.After(time.Second):
+				t.Fatal("the query wasn't cancelled")
+			}
+		})
+	}
+}
+
+func TestHandler_Cancel(t *testing.T) {
+	db, err := sql.Open("user-blocking", "")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	handler := NewHandler(NewService(NewRepository(db), newTestMetrics()), newTestPolicy(t), auth.Config{})
+
+	// The client goes away while the handler waits for the database.
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/list", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handler.List(rr, req)
+		close(done)
+	}()
+	<-testDriver.started
+	cancel()
+
+	select {
+	case query := <-testDriver.cancelled:
+		assert.Contains(t, query, "FROM user_storage")
+	case <-time.After(time.Second):
+		t.Fatal("the query wasn't cancelled")
+	}
+	<-done
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+}
+
+// failingConn is a driver connection whose statements fail with the error.
+type failingConn struct {
+	blockingConn
+	err error
+}
+
+func (c *failingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return nil, c.err
+}
+
+// failingConnector opens the failing connections.
+type failingConnector struct {
+	err error
+}
+
+func (c failingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &failingConn{err: c.err}, nil
+}
+
+func (c failingConnector) Driver() driver.Driver { return testDriver }
+
+func TestRepository_Create_EmailExists(t *testing.T) {
+	cases := []struct {
+		testName      string
+		err           error
+		expectedError error
+	}{
+		{
+			testName:      "EmailTaken",
+			err:           &pq.Error{Code: "23505", Constraint: emailIndex},
+			expectedError: ErrEmailExists,
+		},
+		{
+			testName:      "OtherConstraint",
+			err:           &pq.Error{Code: "23505", Constraint: "user_storage_pkey"},
+			expectedError: &pq.Error{Code: "23505", Constraint: "user_storage_pkey"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			db := sql.OpenDB(failingConnector{tc.err})
+			t.Cleanup(func() { db.Close() })
+
+			err := NewRepository(db).Create(context.Background(), User{ID: uuid.New(), Email: "test@test.com"})
+			assert.Equal(t, tc.expectedError, err)
+		})
+	}
+}
+
+// emptyConn is a driver connection whose queries return no rows.
+type emptyConn struct {
+	blockingConn
+}
+
+func (c *emptyConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return emptyRows{}, nil
+}
+
+// emptyRows is the empty result of a query.
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string {
+	return []string{"id", "email", "name", "password", "created", "updated"}
+}
+
+func (emptyRows) Close() error { return nil }
+
+func (emptyRows) Next(dest []driver.Value) error { return io.EOF }
+
+// emptyConnector opens the connections without rows.
+type emptyConnector struct{}
+
+func (emptyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &emptyConn{}, nil
+}
+
+func (emptyConnector) Driver() driver.Driver { return testDriver }
+
+func TestRepository_NotFound(t *testing.T) {
+	db := sql.OpenDB(emptyConnector{})
+	t.Cleanup(func() { db.Close() })
+	repo := NewRepository(db)
+
+	_, err := repo.FindByID(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = repo.FindByEmail(context.Background(), "test@test.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
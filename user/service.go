@@ -1,33 +1,65 @@
 package user
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 	"golang.org/x/crypto/argon2"
 )
 
 // This file contains the user service implementation.
 
+// tracer traces the service methods.
+var tracer = otel.Tracer("github.com/GTA5-RP-Aristocracy/site-back/user")
+
 type (
 	// service implements the Service interface.
 	service struct {
-		repo Repository
+		repo    Repository
+		metrics *Metrics
 	}
 )
 
 // NewService creates a new user service.
-func NewService(repo Repository) Service {
-	return &service{repo}
+func NewService(repo Repository, metrics *Metrics) Service {
+	return &service{repo, metrics}
 }
 
 // Signup creates a new user account.
-func (s *service) Signup(email, name, password string) error {
+func (s *service) Signup(ctx context.Context, email, name, password string) error {
+	ctx, span := tracer.Start(ctx, "user.Signup")
+	defer span.End()
+
+	err := s.signup(ctx, email, name, password)
+	switch {
+	case err == nil:
+		s.metrics.signups.WithLabelValues(resultSuccess).Inc()
+	case errors.Is(err, ErrEmailExists):
+		s.metrics.signups.WithLabelValues(resultEmailExists).Inc()
+	default:
+		s.metrics.signups.WithLabelValues(resultError).Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// signup creates a new user account.
+func (s *service) signup(ctx context.Context, email, name, password string) error {
+	email = normalizeEmail(email)
+
 	// Check if the email is already registered.
-	_, err := s.repo.FindByEmail(email)
+	// The check only saves hashing the password, the database rejects
+	// the concurrent signups with the same email anyway.
+	_, err := s.repo.FindByEmail(ctx, email)
 	if err == nil {
 		return ErrEmailExists
 	}
@@ -47,35 +79,70 @@ func (s *service) Signup(email, name, password string) error {
 		Name:     name,
 		Password: hash,
 	}
-	return s.repo.Create(user)
+	return s.repo.Create(ctx, user)
 }
 
 // Signin checks the email and password and returns a user.
-func (s *service) Signin(email, password string) (User, error) {
-	user, err := s.repo.FindByEmail(email)
+func (s *service) Signin(ctx context.Context, email, password string) (User, error) {
+	ctx, span := tracer.Start(ctx, "user.Signin")
+	defer span.End()
+
+	user, err := s.signin(ctx, email, password)
+	switch {
+	case err == nil:
+		s.metrics.signins.WithLabelValues(resultSuccess).Inc()
+	case errors.Is(err, ErrInvalidCredentials), errors.Is(err, ErrNotFound):
+		s.metrics.signins.WithLabelValues(resultFailure).Inc()
+	default:
+		s.metrics.signins.WithLabelValues(resultError).Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return user, err
+}
+
+// signin checks the email and password and returns a user.
+func (s *service) signin(ctx context.Context, email, password string) (User, error) {
+	// The repository compares the emails case insensitively.
+	user, err := s.repo.FindByEmail(ctx, email)
 	if err != nil {
 		return User{}, err
 	}
 
-	// TODO: Use a secure password hashing algorithm.
+	_, span := tracer.Start(ctx, "user.verifyPassword")
+	start := time.Now()
 	ok, err := s.checkPasswordHash(password, user.Password)
+	s.metrics.hashVerification.Observe(time.Since(start).Seconds())
+	span.End()
 	if err != nil {
 		return User{}, fmt.Errorf("error checking password hash: %w", err)
 	}
 	if !ok {
-		return User{}, fmt.Errorf("invalid password")
+		return User{}, ErrInvalidCredentials
 	}
 	return user, nil
 }
 
 // Get fetches a user by id.
-func (s *service) Get(id uuid.UUID) (User, error) {
-	return s.repo.FindByID(id)
+func (s *service) Get(ctx context.Context, id uuid.UUID) (User, error) {
+	ctx, span := tracer.Start(ctx, "user.Get")
+	defer span.End()
+
+	return s.repo.FindByID(ctx, id)
 }
 
 // List fetches all users.
-func (s *service) List() ([]User, error) {
-	return s.repo.FindAll()
+func (s *service) List(ctx context.Context) ([]User, error) {
+	ctx, span := tracer.Start(ctx, "user.List")
+	defer span.End()
+
+	return s.repo.FindAll(ctx)
+}
+
+// normalizeEmail trims the email and makes it lowercase, so the emails differing
+// only in case belong to the same user.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
 // check passw and hash sum
@@ -1,14 +1,24 @@
 package user
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"encoding/base64"
+	"strings"
+	"sync"
 	"testing"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 type MockRep struct {
@@ -16,25 +26,25 @@ type MockRep struct {
 }
 
 // FindByID
-func (m *MockRep) FindByID(id uuid.UUID) (User, error) {
+func (m *MockRep) FindByID(ctx context.Context, id uuid.UUID) (User, error) {
 	args := m.Called(id)
 	return args.Get(0).(User), args.Error(1)
 }
 
 // Create
-func (m *MockRep) Create(user User) error {
+func (m *MockRep) Create(ctx context.Context, user User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
 // FindAll.
-func (m *MockRep) FindAll() ([]User, error) {
+func (m *MockRep) FindAll(ctx context.Context) ([]User, error) {
 	args := m.Called()
 	return args.Get(0).([]User), args.Error(1)
 }
 
 // FindByEmail
-func (m *MockRep) FindByEmail(email string) (User, error) {
+func (m *MockRep) FindByEmail(ctx context.Context, email string) (User, error) {
 	args := m.Called(email)
 	return args.Get(0).(User), args.Error(1)
 }
@@ -45,12 +55,12 @@ func TestServiceGet(t *testing.T) {
 
 	testID := uuid.New()
 	mockRepo := new(MockRep)
-	svc := NewService(mockRepo)
+	svc := NewService(mockRepo, newTestMetrics())
 
 	expectUser := User{ID: testID, Name: "Test"}
 	mockRepo.On("FindByID", testID).Return(expectUser, nil)
 
-	user, err := svc.Get(testID)
+	user, err := svc.Get(context.Background(), testID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectUser, user)
@@ -62,11 +72,11 @@ func TestServiceGet(t *testing.T) {
 func TestService_Get_NotFound(t *testing.T) {
 	testID := uuid.New()
 	mockRepo := new(MockRep)
-	svc := NewService(mockRepo)
+	svc := NewService(mockRepo, newTestMetrics())
 
 	mockRepo.On("FindByID", testID).Return(User{}, errors.New("User not found"))
 
-	user, err := svc.Get(testID)
+	user, err := svc.Get(context.Background(), testID)
 
 	assert.Error(t, err)
 	assert.Equal(t, User{}, user)
@@ -78,7 +88,7 @@ func TestService_Get_NotFound(t *testing.T) {
 func TestService_FindAll(t *testing.T) {
 
 	mockRepo := new(MockRep)
-	svc := NewService(mockRepo)
+	svc := NewService(mockRepo, newTestMetrics())
 	myId := uuid.New()
 
 	testUsers := []User{
@@ -86,7 +96,7 @@ func TestService_FindAll(t *testing.T) {
 		{ID: myId, Name: "Testing 2"},
 	}
 	mockRepo.On("FindAll").Return(testUsers, nil)
-	users, err := svc.List()
+	users, err := svc.List(context.Background())
 
 	assert.NoError(t, err)
 	assert.Equal(t, testUsers, users)
@@ -99,7 +109,11 @@ func TestService_FindAll(t *testing.T) {
 
 func TestService_Signin_All(t *testing.T) {
 	mockRepo := new(MockRep)
-	svc := NewService(mockRepo)
+	svc := NewService(mockRepo, newTestMetrics())
+
+	// The repository stores the password hashes.
+	hash, err := (&service{}).passHashed("testpas123")
+	require.NoError(t, err)
 
 	cases := []struct {
 		testName          string
@@ -118,14 +132,14 @@ func TestService_Signin_All(t *testing.T) {
 			expectedUser: User{
 				Name:     "Stas",
 				Email:    "test@test.com",
-				Password: "testpas123",
+				Password: hash,
 			},
 			expectedError:     nil,
 			repoExpectedEmail: "test@test.com",
 			repoOutUser: User{
 				Name:     "Stas",
 				Email:    "test@test.com",
-				Password: "testpas123",
+				Password: hash,
 			},
 			repoOutError: nil,
 		}, 
@@ -145,12 +159,12 @@ func TestService_Signin_All(t *testing.T) {
 			email:    "testUserPaasword@test.com",
 			password: "wrongpasword",
 			expectedUser: User{},
-			expectedError:     ErrNotFound,
+			expectedError:     ErrInvalidCredentials,
 			repoExpectedEmail: "testUserPaasword@test.com",
 			repoOutUser: User{
 				Name:     "Stas",
 				Email:    "test@test.com",
-				Password: "testpas123",
+				Password: hash,
 			},
 			repoOutError: nil,
 		},
@@ -158,7 +172,7 @@ func TestService_Signin_All(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.testName, func(t *testing.T) {			
 			mockRepo.On("FindByEmail", tc.repoExpectedEmail).Return(tc.repoOutUser, tc.repoOutError)
-			user, err := svc.Signin(tc.email, tc.password)
+			user, err := svc.Signin(context.Background(), tc.email, tc.password)
 			assert.Equal(t, tc.expectedUser, user)
 			assert.Equal(t, tc.expectedError, err)
 		})
@@ -246,7 +260,7 @@ func TestService_Signup_All(t *testing.T){
 	for _, tc := range cases {
 		t.Run(tc.testName, func(t *testing.T) {
 			mockRepo := new(MockRep)
-			svc := NewService(mockRepo)
+			svc := NewService(mockRepo, newTestMetrics())
 			mockRepo.On("FindByEmail", tc.repoExpectedEmail).Return(tc.repoOutUser, tc.repoOutError)
 	
 			if errors.Is(tc.repoOutError, ErrNotFound) {
@@ -256,7 +270,7 @@ func TestService_Signup_All(t *testing.T){
 				})).Return(tc.expectedError)
 			}
 	
-			err := svc.Signup(tc.email, tc.name, tc.password)
+			err := svc.Signup(context.Background(), tc.email, tc.name, tc.password)
 			t.Logf("Expected error: %v, Actual error: %v", tc.expectedError, err)
 	
 			if tc.expectedError != nil {
@@ -315,3 +329,123 @@ func TestServicee_checkPasswordHash(t *testing.T){
 }
 	
 
+
+// memoryRepo keeps the users in memory and enforces the unique emails like the database does.
+// Its FindByEmail waits until all the signups have checked the email, so they race to Create.
+type memoryRepo struct {
+	MockRep
+	mu      sync.Mutex
+	users   map[string]User
+	checked sync.WaitGroup
+}
+
+func (r *memoryRepo) FindByEmail(ctx context.Context, email string) (User, error) {
+	r.checked.Done()
+	r.checked.Wait()
+	return User{}, ErrNotFound
+}
+
+func (r *memoryRepo) Create(ctx context.Context, user User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[strings.ToLower(user.Email)]; ok {
+		return ErrEmailExists
+	}
+	r.users[strings.ToLower(user.Email)] = user
+	return nil
+}
+
+func TestService_Signup_Concurrent(t *testing.T) {
+	emails := []string{"racer@test.com", "Racer@test.com", " RACER@TEST.COM", "racer@Test.com"}
+
+	repo := &memoryRepo{users: map[string]User{}}
+	repo.checked.Add(len(emails))
+	svc := NewService(repo, newTestMetrics())
+
+	errs := make([]error, len(emails))
+	var wg sync.WaitGroup
+	for i, email := range emails {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = svc.Signup(context.Background(), email, "Racer", "pass123")
+		}()
+	}
+	wg.Wait()
+
+	var created int
+	for _, err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		assert.ErrorIs(t, err, ErrEmailExists)
+	}
+	assert.Equal(t, 1, created)
+	assert.Contains(t, repo.users, "racer@test.com")
+}
+
+// newTestMetrics creates the metrics in a registry of their own.
+func newTestMetrics() *Metrics {
+	return NewMetrics(prometheus.NewRegistry())
+}
+
+func TestService_Metrics(t *testing.T) {
+	mockRepo := new(MockRep)
+	metrics := newTestMetrics()
+	svc := NewService(mockRepo, metrics)
+
+	hash, err := (&service{}).passHashed("testpas123")
+	require.NoError(t, err)
+	mockRepo.On("FindByEmail", "known@test.com").Return(User{Email: "known@test.com", Password: hash}, nil)
+	mockRepo.On("FindByEmail", "unknown@test.com").Return(User{}, ErrNotFound)
+	mockRepo.On("FindByEmail", "broken@test.com").Return(User{}, errors.New("connection refused"))
+
+	svc.Signin(context.Background(), "known@test.com", "testpas123")
+	svc.Signin(context.Background(), "known@test.com", "wrong")
+	svc.Signin(context.Background(), "unknown@test.com", "testpas123")
+	svc.Signin(context.Background(), "broken@test.com", "testpas123")
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.signins.WithLabelValues(resultSuccess)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.signins.WithLabelValues(resultFailure)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.signins.WithLabelValues(resultError)))
+	// The hashes are verified for the known user only.
+	var histogram dto.Metric
+	require.NoError(t, metrics.hashVerification.Write(&histogram))
+	assert.Equal(t, uint64(2), histogram.GetHistogram().GetSampleCount())
+
+	mockRepo.On("FindByEmail", "taken@test.com").Return(User{}, nil)
+	svc.Signup(context.Background(), "taken@test.com", "Test", "pass123")
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.signups.WithLabelValues(resultEmailExists)))
+}
+
+func TestService_Spans(t *testing.T) {
+	// The tracer of the package delegates to the first provider installed.
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	mockRepo := new(MockRep)
+	svc := NewService(mockRepo, newTestMetrics())
+
+	hash, err := (&service{}).passHashed("testpas123")
+	require.NoError(t, err)
+	mockRepo.On("FindByEmail", "known@test.com").Return(User{Email: "known@test.com", Password: hash}, nil)
+	mockRepo.On("FindByEmail", "broken@test.com").Return(User{}, errors.New("connection refused"))
+
+	_, err = svc.Signin(context.Background(), "known@test.com", "wrong")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = svc.Signin(context.Background(), "broken@test.com", "testpas123")
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	// The password verification is a child of the signin.
+	assert.Equal(t, "user.verifyPassword", spans[0].Name())
+	assert.Equal(t, "user.Signin", spans[1].Name())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	// The wrong credentials aren't an error of the service, unlike the database failure.
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Equal(t, "user.Signin", spans[2].Name())
+	assert.Equal(t, codes.Error, spans[2].Status().Code)
+}
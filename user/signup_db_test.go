@@ -0,0 +1,88 @@
+package user_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/db/migrate"
+	"github.com/GTA5-RP-Aristocracy/site-back/migrations"
+	"github.com/GTA5-RP-Aristocracy/site-back/user"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkingRepo waits in FindByEmail until all the signups have checked the email,
+// so they race to Create.
+type checkingRepo struct {
+	user.Repository
+	checked sync.WaitGroup
+}
+
+func (r *checkingRepo) FindByEmail(ctx context.Context, email string) (user.User, error) {
+	u, err := r.Repository.FindByEmail(ctx, email)
+	r.checked.Done()
+	r.checked.Wait()
+	return u, err
+}
+
+// TestService_Signup_ConcurrentDB races the signups against the unique index of the emails.
+// It runs against the database of TEST_DB_URL, applying the migrations.
+func TestService_Signup_ConcurrentDB(t *testing.T) {
+	dsn := os.Getenv("TEST_DB_URL")
+	if dsn == "" {
+		t.Skip("TEST_DB_URL isn't set")
+	}
+
+	ctx := context.Background()
+	sqlDB, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	_, err = migrate.NewMigrator(sqlDB, migrations.Sources()...).Apply(ctx)
+	require.NoError(t, err)
+
+	cleanup := func() {
+		_, err := sqlDB.ExecContext(ctx, "DELETE FROM user_storage WHERE lower(email) = 'racer@test.com'")
+		require.NoError(t, err)
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	emails := []string{"racer@test.com", "Racer@test.com"}
+	repo := &checkingRepo{Repository: user.NewRepository(sqlDB)}
+	repo.checked.Add(len(emails))
+	svc := user.NewService(repo, user.NewMetrics(prometheus.NewRegistry()))
+
+	errs := make([]error, len(emails))
+	var wg sync.WaitGroup
+	for i, email := range emails {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = svc.Signup(ctx, email, "Racer", "pass123")
+		}()
+	}
+	wg.Wait()
+
+	var created, exists int
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case errors.Is(err, user.ErrEmailExists):
+			exists++
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	assert.Equal(t, 1, created)
+	assert.Equal(t, 1, exists)
+
+	var count int
+	require.NoError(t, sqlDB.QueryRowContext(ctx, "SELECT count(*) FROM user_storage WHERE lower(email) = 'racer@test.com'").Scan(&count))
+	assert.Equal(t, 1, count)
+}
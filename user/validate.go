@@ -0,0 +1,139 @@
+package user
+
+import (
+	_ "embed"
+	"fmt"
+	"net/mail"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/api"
+)
+
+// This file contains the validation of the user requests.
+
+const (
+	// emailMaxLength and emailLocalMaxLength are the limits of RFC 5321.
+	emailMaxLength      = 254
+	emailLocalMaxLength = 64
+	// emailInPasswordMinLength is the shortest local part of the email which is looked for
+	// in the passwords, the shorter ones match too many passwords by chance.
+	emailInPasswordMinLength = 4
+)
+
+var (
+	// namePattern matches the names of letters, digits, dots, dashes and underscores.
+	namePattern = regexp.MustCompile(`^[\p{L}\p{N}._-]+$`)
+
+	// breachedPasswords is the built in list of the breached passwords.
+	//go:embed breached_passwords.txt
+	breachedPasswords string
+)
+
+type (
+	// Policy validates the user requests against the configured rules.
+	Policy struct {
+		config   Config
+		reserved map[string]bool
+		breached map[string]bool
+	}
+)
+
+// NewPolicy creates the policy of the configuration, reading the breached passwords file.
+func NewPolicy(config Config) (*Policy, error) {
+	p := &Policy{config: config, reserved: map[string]bool{}, breached: map[string]bool{}}
+	for _, name := range config.ReservedNames {
+		p.reserved[strings.ToLower(name)] = true
+	}
+
+	p.addBreached(breachedPasswords)
+	if config.BreachedPasswordsFile != "" {
+		data, err := os.ReadFile(config.BreachedPasswordsFile)
+		if err != nil {
+			return nil, fmt.Errorf("read breached passwords: %w", err)
+		}
+		p.addBreached(string(data))
+	}
+	return p, nil
+}
+
+// addBreached adds the passwords of the list, skipping the comments.
+func (p *Policy) addBreached(list string) {
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			p.breached[strings.ToLower(line)] = true
+		}
+	}
+}
+
+// validateSignup checks the signup request, reporting all the invalid fields at once.
+func (p *Policy) validateSignup(req signupRequest) error {
+	var v api.Validation
+	email := strings.TrimSpace(req.Email)
+	validateEmail(&v, email)
+
+	nameLength := utf8.RuneCountInString(req.Name)
+	v.Check(req.Name != "", "name", "required", "is required")
+	if v.Valid("name") {
+		v.Check(nameLength >= p.config.NameMinLength, "name", "too_short",
+			"must be at least %d characters long", p.config.NameMinLength)
+		v.Check(nameLength <= p.config.NameMaxLength, "name", "too_long",
+			"must be at most %d characters long", p.config.NameMaxLength)
+		v.Check(namePattern.MatchString(req.Name), "name", "invalid_characters",
+			"may contain letters, digits, dots, dashes and underscores only")
+		v.Check(!p.reserved[strings.ToLower(req.Name)], "name", "reserved", "is reserved")
+	}
+
+	passwordLength := utf8.RuneCountInString(req.Password)
+	v.Check(req.Password != "", "password", "required", "is required")
+	if v.Valid("password") {
+		v.Check(passwordLength >= p.config.PasswordMinLength, "password", "too_short",
+			"must be at least %d characters long", p.config.PasswordMinLength)
+		v.Check(passwordLength <= p.config.PasswordMaxLength, "password", "too_long",
+			"must be at most %d characters long", p.config.PasswordMaxLength)
+		v.Check(!p.breached[strings.ToLower(req.Password)], "password", "breached",
+			"is known from data breaches, choose another one")
+		v.Check(!containsEmail(req.Password, email), "password", "contains_email", "must not contain the email")
+	}
+
+	return v.Err()
+}
+
+// validateSignin checks that the signin request has the credentials. The policy
+// isn't applied, since the accounts may predate its rules.
+func validateSignin(req signinRequest) error {
+	var v api.Validation
+	v.Check(req.Email != "", "email", "required", "is required")
+	v.Check(req.Password != "", "password", "required", "is required")
+	return v.Err()
+}
+
+// validateEmail checks the syntax and the length of the email address.
+func validateEmail(v *api.Validation, email string) {
+	v.Check(email != "", "email", "required", "is required")
+	if !v.Valid("email") {
+		return
+	}
+
+	v.Check(len(email) <= emailMaxLength, "email", "too_long", "must be at most %d characters long", emailMaxLength)
+	// The display names and the comments of the addresses aren't allowed.
+	address, err := mail.ParseAddress(email)
+	local, _, _ := strings.Cut(email, "@")
+	v.Check(err == nil && address.Address == email && len(local) <= emailLocalMaxLength, "email", "invalid",
+		"must be a valid email address")
+}
+
+// containsEmail reports whether the password contains the email or its local part.
+func containsEmail(password, email string) bool {
+	if email == "" {
+		return false
+	}
+
+	password, email = strings.ToLower(password), strings.ToLower(email)
+	local, _, _ := strings.Cut(email, "@")
+	return strings.Contains(password, email) ||
+		len(local) >= emailInPasswordMinLength && strings.Contains(password, local)
+}
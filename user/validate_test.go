@@ -0,0 +1,119 @@
+package user
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPolicy creates the policy of the default configuration.
+func newTestPolicy(t *testing.T) *Policy {
+	policy, err := NewPolicy(Config{
+		NameMinLength:     3,
+		NameMaxLength:     32,
+		ReservedNames:     []string{"admin", "support"},
+		PasswordMinLength: 10,
+		PasswordMaxLength: 128,
+	})
+	require.NoError(t, err)
+	return policy
+}
+
+func TestPolicy_ValidateSignup(t *testing.T) {
+	cases := []struct {
+		testName       string
+		req            signupRequest
+		expectedFields []api.FieldError
+	}{
+		{
+			testName: "Valid",
+			req:      signupRequest{Email: " Player@Example.com ", Name: "Niko_Bellic", Password: "correct-horse-42"},
+		},
+		{
+			testName: "Empty",
+			req:      signupRequest{},
+			expectedFields: []api.FieldError{
+				{Field: "email", Code: "required", Message: "is required"},
+				{Field: "name", Code: "required", Message: "is required"},
+				{Field: "password", Code: "required", Message: "is required"},
+			},
+		},
+		{
+			testName: "AllProblems",
+			req:      signupRequest{Email: "Player <player@example.com>", Name: "A!", Password: "short"},
+			expectedFields: []api.FieldError{
+				{Field: "email", Code: "invalid", Message: "must be a valid email address"},
+				{Field: "name", Code: "too_short", Message: "must be at least 3 characters long"},
+				{Field: "name", Code: "invalid_characters", Message: "may contain letters, digits, dots, dashes and underscores only"},
+				{Field: "password", Code: "too_short", Message: "must be at least 10 characters long"},
+			},
+		},
+		{
+			testName: "TooLong",
+			req:      signupRequest{Email: strings.Repeat("a", 65) + "@example.com", Name: strings.Repeat("a", 33), Password: strings.Repeat("b", 129)},
+			expectedFields: []api.FieldError{
+				{Field: "email", Code: "invalid", Message: "must be a valid email address"},
+				{Field: "name", Code: "too_long", Message: "must be at most 32 characters long"},
+				{Field: "password", Code: "too_long", Message: "must be at most 128 characters long"},
+			},
+		},
+		{
+			testName: "Reserved",
+			req:      signupRequest{Email: "player@example.com", Name: "Admin", Password: "correct-horse-42"},
+			expectedFields: []api.FieldError{
+				{Field: "name", Code: "reserved", Message: "is reserved"},
+			},
+		},
+		{
+			testName: "Breached",
+			req:      signupRequest{Email: "player@example.com", Name: "player", Password: "Password1234"},
+			expectedFields: []api.FieldError{
+				{Field: "password", Code: "breached", Message: "is known from data breaches, choose another one"},
+			},
+		},
+		{
+			testName: "ContainsEmail",
+			req:      signupRequest{Email: "nikobellic@example.com", Name: "player", Password: "NikoBellic-2008"},
+			expectedFields: []api.FieldError{
+				{Field: "password", Code: "contains_email", Message: "must not contain the email"},
+			},
+		},
+	}
+
+	policy := newTestPolicy(t)
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			err := policy.validateSignup(c.req)
+			if c.expectedFields == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var validationErr *api.ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, c.expectedFields, validationErr.Fields)
+		})
+	}
+}
+
+func TestNewPolicy_BreachedPasswordsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(file, []byte("# leaked\nLosSantos-1999\n"), 0o644))
+
+	policy, err := NewPolicy(Config{NameMinLength: 3, NameMaxLength: 32, PasswordMinLength: 10, PasswordMaxLength: 128, BreachedPasswordsFile: file})
+	require.NoError(t, err)
+
+	// Both the built in and the configured lists are used.
+	for _, password := range []string{"lossantos-1999", "qwertyuiop"} {
+		err := policy.validateSignup(signupRequest{Email: "player@example.com", Name: "player", Password: password})
+		assert.ErrorContains(t, err, "password breached")
+	}
+
+	_, err = NewPolicy(Config{BreachedPasswordsFile: filepath.Join(t.TempDir(), "missing.txt")})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,13 @@
+package vip
+
+import "time"
+
+type (
+	// Config represents the configuration options for VIP subscriptions.
+	Config struct {
+		// GracePeriod is how long players keep the perks after the expiry.
+		GracePeriod time.Duration `env:"VIP_GRACE_PERIOD" envDefault:"72h" yaml:"grace_period"`
+		// ExpireInterval is how often the background job looks for expired subscriptions.
+		ExpireInterval time.Duration `env:"VIP_EXPIRE_INTERVAL" envDefault:"1m" yaml:"expire_interval"`
+	}
+)
@@ -0,0 +1,53 @@
+package vip
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// This file defines the VIP related interfaces.
+
+type (
+	// Service represents the VIP service interface.
+	Service interface {
+		// Grant gives VIP status to a user, stacking it with the user's subscriptions of the same tier.
+		Grant(ctx context.Context, grant Grant) (Subscription, error)
+		// Revoke takes a subscription back.
+		Revoke(ctx context.Context, id uuid.UUID) error
+		// RevokeOrderItem takes back the subscription bought with the order item, if any.
+		RevokeOrderItem(ctx context.Context, orderItemID uuid.UUID) error
+		// Subscriptions fetches all subscriptions of a user.
+		Subscriptions(ctx context.Context, userID uuid.UUID) ([]Subscription, error)
+		// Entitlements fetches the current VIP status of a user.
+		Entitlements(ctx context.Context, userID uuid.UUID) (Entitlements, error)
+		// ExpireDue moves subscriptions past their expiry to the grace and expired statuses.
+		// It returns the number of changed subscriptions.
+		ExpireDue(ctx context.Context) (int, error)
+	}
+
+	// Repository represents the VIP repository interface.
+	Repository interface {
+		// Stack inserts the subscription built by fn from the current subscriptions of the user.
+		// Concurrent calls for the same user are serialized. It reports false and doesn't call fn
+		// if the order item of the subscription has already been granted.
+		Stack(ctx context.Context, userID uuid.UUID, orderItemID uuid.NullUUID, fn func(current []Subscription) (Subscription, error)) (Subscription, bool, error)
+		// FindByID returns a subscription by id.
+		FindByID(ctx context.Context, id uuid.UUID) (Subscription, error)
+		// FindByOrderItemID returns the subscription bought with the order item.
+		FindByOrderItemID(ctx context.Context, orderItemID uuid.UUID) (Subscription, error)
+		// FindByUser returns all subscriptions of a user.
+		FindByUser(ctx context.Context, userID uuid.UUID) ([]Subscription, error)
+		// FindDue returns subscriptions with the status that expired before the given time.
+		FindDue(ctx context.Context, status Status, before time.Time, limit int) ([]Subscription, error)
+		// UpdateStatus moves the subscription from one status to another.
+		// It reports false if the subscription is not in the expected status anymore.
+		UpdateStatus(ctx context.Context, id uuid.UUID, from, to Status) (bool, error)
+	}
+
+	// Publisher delivers subscription events to interested parties.
+	Publisher interface {
+		Publish(ctx context.Context, event Event) error
+	}
+)
@@ -0,0 +1,12 @@
+package vip
+
+// This file contains VIP related errors.
+
+import "errors"
+
+// Define custom errors.
+var (
+	ErrNotFound    = errors.New("vip: subscription not found")
+	ErrUnknownTier = errors.New("vip: unknown tier")
+	ErrInvalidDays = errors.New("vip: days must be positive")
+)
@@ -0,0 +1,41 @@
+package vip
+
+import (
+	"context"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/shop"
+	"github.com/google/uuid"
+)
+
+// This file connects VIP subscriptions to the shop.
+
+type (
+	// Fulfiller turns paid VIP products into subscriptions, it implements shop.Fulfiller.
+	Fulfiller struct {
+		service Service
+	}
+)
+
+// NewFulfiller creates a new shop fulfiller for VIP products.
+func NewFulfiller(service Service) *Fulfiller {
+	return &Fulfiller{service}
+}
+
+// Fulfill grants the VIP tier for the product days times the item quantity.
+// The order item id makes repeated calls grant the subscription only once.
+func (f *Fulfiller) Fulfill(ctx context.Context, order shop.Order, item shop.OrderItem, product shop.Product) error {
+	_, err := f.service.Grant(ctx, Grant{
+		UserID:      order.UserID,
+		Tier:        product.VIPTier,
+		Days:        product.VIPDays * item.Quantity,
+		Source:      SourcePurchase,
+		OrderItemID: uuid.NullUUID{UUID: item.ID, Valid: true},
+		Note:        "order " + order.ID.String(),
+	})
+	return err
+}
+
+// Revoke takes back the subscription bought with the order item.
+func (f *Fulfiller) Revoke(ctx context.Context, _ shop.Order, item shop.OrderItem, _ shop.Product) error {
+	return f.service.RevokeOrderItem(ctx, item.ID)
+}
@@ -0,0 +1,183 @@
+package vip
+
+import (
+	"net/http"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/api"
+	"github.com/GTA5-RP-Aristocracy/site-back/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+)
+
+// This file contains VIP related http handlers.
+
+const (
+	pathRoot              = "/vip"
+	pathMe                = "/me"
+	pathEntitlements      = "/entitlements/{userID}"
+	pathAdmin             = "/admin"
+	pathGrants            = "/grants"
+	pathUserSubscriptions = "/users/{userID}/subscriptions"
+	pathRevoke            = "/subscriptions/{id}/revoke"
+)
+
+// errorMappings map the VIP errors to the problem responses.
+var errorMappings = []api.Mapping{
+	{Err: ErrNotFound, Status: http.StatusNotFound, Code: "subscription_not_found"},
+	{Err: ErrUnknownTier, Status: http.StatusBadRequest, Code: "unknown_tier"},
+	{Err: ErrInvalidDays, Status: http.StatusBadRequest, Code: "invalid_days"},
+}
+
+type (
+	// Handler represents a set of http handlers for VIP subscriptions.
+	Handler struct {
+		service Service
+		auth    auth.Config
+	}
+
+	// meResponse represents the VIP status of the current user.
+	meResponse struct {
+		Entitlements  Entitlements   `json:"entitlements"`
+		Subscriptions []Subscription `json:"subscriptions"`
+	}
+)
+
+// NewHandler creates a new VIP http handler.
+func NewHandler(service Service, authConfig auth.Config) *Handler {
+	return &Handler{service, authConfig}
+}
+
+// RegisterVIPRouter registers VIP routes.
+func (h *Handler) RegisterVIPRouter(externalRouter chi.Router) {
+	r := chi.NewRouter()
+
+	// Player status.
+	r.With(auth.RequireUser(h.auth)).Get(pathMe, h.Me)
+
+	// Game server checks.
+	r.With(auth.RequireToken(h.auth.ServerToken)).Get(pathEntitlements, h.Entitlements)
+
+	// Staff management.
+	r.Route(pathAdmin, func(r chi.Router) {
+		r.Use(auth.RequireToken(h.auth.AdminToken))
+		r.Post(pathGrants, h.Grant)
+		r.Get(pathUserSubscriptions, h.Subscriptions)
+		r.Post(pathRevoke, h.Revoke)
+	})
+
+	externalRouter.Mount(pathRoot, r)
+}
+
+// Me handles the VIP status request of the current user.
+func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
+	userID, _ := auth.UserID(r.Context())
+
+	entitlements, err := h.service.Entitlements(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	subs, err := h.service.Subscriptions(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if subs == nil {
+		subs = []Subscription{}
+	}
+
+	writeJSON(w, http.StatusOK, meResponse{entitlements, subs})
+}
+
+// Entitlements handles the game server request of the player's current VIP status.
+func (h *Handler) Entitlements(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseID(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	entitlements, err := h.service.Entitlements(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entitlements)
+}
+
+// Grant handles the complimentary VIP request of the staff.
+func (h *Handler) Grant(w http.ResponseWriter, r *http.Request) {
+	var grant Grant
+	if err := api.DecodeJSON(w, r, &grant); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	grant.Source = SourceComplimentary
+
+	sub, err := h.service.Grant(r.Context(), grant)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+// Subscriptions handles the staff request of a user's subscriptions.
+func (h *Handler) Subscriptions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseID(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	subs, err := h.service.Subscriptions(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if subs == nil {
+		subs = []Subscription{}
+	}
+
+	writeJSON(w, http.StatusOK, subs)
+}
+
+// Revoke handles the staff request to take a subscription back.
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Revoke(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseID parses the uuid url parameter.
+func parseID(w http.ResponseWriter, r *http.Request, param string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, param))
+	if err != nil {
+		writeError(w, r, api.ErrInvalidID)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writeError writes the problem matching the error.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	api.WriteError(w, r, err, errorMappings...)
+}
+
+// writeJSON writes the response in JSON format.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
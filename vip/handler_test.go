@@ -0,0 +1,176 @@
+package vip
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAuth signs the sessions of the tests.
+var testAuth = auth.Config{SessionSecret: "0123456789abcdef0123456789abcdef", SessionTTL: time.Hour}
+
+// newSession returns the signed session of the user.
+func newSession(t *testing.T, id uuid.UUID) string {
+	session, err := auth.NewSession(testAuth, id, time.Now())
+	require.NoError(t, err)
+	return session
+}
+
+type MockService struct {
+	Service
+	funcGrant         func(grant Grant) (Subscription, error)
+	funcEntitlements  func(userID uuid.UUID) (Entitlements, error)
+	funcSubscriptions func(userID uuid.UUID) ([]Subscription, error)
+}
+
+func (m *MockService) Grant(ctx context.Context, grant Grant) (Subscription, error) {
+	return m.funcGrant(grant)
+}
+
+func (m *MockService) Entitlements(ctx context.Context, userID uuid.UUID) (Entitlements, error) {
+	return m.funcEntitlements(userID)
+}
+
+func (m *MockService) Subscriptions(ctx context.Context, userID uuid.UUID) ([]Subscription, error) {
+	return m.funcSubscriptions(userID)
+}
+
+func TestHandlerEntitlements(t *testing.T) {
+	userID := uuid.New()
+
+	cases := []struct {
+		testName       string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{
+			testName:       "ok",
+			path:           "/vip/entitlements/" + userID.String(),
+			token:          "server",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			testName:       "without token",
+			path:           "/vip/entitlements/" + userID.String(),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "invalid id",
+			path:           "/vip/entitlements/invalid",
+			token:          "server",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			router := chi.NewRouter()
+			handler := NewHandler(&MockService{
+				funcEntitlements: func(id uuid.UUID) (Entitlements, error) {
+					assert.Equal(t, userID, id)
+					return Entitlements{UserID: id}, nil
+				},
+			}, auth.Config{ServerToken: "server", AdminToken: "admin"})
+			handler.RegisterVIPRouter(router)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestHandlerMe(t *testing.T) {
+	userID := uuid.New()
+
+	router := chi.NewRouter()
+	handler := NewHandler(&MockService{
+		funcEntitlements: func(id uuid.UUID) (Entitlements, error) {
+			return Entitlements{UserID: id}, nil
+		},
+		funcSubscriptions: func(id uuid.UUID) ([]Subscription, error) {
+			assert.Equal(t, userID, id)
+			return nil, nil
+		},
+	}, testAuth)
+	handler.RegisterVIPRouter(router)
+
+	req := httptest.NewRequest(http.MethodGet, "/vip/me", nil)
+	req.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: newSession(t, userID)})
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"subscriptions":[]`)
+}
+
+func TestHandlerGrant(t *testing.T) {
+	cases := []struct {
+		testName       string
+		requestBody    string
+		funcGrant      func(grant Grant) (Subscription, error)
+		expectedStatus int
+	}{
+		{
+			testName:    "ok",
+			requestBody: `{"user_id":"123e4567-e89b-12d3-a456-426614174000","tier":"gold","days":30,"issued_by":"admin"}`,
+			funcGrant: func(grant Grant) (Subscription, error) {
+				assert.Equal(t, SourceComplimentary, grant.Source)
+				assert.False(t, grant.OrderItemID.Valid)
+				return Subscription{Tier: grant.Tier}, nil
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			testName:       "purchase source is rejected",
+			requestBody:    `{"user_id":"123e4567-e89b-12d3-a456-426614174000","tier":"gold","days":30,"source":"purchase"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			testName:    "unknown tier",
+			requestBody: `{"user_id":"123e4567-e89b-12d3-a456-426614174000","tier":"platinum","days":30}`,
+			funcGrant: func(grant Grant) (Subscription, error) {
+				return Subscription{}, ErrUnknownTier
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			testName:       "invalid body",
+			requestBody:    `{"tier":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			router := chi.NewRouter()
+			handler := NewHandler(&MockService{funcGrant: tc.funcGrant}, auth.Config{AdminToken: "admin"})
+			handler.RegisterVIPRouter(router)
+
+			req := httptest.NewRequest(http.MethodPost, "/vip/admin/grants", strings.NewReader(tc.requestBody))
+			req.Header.Set("Authorization", "Bearer admin")
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package vip
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// This file contains the background job that expires subscriptions.
+
+type (
+	// Expirer periodically moves expired subscriptions to the grace and expired statuses.
+	Expirer struct {
+		service  Service
+		interval time.Duration
+		logger   zerolog.Logger
+	}
+)
+
+// NewExpirer creates a new subscription expiry job.
+func NewExpirer(service Service, config Config, logger zerolog.Logger) *Expirer {
+	return &Expirer{service, config.ExpireInterval, logger}
+}
+
+// Run expires due subscriptions every interval until the context is cancelled.
+func (e *Expirer) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		changed, err := e.service.ExpireDue(ctx)
+		if err != nil {
+			e.logger.Error().Err(err).Msg("failed to expire vip subscriptions")
+		} else if changed > 0 {
+			e.logger.Info().Int("count", changed).Msg("vip subscriptions expired")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package vip
+
+import "embed"
+
+// Migrations holds the SQL migrations of the VIP subscription tables.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS vip_subscription;
\ No newline at end of file
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS vip_subscription (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES user_storage (id),
+    tier VARCHAR(32) NOT NULL,
+    source VARCHAR(32) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    starts TIMESTAMP NOT NULL,
+    expires TIMESTAMP NOT NULL,
+    order_item_id UUID UNIQUE REFERENCES shop_order_item (id),
+    issued_by VARCHAR(255) NOT NULL DEFAULT '',
+    note TEXT NOT NULL DEFAULT '',
+    created TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (expires > starts)
+);
+
+CREATE INDEX IF NOT EXISTS vip_subscription_user_index ON vip_subscription (user_id);
+CREATE INDEX IF NOT EXISTS vip_subscription_due_index ON vip_subscription (status, expires);
\ No newline at end of file
@@ -0,0 +1,119 @@
+package vip
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// This file defines the VIP models, e.g. tiers, subscriptions and their events.
+
+const (
+	// SourcePurchase marks subscriptions bought in the shop.
+	SourcePurchase Source = "purchase"
+	// SourceComplimentary marks subscriptions issued by staff for free.
+	SourceComplimentary Source = "complimentary"
+	// SourceReward marks subscriptions given by promo codes and referrals.
+	SourceReward Source = "reward"
+
+	// StatusActive is set until the subscription expires, including the not yet started ones.
+	StatusActive Status = "active"
+	// StatusGrace is set after the expiry while the player keeps the perks for the grace period.
+	StatusGrace Status = "grace"
+	// StatusExpired is set once the grace period is over.
+	StatusExpired Status = "expired"
+	// StatusRevoked is set when the subscription was taken back, e.g. refunded.
+	StatusRevoked Status = "revoked"
+
+	EventStarted      EventType = "subscription.started"
+	EventGraceStarted EventType = "subscription.grace_started"
+	EventExpired      EventType = "subscription.expired"
+	EventRevoked      EventType = "subscription.revoked"
+)
+
+type (
+	// Source represents the way a subscription was obtained.
+	Source string
+
+	// Status represents the state of a subscription.
+	Status string
+
+	// EventType represents the type of a subscription event.
+	EventType string
+
+	// Tier represents a VIP tier, higher ranks win when several subscriptions are active.
+	Tier struct {
+		Name  string   `json:"name"`
+		Rank  int      `json:"rank"`
+		Perks []string `json:"perks"`
+	}
+
+	// Subscription represents a period of VIP status attached to a user account.
+	// Every purchase or grant creates a new subscription, repeated purchases
+	// of the same tier are stacked one after another.
+	Subscription struct {
+		ID          uuid.UUID     `json:"id"`
+		UserID      uuid.UUID     `json:"user_id"`
+		Tier        string        `json:"tier"`
+		Source      Source        `json:"source"`
+		Status      Status        `json:"status"`
+		Starts      time.Time     `json:"starts"`
+		Expires     time.Time     `json:"expires"`
+		OrderItemID uuid.NullUUID `json:"order_item_id"`
+		IssuedBy    string        `json:"issued_by,omitempty"`
+		Note        string        `json:"note,omitempty"`
+		Created     time.Time     `json:"created"`
+		Updated     time.Time     `json:"updated"`
+	}
+
+	// Grant represents a request to give VIP status to a user.
+	Grant struct {
+		UserID uuid.UUID `json:"user_id"`
+		Tier   string    `json:"tier"`
+		Days   int       `json:"days"`
+		Source Source    `json:"-"`
+		// OrderItemID makes purchases idempotent, an order item is granted only once.
+		OrderItemID uuid.NullUUID `json:"-"`
+		IssuedBy    string        `json:"issued_by"`
+		Note        string        `json:"note"`
+	}
+
+	// Entitlements represents the current VIP status of a user as seen by the game server.
+	Entitlements struct {
+		UserID  uuid.UUID  `json:"user_id"`
+		Active  bool       `json:"active"`
+		Tier    string     `json:"tier,omitempty"`
+		Rank    int        `json:"rank"`
+		Perks   []string   `json:"perks"`
+		Expires *time.Time `json:"expires,omitempty"`
+		InGrace bool       `json:"in_grace"`
+	}
+
+	// Event represents a subscription lifecycle change.
+	Event struct {
+		Type           EventType `json:"type"`
+		SubscriptionID uuid.UUID `json:"subscription_id"`
+		UserID         uuid.UUID `json:"user_id"`
+		Tier           string    `json:"tier"`
+		Expires        time.Time `json:"expires"`
+		Occurred       time.Time `json:"occurred"`
+	}
+)
+
+// DefaultTiers lists the VIP tiers sold on the server.
+var DefaultTiers = []Tier{
+	{Name: "bronze", Rank: 1, Perks: []string{"priority_queue"}},
+	{Name: "silver", Rank: 2, Perks: []string{"priority_queue", "custom_plate"}},
+	{Name: "gold", Rank: 3, Perks: []string{"priority_queue", "custom_plate", "extra_garage"}},
+}
+
+// ActiveAt reports whether the subscription gives its perks at the moment.
+func (s Subscription) ActiveAt(now time.Time) bool {
+	return s.Status == StatusActive && !now.Before(s.Starts) && now.Before(s.Expires)
+}
+
+// InGraceAt reports whether the subscription is expired but still within the grace period.
+func (s Subscription) InGraceAt(now time.Time, grace time.Duration) bool {
+	return (s.Status == StatusActive || s.Status == StatusGrace) &&
+		!now.Before(s.Expires) && now.Before(s.Expires.Add(grace))
+}
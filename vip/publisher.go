@@ -0,0 +1,33 @@
+package vip
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// This file contains subscription event publishers.
+
+type (
+	// LogPublisher writes subscription events to the log.
+	LogPublisher struct {
+		logger zerolog.Logger
+	}
+)
+
+// NewLogPublisher creates a new publisher that writes events to the logger.
+func NewLogPublisher(logger zerolog.Logger) *LogPublisher {
+	return &LogPublisher{logger}
+}
+
+// Publish writes the event to the log.
+func (p *LogPublisher) Publish(_ context.Context, event Event) error {
+	p.logger.Info().
+		Str("event", string(event.Type)).
+		Str("subscription_id", event.SubscriptionID.String()).
+		Str("user_id", event.UserID.String()).
+		Str("tier", event.Tier).
+		Time("expires", event.Expires).
+		Msg("vip subscription event")
+	return nil
+}
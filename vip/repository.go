@@ -0,0 +1,174 @@
+package vip
+
+// This file contains VIP repository related code.
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/db"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+const (
+	subscriptionColumns = "id, user_id, tier, source, status, starts, expires, order_item_id, issued_by, note, created, updated"
+)
+
+type (
+	// repository implements the Repository interface.
+	repository struct {
+		db *sql.DB
+		tx *db.TxManager
+	}
+
+	// scanner is implemented by both *sql.Row and *sql.Rows.
+	scanner interface {
+		Scan(dest ...any) error
+	}
+)
+
+// NewRepository creates a new VIP repository.
+// It runs the queries in the transaction of the context, see db.TxManager.
+func NewRepository(sqlDB *sql.DB) Repository {
+	return &repository{sqlDB, db.NewTxManager(sqlDB)}
+}
+
+// querier returns the transaction of the context or the database.
+func (r *repository) querier(ctx context.Context) db.Querier {
+	return db.QuerierFrom(ctx, r.db)
+}
+
+// Stack inserts the subscription built by fn from the current subscriptions of the user.
+func (r *repository) Stack(ctx context.Context, userID uuid.UUID, orderItemID uuid.NullUUID, fn func(current []Subscription) (Subscription, error)) (Subscription, bool, error) {
+	var (
+		sub     Subscription
+		created bool
+	)
+	err := r.tx.InTx(ctx, func(ctx context.Context) error {
+		q := r.querier(ctx)
+
+		// Serialize the stacking of the user's subscriptions.
+		if _, err := q.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('vip:' || $1))", userID.String()); err != nil {
+			return fmt.Errorf("lock user: %w", err)
+		}
+
+		if orderItemID.Valid {
+			var exists bool
+			err := q.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM vip_subscription WHERE order_item_id = $1)", orderItemID).Scan(&exists)
+			if err != nil {
+				return fmt.Errorf("check order item: %w", err)
+			}
+			if exists {
+				return nil
+			}
+		}
+
+		current, err := findByUser(ctx, q, userID)
+		if err != nil {
+			return fmt.Errorf("find subscriptions: %w", err)
+		}
+
+		sub, err = fn(current)
+		if err != nil {
+			return err
+		}
+
+		err = q.QueryRowContext(ctx,
+			"INSERT INTO vip_subscription (id, user_id, tier, source, status, starts, expires, order_item_id, issued_by, note) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING created, updated",
+			sub.ID, sub.UserID, sub.Tier, sub.Source, sub.Status, sub.Starts, sub.Expires, sub.OrderItemID, sub.IssuedBy, sub.Note).
+			Scan(&sub.Created, &sub.Updated)
+		if err != nil {
+			return fmt.Errorf("insert subscription: %w", err)
+		}
+		created = true
+		return nil
+	})
+	if err != nil || !created {
+		return Subscription{}, false, err
+	}
+	return sub, true, nil
+}
+
+// FindByID returns a subscription by id.
+func (r *repository) FindByID(ctx context.Context, id uuid.UUID) (Subscription, error) {
+	sub, err := scanSubscription(r.querier(ctx).QueryRowContext(ctx, "SELECT "+subscriptionColumns+" FROM vip_subscription WHERE id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Subscription{}, ErrNotFound
+	}
+	return sub, err
+}
+
+// FindByOrderItemID returns the subscription bought with the order item.
+func (r *repository) FindByOrderItemID(ctx context.Context, orderItemID uuid.UUID) (Subscription, error) {
+	sub, err := scanSubscription(r.querier(ctx).QueryRowContext(ctx, "SELECT "+subscriptionColumns+" FROM vip_subscription WHERE order_item_id = $1", orderItemID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Subscription{}, ErrNotFound
+	}
+	return sub, err
+}
+
+// FindByUser returns all subscriptions of a user.
+func (r *repository) FindByUser(ctx context.Context, userID uuid.UUID) ([]Subscription, error) {
+	return findByUser(ctx, r.querier(ctx), userID)
+}
+
+// FindDue returns subscriptions with the status that expired before the given time.
+func (r *repository) FindDue(ctx context.Context, status Status, before time.Time, limit int) ([]Subscription, error) {
+	rows, err := r.querier(ctx).QueryContext(ctx,
+		"SELECT "+subscriptionColumns+" FROM vip_subscription WHERE status = $1 AND expires <= $2 ORDER BY expires LIMIT $3",
+		status, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanSubscriptions(rows)
+}
+
+// UpdateStatus moves the subscription from one status to another.
+func (r *repository) UpdateStatus(ctx context.Context, id uuid.UUID, from, to Status) (bool, error) {
+	res, err := r.querier(ctx).ExecContext(ctx,
+		"UPDATE vip_subscription SET status = $3, updated = NOW() WHERE id = $1 AND status = $2",
+		id, from, to)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// findByUser returns all subscriptions of a user ordered by their start.
+func findByUser(ctx context.Context, q db.Querier, userID uuid.UUID) ([]Subscription, error) {
+	rows, err := q.QueryContext(ctx,
+		"SELECT "+subscriptionColumns+" FROM vip_subscription WHERE user_id = $1 ORDER BY starts, created", userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanSubscriptions(rows)
+}
+
+// scanSubscriptions scans and closes subscription rows.
+func scanSubscriptions(rows *sql.Rows) ([]Subscription, error) {
+	defer rows.Close()
+
+	var subs []Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// scanSubscription scans a subscription row.
+func scanSubscription(row scanner) (Subscription, error) {
+	var s Subscription
+	err := row.Scan(&s.ID, &s.UserID, &s.Tier, &s.Source, &s.Status, &s.Starts, &s.Expires,
+		&s.OrderItemID, &s.IssuedBy, &s.Note, &s.Created, &s.Updated)
+	return s, err
+}
@@ -0,0 +1,296 @@
+package vip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// This file contains the VIP service implementation.
+
+// tracer traces the service methods.
+var tracer = otel.Tracer("github.com/GTA5-RP-Aristocracy/site-back/vip")
+
+const (
+	// expireBatch limits the number of subscriptions expired in a single run.
+	expireBatch = 100
+)
+
+type (
+	// service implements the Service interface.
+	service struct {
+		repo      Repository
+		publisher Publisher
+		tiers     map[string]Tier
+		grace     time.Duration
+		now       func() time.Time
+	}
+)
+
+// NewService creates a new VIP service.
+func NewService(repo Repository, publisher Publisher, tiers []Tier, config Config) Service {
+	s := &service{
+		repo:      repo,
+		publisher: publisher,
+		tiers:     make(map[string]Tier, len(tiers)),
+		grace:     config.GracePeriod,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+	for _, tier := range tiers {
+		s.tiers[tier.Name] = tier
+	}
+	return s
+}
+
+// Grant gives VIP status to a user.
+//
+// A subscription of the same tier that is still active or in the grace period is extended:
+// the new one starts right at its expiry, so renewing during the grace period doesn't lose days.
+// Subscriptions of different tiers run side by side and the highest tier wins.
+func (s *service) Grant(ctx context.Context, grant Grant) (Subscription, error) {
+	ctx, span := tracer.Start(ctx, "vip.Grant")
+	defer span.End()
+
+	grant.Tier = strings.ToLower(strings.TrimSpace(grant.Tier))
+	if _, ok := s.tiers[grant.Tier]; !ok {
+		return Subscription{}, ErrUnknownTier
+	}
+	if grant.Days <= 0 {
+		return Subscription{}, ErrInvalidDays
+	}
+	if grant.Source == "" {
+		grant.Source = SourceComplimentary
+	}
+
+	now := s.now()
+	sub, created, err := s.repo.Stack(ctx, grant.UserID, grant.OrderItemID, func(current []Subscription) (Subscription, error) {
+		// Continue from the latest expiry of the same tier that is still active or in grace.
+		var latest time.Time
+		for _, c := range current {
+			if c.Tier != grant.Tier || (c.Status != StatusActive && c.Status != StatusGrace) {
+				continue
+			}
+			if c.Expires.Add(s.grace).After(now) && c.Expires.After(latest) {
+				latest = c.Expires
+			}
+		}
+
+		starts := now
+		if !latest.IsZero() {
+			starts = latest
+		}
+
+		return Subscription{
+			ID:          uuid.New(),
+			UserID:      grant.UserID,
+			Tier:        grant.Tier,
+			Source:      grant.Source,
+			Status:      StatusActive,
+			Starts:      starts,
+			Expires:     starts.AddDate(0, 0, grant.Days),
+			OrderItemID: grant.OrderItemID,
+			IssuedBy:    grant.IssuedBy,
+			Note:        grant.Note,
+		}, nil
+	})
+	if err != nil {
+		return Subscription{}, fmt.Errorf("stack subscription: %w", err)
+	}
+
+	// The order item has already been granted.
+	if !created {
+		return s.repo.FindByOrderItemID(ctx, grant.OrderItemID.UUID)
+	}
+
+	s.publish(ctx, EventStarted, sub, now)
+	return sub, nil
+}
+
+// Revoke takes a subscription back.
+func (s *service) Revoke(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "vip.Revoke")
+	defer span.End()
+
+	sub, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.revoke(ctx, sub)
+}
+
+// RevokeOrderItem takes back the subscription bought with the order item, if any.
+func (s *service) RevokeOrderItem(ctx context.Context, orderItemID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "vip.RevokeOrderItem")
+	defer span.End()
+
+	sub, err := s.repo.FindByOrderItemID(ctx, orderItemID)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.revoke(ctx, sub)
+}
+
+// Subscriptions fetches all subscriptions of a user.
+func (s *service) Subscriptions(ctx context.Context, userID uuid.UUID) ([]Subscription, error) {
+	ctx, span := tracer.Start(ctx, "vip.Subscriptions")
+	defer span.End()
+
+	return s.repo.FindByUser(ctx, userID)
+}
+
+// Entitlements fetches the current VIP status of a user.
+// The highest active tier wins, a subscription in the grace period counts only
+// if there is no active one.
+func (s *service) Entitlements(ctx context.Context, userID uuid.UUID) (Entitlements, error) {
+	ctx, span := tracer.Start(ctx, "vip.Entitlements")
+	defer span.End()
+
+	subs, err := s.repo.FindByUser(ctx, userID)
+	if err != nil {
+		return Entitlements{}, err
+	}
+
+	now := s.now()
+	var best *Subscription
+	bestGrace := false
+	for i := range subs {
+		sub := &subs[i]
+		active, grace := sub.ActiveAt(now), sub.InGraceAt(now, s.grace)
+		if !active && !grace {
+			continue
+		}
+		if best == nil || (bestGrace && active) || (bestGrace == grace && s.tiers[sub.Tier].Rank > s.tiers[best.Tier].Rank) {
+			best, bestGrace = sub, grace
+		}
+	}
+
+	entitlements := Entitlements{UserID: userID, Perks: []string{}}
+	if best == nil {
+		return entitlements, nil
+	}
+
+	tier := s.tiers[best.Tier]
+	entitlements.Active = true
+	entitlements.Tier = tier.Name
+	entitlements.Rank = tier.Rank
+	entitlements.Perks = append(entitlements.Perks, tier.Perks...)
+	entitlements.InGrace = bestGrace
+
+	// Report the end of the continuous period of the tier, including the stacked subscriptions.
+	expires := best.Expires
+	for changed := true; changed; {
+		changed = false
+		for _, sub := range subs {
+			if sub.Tier == best.Tier && sub.Status == StatusActive && !sub.Starts.After(expires) && sub.Expires.After(expires) {
+				expires, changed = sub.Expires, true
+			}
+		}
+	}
+	entitlements.Expires = &expires
+	return entitlements, nil
+}
+
+// ExpireDue moves subscriptions past their expiry to the grace and expired statuses.
+// Every transition is a conditional update, so several replicas may run it at once
+// and every event is still published only once.
+func (s *service) ExpireDue(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "vip.ExpireDue")
+	defer span.End()
+
+	now := s.now()
+	changed := 0
+
+	// Active subscriptions past their expiry enter the grace period,
+	// unless the user keeps the same tier thanks to a stacked subscription.
+	due, err := s.repo.FindDue(ctx, StatusActive, now, expireBatch)
+	if err != nil {
+		return changed, fmt.Errorf("find expired subscriptions: %w", err)
+	}
+	for _, sub := range due {
+		next, event := StatusGrace, EventGraceStarted
+		if !sub.InGraceAt(now, s.grace) {
+			next, event = StatusExpired, EventExpired
+		} else if continued, err := s.continued(ctx, sub, now); err != nil {
+			return changed, err
+		} else if continued {
+			next, event = StatusExpired, EventExpired
+		}
+
+		if ok, err := s.transition(ctx, sub, next, event, now); err != nil {
+			return changed, err
+		} else if ok {
+			changed++
+		}
+	}
+
+	// Subscriptions past the grace period expire.
+	due, err = s.repo.FindDue(ctx, StatusGrace, now.Add(-s.grace), expireBatch)
+	if err != nil {
+		return changed, fmt.Errorf("find subscriptions in grace: %w", err)
+	}
+	for _, sub := range due {
+		if ok, err := s.transition(ctx, sub, StatusExpired, EventExpired, now); err != nil {
+			return changed, err
+		} else if ok {
+			changed++
+		}
+	}
+
+	return changed, nil
+}
+
+// continued reports whether another subscription of the same tier is active at the moment.
+func (s *service) continued(ctx context.Context, sub Subscription, now time.Time) (bool, error) {
+	subs, err := s.repo.FindByUser(ctx, sub.UserID)
+	if err != nil {
+		return false, fmt.Errorf("find subscriptions: %w", err)
+	}
+	for _, other := range subs {
+		if other.ID != sub.ID && other.Tier == sub.Tier && other.ActiveAt(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// revoke moves a subscription that still gives perks to the revoked status.
+func (s *service) revoke(ctx context.Context, sub Subscription) error {
+	if sub.Status != StatusActive && sub.Status != StatusGrace {
+		return nil
+	}
+	_, err := s.transition(ctx, sub, StatusRevoked, EventRevoked, s.now())
+	return err
+}
+
+// transition moves the subscription to the next status and publishes the event.
+func (s *service) transition(ctx context.Context, sub Subscription, next Status, event EventType, now time.Time) (bool, error) {
+	ok, err := s.repo.UpdateStatus(ctx, sub.ID, sub.Status, next)
+	if err != nil {
+		return false, fmt.Errorf("update subscription: %w", err)
+	}
+	if ok {
+		s.publish(ctx, event, sub, now)
+	}
+	return ok, nil
+}
+
+// publish delivers the event, the subscription change is already stored
+// so delivery errors don't fail the operation.
+func (s *service) publish(ctx context.Context, eventType EventType, sub Subscription, now time.Time) {
+	_ = s.publisher.Publish(ctx, Event{
+		Type:           eventType,
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		Tier:           sub.Tier,
+		Expires:        sub.Expires,
+		Occurred:       now,
+	})
+}
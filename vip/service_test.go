@@ -0,0 +1,350 @@
+package vip
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) Stack(ctx context.Context, userID uuid.UUID, orderItemID uuid.NullUUID, fn func(current []Subscription) (Subscription, error)) (Subscription, bool, error) {
+	args := m.Called(userID, orderItemID, fn)
+	return args.Get(0).(Subscription), args.Bool(1), args.Error(2)
+}
+
+func (m *MockRepo) FindByID(ctx context.Context, id uuid.UUID) (Subscription, error) {
+	args := m.Called(id)
+	return args.Get(0).(Subscription), args.Error(1)
+}
+
+func (m *MockRepo) FindByOrderItemID(ctx context.Context, orderItemID uuid.UUID) (Subscription, error) {
+	args := m.Called(orderItemID)
+	return args.Get(0).(Subscription), args.Error(1)
+}
+
+func (m *MockRepo) FindByUser(ctx context.Context, userID uuid.UUID) ([]Subscription, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]Subscription), args.Error(1)
+}
+
+func (m *MockRepo) FindDue(ctx context.Context, status Status, before time.Time, limit int) ([]Subscription, error) {
+	args := m.Called(status, before, limit)
+	return args.Get(0).([]Subscription), args.Error(1)
+}
+
+func (m *MockRepo) UpdateStatus(ctx context.Context, id uuid.UUID, from, to Status) (bool, error) {
+	args := m.Called(id, from, to)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockPublisher struct {
+	mock.Mock
+}
+
+func (m *MockPublisher) Publish(ctx context.Context, event Event) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+var testNow = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+// newTestService creates a service with a fixed clock and a 3 days grace period.
+func newTestService(repo Repository, publisher Publisher) *service {
+	s := NewService(repo, publisher, DefaultTiers, Config{GracePeriod: 72 * time.Hour}).(*service)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+// stackOn makes the Stack call build the subscription from the given current ones.
+func stackOn(mockRepo *MockRepo, current []Subscription) {
+	stack := mockRepo.On("Stack", mock.Anything, mock.Anything, mock.Anything)
+	stack.Run(func(args mock.Arguments) {
+		fn := args.Get(2).(func([]Subscription) (Subscription, error))
+		sub, err := fn(current)
+		stack.ReturnArguments = mock.Arguments{sub, err == nil, err}
+	})
+}
+
+func TestService_Grant_Stacking(t *testing.T) {
+	userID := uuid.New()
+	day := 24 * time.Hour
+
+	cases := []struct {
+		testName       string
+		current        []Subscription
+		tier           string
+		expectedStarts time.Time
+	}{
+		{
+			testName:       "first subscription",
+			tier:           "gold",
+			expectedStarts: testNow,
+		},
+		{
+			testName: "active subscription of the same tier",
+			current: []Subscription{
+				{Tier: "gold", Status: StatusActive, Starts: testNow.Add(-day), Expires: testNow.Add(5 * day)},
+				{Tier: "gold", Status: StatusActive, Starts: testNow.Add(5 * day), Expires: testNow.Add(35 * day)},
+			},
+			tier:           "gold",
+			expectedStarts: testNow.Add(35 * day),
+		},
+		{
+			testName: "renewal in the grace period",
+			current: []Subscription{
+				{Tier: "gold", Status: StatusGrace, Starts: testNow.Add(-31 * day), Expires: testNow.Add(-day)},
+			},
+			tier:           "gold",
+			expectedStarts: testNow.Add(-day),
+		},
+		{
+			testName: "renewal after the grace period",
+			current: []Subscription{
+				{Tier: "gold", Status: StatusGrace, Starts: testNow.Add(-35 * day), Expires: testNow.Add(-5 * day)},
+			},
+			tier:           "gold",
+			expectedStarts: testNow,
+		},
+		{
+			testName: "active subscription of another tier",
+			current: []Subscription{
+				{Tier: "silver", Status: StatusActive, Starts: testNow.Add(-day), Expires: testNow.Add(5 * day)},
+			},
+			tier:           "gold",
+			expectedStarts: testNow,
+		},
+		{
+			testName: "revoked subscription of the same tier",
+			current: []Subscription{
+				{Tier: "gold", Status: StatusRevoked, Starts: testNow.Add(-day), Expires: testNow.Add(5 * day)},
+			},
+			tier:           "gold",
+			expectedStarts: testNow,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			mockPublisher := new(MockPublisher)
+			svc := newTestService(mockRepo, mockPublisher)
+
+			stackOn(mockRepo, tc.current)
+			mockPublisher.On("Publish", mock.MatchedBy(func(e Event) bool { return e.Type == EventStarted })).Return(nil)
+
+			sub, err := svc.Grant(context.Background(), Grant{UserID: userID, Tier: tc.tier, Days: 30})
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedStarts, sub.Starts)
+			assert.Equal(t, tc.expectedStarts.AddDate(0, 0, 30), sub.Expires)
+			assert.Equal(t, StatusActive, sub.Status)
+			assert.Equal(t, SourceComplimentary, sub.Source)
+			mockPublisher.AssertNumberOfCalls(t, "Publish", 1)
+		})
+	}
+}
+
+func TestService_Grant_Invalid(t *testing.T) {
+	cases := []struct {
+		testName      string
+		grant         Grant
+		expectedError error
+	}{
+		{
+			testName:      "unknown tier",
+			grant:         Grant{Tier: "platinum", Days: 30},
+			expectedError: ErrUnknownTier,
+		},
+		{
+			testName:      "no days",
+			grant:         Grant{Tier: "gold"},
+			expectedError: ErrInvalidDays,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			svc := newTestService(mockRepo, new(MockPublisher))
+
+			_, err := svc.Grant(context.Background(), tc.grant)
+
+			assert.ErrorIs(t, err, tc.expectedError)
+			mockRepo.AssertNotCalled(t, "Stack", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_Grant_OrderItemOnce(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockPublisher := new(MockPublisher)
+	svc := newTestService(mockRepo, mockPublisher)
+
+	itemID := uuid.New()
+	existing := Subscription{ID: uuid.New(), Tier: "gold", OrderItemID: uuid.NullUUID{UUID: itemID, Valid: true}}
+	mockRepo.On("Stack", mock.Anything, existing.OrderItemID, mock.Anything).Return(Subscription{}, false, nil)
+	mockRepo.On("FindByOrderItemID", itemID).Return(existing, nil)
+
+	sub, err := svc.Grant(context.Background(), Grant{Tier: "gold", Days: 30, Source: SourcePurchase, OrderItemID: existing.OrderItemID})
+	require.NoError(t, err)
+
+	assert.Equal(t, existing.ID, sub.ID)
+	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything)
+}
+
+func TestService_Entitlements(t *testing.T) {
+	userID := uuid.New()
+	day := 24 * time.Hour
+
+	cases := []struct {
+		testName        string
+		subs            []Subscription
+		expectedActive  bool
+		expectedTier    string
+		expectedGrace   bool
+		expectedExpires time.Time
+	}{
+		{
+			testName: "no subscriptions",
+		},
+		{
+			testName: "highest tier wins",
+			subs: []Subscription{
+				{Tier: "silver", Status: StatusActive, Starts: testNow.Add(-day), Expires: testNow.Add(10 * day)},
+				{Tier: "gold", Status: StatusActive, Starts: testNow.Add(-day), Expires: testNow.Add(2 * day)},
+			},
+			expectedActive:  true,
+			expectedTier:    "gold",
+			expectedExpires: testNow.Add(2 * day),
+		},
+		{
+			testName: "stacked subscriptions",
+			subs: []Subscription{
+				{Tier: "gold", Status: StatusActive, Starts: testNow.Add(-day), Expires: testNow.Add(2 * day)},
+				{Tier: "gold", Status: StatusActive, Starts: testNow.Add(2 * day), Expires: testNow.Add(32 * day)},
+			},
+			expectedActive:  true,
+			expectedTier:    "gold",
+			expectedExpires: testNow.Add(32 * day),
+		},
+		{
+			testName: "active wins over grace",
+			subs: []Subscription{
+				{Tier: "gold", Status: StatusGrace, Starts: testNow.Add(-31 * day), Expires: testNow.Add(-day)},
+				{Tier: "bronze", Status: StatusActive, Starts: testNow.Add(-day), Expires: testNow.Add(day)},
+			},
+			expectedActive:  true,
+			expectedTier:    "bronze",
+			expectedExpires: testNow.Add(day),
+		},
+		{
+			testName: "grace period",
+			subs: []Subscription{
+				{Tier: "gold", Status: StatusGrace, Starts: testNow.Add(-31 * day), Expires: testNow.Add(-day)},
+			},
+			expectedActive:  true,
+			expectedTier:    "gold",
+			expectedGrace:   true,
+			expectedExpires: testNow.Add(-day),
+		},
+		{
+			testName: "expired and revoked",
+			subs: []Subscription{
+				{Tier: "gold", Status: StatusExpired, Starts: testNow.Add(-40 * day), Expires: testNow.Add(-10 * day)},
+				{Tier: "gold", Status: StatusRevoked, Starts: testNow.Add(-day), Expires: testNow.Add(day)},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			svc := newTestService(mockRepo, new(MockPublisher))
+
+			mockRepo.On("FindByUser", userID).Return(tc.subs, nil)
+
+			entitlements, err := svc.Entitlements(context.Background(), userID)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedActive, entitlements.Active)
+			assert.Equal(t, tc.expectedTier, entitlements.Tier)
+			assert.Equal(t, tc.expectedGrace, entitlements.InGrace)
+			if tc.expectedActive {
+				require.NotNil(t, entitlements.Expires)
+				assert.Equal(t, tc.expectedExpires, *entitlements.Expires)
+				assert.NotEmpty(t, entitlements.Perks)
+			} else {
+				assert.Nil(t, entitlements.Expires)
+			}
+		})
+	}
+}
+
+func TestService_ExpireDue(t *testing.T) {
+	userID := uuid.New()
+	day := 24 * time.Hour
+
+	expired := Subscription{ID: uuid.New(), UserID: userID, Tier: "silver", Status: StatusActive, Starts: testNow.Add(-31 * day), Expires: testNow.Add(-day)}
+	continued := Subscription{ID: uuid.New(), UserID: userID, Tier: "gold", Status: StatusActive, Starts: testNow.Add(-31 * day), Expires: testNow.Add(-time.Hour)}
+	next := Subscription{ID: uuid.New(), UserID: userID, Tier: "gold", Status: StatusActive, Starts: continued.Expires, Expires: testNow.Add(29 * day)}
+	late := Subscription{ID: uuid.New(), UserID: userID, Tier: "bronze", Status: StatusActive, Starts: testNow.Add(-40 * day), Expires: testNow.Add(-10 * day)}
+	grace := Subscription{ID: uuid.New(), UserID: userID, Tier: "bronze", Status: StatusGrace, Starts: testNow.Add(-35 * day), Expires: testNow.Add(-5 * day)}
+	raced := Subscription{ID: uuid.New(), UserID: userID, Tier: "gold", Status: StatusGrace, Starts: testNow.Add(-35 * day), Expires: testNow.Add(-4 * day)}
+
+	mockRepo := new(MockRepo)
+	mockPublisher := new(MockPublisher)
+	svc := newTestService(mockRepo, mockPublisher)
+
+	mockRepo.On("FindDue", StatusActive, testNow, expireBatch).Return([]Subscription{expired, continued, late}, nil)
+	mockRepo.On("FindDue", StatusGrace, testNow.Add(-72*time.Hour), expireBatch).Return([]Subscription{grace, raced}, nil)
+	mockRepo.On("FindByUser", userID).Return([]Subscription{expired, continued, next, late}, nil)
+	mockRepo.On("UpdateStatus", expired.ID, StatusActive, StatusGrace).Return(true, nil)
+	mockRepo.On("UpdateStatus", continued.ID, StatusActive, StatusExpired).Return(true, nil)
+	mockRepo.On("UpdateStatus", late.ID, StatusActive, StatusExpired).Return(true, nil)
+	mockRepo.On("UpdateStatus", grace.ID, StatusGrace, StatusExpired).Return(true, nil)
+	// Another replica has already expired it.
+	mockRepo.On("UpdateStatus", raced.ID, StatusGrace, StatusExpired).Return(false, nil)
+	mockPublisher.On("Publish", mock.Anything).Return(nil)
+
+	changed, err := svc.ExpireDue(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, changed)
+	mockRepo.AssertExpectations(t)
+	mockPublisher.AssertNumberOfCalls(t, "Publish", 4)
+	mockPublisher.AssertCalled(t, "Publish", mock.MatchedBy(func(e Event) bool {
+		return e.SubscriptionID == expired.ID && e.Type == EventGraceStarted
+	}))
+	mockPublisher.AssertNotCalled(t, "Publish", mock.MatchedBy(func(e Event) bool {
+		return e.SubscriptionID == raced.ID
+	}))
+}
+
+func TestService_RevokeOrderItem(t *testing.T) {
+	itemID := uuid.New()
+	sub := Subscription{ID: uuid.New(), Tier: "gold", Status: StatusActive}
+
+	mockRepo := new(MockRepo)
+	mockPublisher := new(MockPublisher)
+	svc := newTestService(mockRepo, mockPublisher)
+
+	mockRepo.On("FindByOrderItemID", itemID).Return(sub, nil)
+	mockRepo.On("UpdateStatus", sub.ID, StatusActive, StatusRevoked).Return(true, nil)
+	mockPublisher.On("Publish", mock.MatchedBy(func(e Event) bool { return e.Type == EventRevoked })).Return(nil)
+
+	require.NoError(t, svc.RevokeOrderItem(context.Background(), itemID))
+
+	// Items without a subscription have nothing to revoke.
+	mockRepo.On("FindByOrderItemID", mock.Anything).Return(Subscription{}, ErrNotFound)
+	assert.NoError(t, svc.RevokeOrderItem(context.Background(), uuid.New()))
+
+	mockPublisher.AssertNumberOfCalls(t, "Publish", 1)
+}
@@ -0,0 +1,18 @@
+package webhook
+
+type (
+	// Config represents the configuration options for the webhook providers.
+	Config struct {
+		// FakeSecret enables the fake provider webhooks signed with this secret.
+		FakeSecret string `env:"WEBHOOK_FAKE_SECRET" yaml:"fake_secret" secret:"true"`
+	}
+)
+
+// Providers returns the webhook providers enabled by the configuration.
+func (c Config) Providers() []Provider {
+	var providers []Provider
+	if c.FakeSecret != "" {
+		providers = append(providers, NewFakeProvider(c.FakeSecret))
+	}
+	return providers
+}
@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/shop"
+	"github.com/google/uuid"
+)
+
+// This file defines the webhook related interfaces.
+
+type (
+	// Service represents the webhook service interface.
+	Service interface {
+		// Ingest verifies, stores and processes a webhook delivery.
+		// Deliveries of an event already processed or being processed are acknowledged without processing.
+		Ingest(ctx context.Context, provider string, header http.Header, body []byte) (Record, error)
+		// Replay processes a stored event again.
+		// It returns ErrProcessing if the event is being processed.
+		Replay(ctx context.Context, id uuid.UUID) (Record, error)
+		// ReplayFailed processes the stored events that haven't been processed successfully.
+		ReplayFailed(ctx context.Context, limit int) ([]Record, error)
+		// List fetches the latest stored events with the given status.
+		List(ctx context.Context, status Status, limit int) ([]Record, error)
+	}
+
+	// Repository represents the webhook repository interface.
+	Repository interface {
+		// Create inserts a new record unless the provider event is already stored.
+		// It reports whether the record was inserted.
+		Create(ctx context.Context, record Record) (bool, error)
+		// FindByID returns a record by id.
+		FindByID(ctx context.Context, id uuid.UUID) (Record, error)
+		// FindByEventID returns a record by the provider and its event id.
+		FindByEventID(ctx context.Context, provider, eventID string) (Record, error)
+		// FindByStatus returns the latest records with one of the given statuses.
+		FindByStatus(ctx context.Context, limit int, statuses ...Status) ([]Record, error)
+		// Claim marks the record as processing if it has one of the given statuses,
+		// or if its previous claim has timed out. It reports whether the record was claimed.
+		Claim(ctx context.Context, id uuid.UUID, statuses ...Status) (bool, error)
+		// UpdateStatus stores the processing result and counts the attempt.
+		UpdateStatus(ctx context.Context, id uuid.UUID, status Status, message string) error
+	}
+
+	// Provider represents a payment provider that sends webhooks.
+	Provider interface {
+		// Name returns the provider name, it matches the shop payment provider name.
+		Name() string
+		// Verify checks the signature of the delivery.
+		Verify(header http.Header, body []byte) error
+		// Parse decodes the provider event from the payload.
+		Parse(body []byte) (Event, error)
+	}
+
+	// PaymentProcessor applies payment events to orders, it is implemented by shop.Service.
+	PaymentProcessor interface {
+		ApplyPayment(ctx context.Context, event shop.PaymentEvent) (bool, error)
+	}
+)
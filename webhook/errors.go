@@ -0,0 +1,14 @@
+package webhook
+
+// This file contains webhook related errors.
+
+import "errors"
+
+// Define custom errors.
+var (
+	ErrUnknownProvider  = errors.New("webhook: unknown provider")
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	ErrInvalidPayload   = errors.New("webhook: invalid payload")
+	ErrNotFound         = errors.New("webhook: event not found")
+	ErrProcessing       = errors.New("webhook: event is being processed")
+)
@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/api"
+	"github.com/GTA5-RP-Aristocracy/site-back/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+)
+
+// This file contains webhook related http handlers.
+
+const (
+	pathRoot         = "/webhooks"
+	pathIngest       = "/{provider}"
+	pathAdmin        = "/admin"
+	pathEvents       = "/events"
+	pathReplay       = "/events/{id}/replay"
+	pathReplayFailed = "/events/replay-failed"
+
+	// maxPayloadSize limits the size of a webhook delivery.
+	maxPayloadSize = 1 << 20
+)
+
+// errorMappings map the webhook errors to the problem responses.
+var errorMappings = []api.Mapping{
+	{Err: ErrUnknownProvider, Status: http.StatusNotFound, Code: "unknown_provider"},
+	{Err: ErrNotFound, Status: http.StatusNotFound, Code: "event_not_found"},
+	{Err: ErrInvalidSignature, Status: http.StatusUnauthorized, Code: "invalid_signature"},
+	{Err: ErrInvalidPayload, Status: http.StatusBadRequest, Code: "invalid_payload"},
+	{Err: ErrProcessing, Status: http.StatusConflict, Code: "event_processing"},
+}
+
+type (
+	// Handler represents a set of http handlers for payment provider webhooks.
+	Handler struct {
+		service Service
+		auth    auth.Config
+	}
+)
+
+// NewHandler creates a new webhook http handler.
+func NewHandler(service Service, authConfig auth.Config) *Handler {
+	return &Handler{service, authConfig}
+}
+
+// RegisterWebhookRouter registers webhook routes.
+func (h *Handler) RegisterWebhookRouter(externalRouter chi.Router) {
+	r := chi.NewRouter()
+
+	// Provider deliveries are authenticated by their signatures.
+	r.Post(pathIngest, h.Ingest)
+
+	r.Route(pathAdmin, func(r chi.Router) {
+		r.Use(auth.RequireToken(h.auth.AdminToken))
+		r.Get(pathEvents, h.List)
+		r.Post(pathReplay, h.Replay)
+		r.Post(pathReplayFailed, h.ReplayFailed)
+	})
+
+	externalRouter.Mount(pathRoot, r)
+}
+
+// Ingest handles a webhook delivery of a payment provider.
+// Any non 2xx response makes the provider retry the delivery.
+func (h *Handler) Ingest(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadSize))
+	if err != nil {
+		writeError(w, r, api.ErrInvalidBody)
+		return
+	}
+
+	record, err := h.service.Ingest(r.Context(), chi.URLParam(r, "provider"), r.Header, body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]Status{"status": record.Status})
+}
+
+// List handles the stored events request.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	status := Status(r.URL.Query().Get("status"))
+	if status == "" {
+		status = StatusFailed
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	records, err := h.service.List(r.Context(), status, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if records == nil {
+		records = []Record{}
+	}
+
+	writeJSON(w, http.StatusOK, records)
+}
+
+// Replay handles the replay request of a stored event.
+func (h *Handler) Replay(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, api.ErrInvalidID)
+		return
+	}
+
+	record, err := h.service.Replay(r.Context(), id)
+	if err != nil && record.ID == uuid.Nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Processing errors are stored in the record.
+	writeJSON(w, http.StatusOK, record)
+}
+
+// ReplayFailed handles the replay request of all failed events.
+func (h *Handler) ReplayFailed(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	records, err := h.service.ReplayFailed(r.Context(), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if records == nil {
+		records = []Record{}
+	}
+
+	writeJSON(w, http.StatusOK, records)
+}
+
+// writeError writes the problem matching the error.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	api.WriteError(w, r, err, errorMappings...)
+}
+
+// writeJSON writes the response in JSON format.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockService struct {
+	Service
+	funcIngest func(provider string, header http.Header, body []byte) (Record, error)
+	funcReplay func(id uuid.UUID) (Record, error)
+}
+
+func (m *MockService) Ingest(ctx context.Context, provider string, header http.Header, body []byte) (Record, error) {
+	return m.funcIngest(provider, header, body)
+}
+
+func (m *MockService) Replay(ctx context.Context, id uuid.UUID) (Record, error) {
+	return m.funcReplay(id)
+}
+
+func TestHandlerIngest(t *testing.T) {
+	cases := []struct {
+		testName       string
+		ingestErr      error
+		expectedStatus int
+	}{
+		{
+			testName:       "ok",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			testName:       "unknown provider",
+			ingestErr:      ErrUnknownProvider,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			testName:       "invalid signature",
+			ingestErr:      ErrInvalidSignature,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "invalid payload",
+			ingestErr:      ErrInvalidPayload,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			testName:       "processing error",
+			ingestErr:      errors.New("internal error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			router := chi.NewRouter()
+			handler := NewHandler(&MockService{
+				funcIngest: func(provider string, header http.Header, body []byte) (Record, error) {
+					assert.Equal(t, "fake", provider)
+					assert.Equal(t, `{"id":"evt_1"}`, string(body))
+					return Record{Status: StatusProcessed}, tc.ingestErr
+				},
+			}, auth.Config{})
+			handler.RegisterWebhookRouter(router)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/fake", strings.NewReader(`{"id":"evt_1"}`))
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestHandlerReplay(t *testing.T) {
+	recordID := uuid.New()
+
+	cases := []struct {
+		testName       string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{
+			testName:       "ok",
+			path:           "/webhooks/admin/events/" + recordID.String() + "/replay",
+			token:          "admin",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			testName:       "without token",
+			path:           "/webhooks/admin/events/" + recordID.String() + "/replay",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "unknown event",
+			path:           "/webhooks/admin/events/" + uuid.NewString() + "/replay",
+			token:          "admin",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			router := chi.NewRouter()
+			handler := NewHandler(&MockService{
+				funcReplay: func(id uuid.UUID) (Record, error) {
+					if id != recordID {
+						return Record{}, ErrNotFound
+					}
+					return Record{ID: id, Status: StatusFailed}, errors.New("still failing")
+				},
+			}, auth.Config{AdminToken: "admin"})
+			handler.RegisterWebhookRouter(router)
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
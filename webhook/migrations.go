@@ -0,0 +1,8 @@
+package webhook
+
+import "embed"
+
+// Migrations holds the SQL migrations of the payment webhook tables.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS webhook_event;
\ No newline at end of file
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS webhook_event (
+    id UUID PRIMARY KEY,
+    provider VARCHAR(64) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload BYTEA NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    received TIMESTAMP NOT NULL DEFAULT NOW(),
+    processed TIMESTAMP,
+    UNIQUE (provider, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_event_status_index ON webhook_event (status, received);
\ No newline at end of file
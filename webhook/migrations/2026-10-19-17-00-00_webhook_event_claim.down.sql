@@ -0,0 +1 @@
+ALTER TABLE webhook_event DROP COLUMN IF EXISTS claimed;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- The claim time lets another delivery take over an interrupted processing.
+ALTER TABLE webhook_event ADD COLUMN IF NOT EXISTS claimed TIMESTAMP;
\ No newline at end of file
@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/shop"
+	"github.com/google/uuid"
+)
+
+// This file defines the webhook models, e.g. provider events and their stored records.
+
+const (
+	EventPaymentSucceeded EventType = "payment.succeeded"
+	EventPaymentFailed    EventType = "payment.failed"
+	EventPaymentRefunded  EventType = "payment.refunded"
+
+	// StatusReceived is set when the event is stored but not processed yet.
+	StatusReceived Status = "received"
+	// StatusProcessing is set while a delivery or a replay processes the event.
+	StatusProcessing Status = "processing"
+	// StatusProcessed is set when the event changed an order.
+	StatusProcessed Status = "processed"
+	// StatusIgnored is set when the event was a duplicate or arrived out of order.
+	StatusIgnored Status = "ignored"
+	// StatusFailed is set when processing failed, the event may be replayed.
+	StatusFailed Status = "failed"
+)
+
+type (
+	// EventType represents the type of a provider event.
+	EventType string
+
+	// Status represents the processing status of a stored event.
+	Status string
+
+	// Event represents a verified and parsed provider event.
+	Event struct {
+		// ID is the event id assigned by the provider, it is unique per provider.
+		ID        string
+		Type      EventType
+		OrderID   uuid.UUID
+		Reference string
+		Amount    int64
+		Currency  string
+		Occurred  time.Time
+	}
+
+	// Record represents a stored webhook delivery with its raw payload.
+	Record struct {
+		ID        uuid.UUID  `json:"id"`
+		Provider  string     `json:"provider"`
+		EventID   string     `json:"event_id"`
+		EventType EventType  `json:"event_type"`
+		Payload   []byte     `json:"payload"`
+		Status    Status     `json:"status"`
+		Error     string     `json:"error,omitempty"`
+		Attempts  int        `json:"attempts"`
+		Received  time.Time  `json:"received"`
+		Processed *time.Time `json:"processed,omitempty"`
+	}
+)
+
+// PaymentStatus returns the shop payment status matching the event type.
+func (t EventType) PaymentStatus() (shop.PaymentStatus, bool) {
+	switch t {
+	case EventPaymentSucceeded:
+		return shop.PaymentSucceeded, true
+	case EventPaymentFailed:
+		return shop.PaymentFailed, true
+	case EventPaymentRefunded:
+		return shop.PaymentRefunded, true
+	}
+	return "", false
+}
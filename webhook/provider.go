@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/shop"
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+)
+
+// This file contains webhook provider implementations.
+
+const (
+	// SignatureHeader is the header the fake provider signs its deliveries with.
+	SignatureHeader = "X-Fake-Signature"
+
+	// signatureTolerance limits the age of a signed delivery to prevent replay attacks.
+	signatureTolerance = 5 * time.Minute
+)
+
+type (
+	// FakeProvider simulates the webhooks of a payment provider for offline testing.
+	// It pairs with shop.FakeProvider and signs deliveries the way real providers do:
+	// an HMAC-SHA256 of the timestamp and the body, e.g. "t=1700000000,v1=<hex>".
+	FakeProvider struct {
+		secret []byte
+		now    func() time.Time
+	}
+
+	// FakePayload represents the body of a fake provider delivery.
+	FakePayload struct {
+		ID      string    `json:"id"`
+		Type    EventType `json:"type"`
+		Created int64     `json:"created"`
+		Data    struct {
+			OrderID   uuid.UUID `json:"order_id"`
+			Reference string    `json:"reference"`
+			Amount    int64     `json:"amount"`
+			Currency  string    `json:"currency"`
+		} `json:"data"`
+	}
+)
+
+// NewFakeProvider creates a new fake webhook provider with the signing secret.
+func NewFakeProvider(secret string) *FakeProvider {
+	return &FakeProvider{secret: []byte(secret), now: time.Now}
+}
+
+// Name returns the provider name.
+func (p *FakeProvider) Name() string {
+	return shop.FakeProviderName
+}
+
+// Verify checks the signature of the delivery.
+func (p *FakeProvider) Verify(header http.Header, body []byte) error {
+	var timestamp, signature string
+	for _, part := range strings.Split(header.Get(SignatureHeader), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: missing timestamp", ErrInvalidSignature)
+	}
+	if age := p.now().Sub(time.Unix(unix, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return fmt.Errorf("%w: timestamp outside of the tolerance", ErrInvalidSignature)
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, p.mac(timestamp, body)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Parse decodes the provider event from the payload.
+func (p *FakeProvider) Parse(body []byte) (Event, error) {
+	var payload FakePayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return Event{}, fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+	if payload.ID == "" || payload.Data.OrderID == uuid.Nil {
+		return Event{}, fmt.Errorf("%w: missing event or order id", ErrInvalidPayload)
+	}
+
+	return Event{
+		ID:        payload.ID,
+		Type:      payload.Type,
+		OrderID:   payload.Data.OrderID,
+		Reference: payload.Data.Reference,
+		Amount:    payload.Data.Amount,
+		Currency:  payload.Data.Currency,
+		Occurred:  time.Unix(payload.Created, 0),
+	}, nil
+}
+
+// Sign returns the signature header value of the body signed at the given time.
+func (p *FakeProvider) Sign(body []byte, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(p.mac(timestamp, body)))
+}
+
+// mac computes the HMAC of the timestamp and the body.
+func (p *FakeProvider) mac(timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeProvider_Verify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	provider := NewFakeProvider("secret")
+	provider.now = func() time.Time { return now }
+	body := []byte(`{"id":"evt_1"}`)
+
+	cases := []struct {
+		testName      string
+		signature     string
+		body          []byte
+		expectedError error
+	}{
+		{
+			testName:  "ok",
+			signature: provider.Sign(body, now),
+			body:      body,
+		},
+		{
+			testName:      "tampered body",
+			signature:     provider.Sign(body, now),
+			body:          []byte(`{"id":"evt_2"}`),
+			expectedError: ErrInvalidSignature,
+		},
+		{
+			testName:      "other secret",
+			signature:     NewFakeProvider("other").Sign(body, now),
+			body:          body,
+			expectedError: ErrInvalidSignature,
+		},
+		{
+			testName:      "too old",
+			signature:     provider.Sign(body, now.Add(-time.Hour)),
+			body:          body,
+			expectedError: ErrInvalidSignature,
+		},
+		{
+			testName:      "missing",
+			body:          body,
+			expectedError: ErrInvalidSignature,
+		},
+		{
+			testName:      "malformed",
+			signature:     "t=1700000000,v1=zz",
+			body:          body,
+			expectedError: ErrInvalidSignature,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			header := http.Header{}
+			header.Set(SignatureHeader, tc.signature)
+
+			err := provider.Verify(header, tc.body)
+
+			assert.ErrorIs(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestFakeProvider_Parse(t *testing.T) {
+	provider := NewFakeProvider("secret")
+	orderID := uuid.New()
+
+	event, err := provider.Parse([]byte(`{"id":"evt_1","type":"payment.succeeded","created":1700000000,"data":{"order_id":"` +
+		orderID.String() + `","reference":"fake_1","amount":100,"currency":"RUB"}}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, Event{
+		ID:        "evt_1",
+		Type:      EventPaymentSucceeded,
+		OrderID:   orderID,
+		Reference: "fake_1",
+		Amount:    100,
+		Currency:  "RUB",
+		Occurred:  time.Unix(1700000000, 0),
+	}, event)
+
+	_, err = provider.Parse([]byte(`{"type":"payment.succeeded"}`))
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+
+	_, err = provider.Parse([]byte(`not json`))
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+}
@@ -0,0 +1,148 @@
+package webhook
+
+// This file contains webhook repository related code.
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/db"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	recordColumns = "id, provider, event_id, event_type, payload, status, error, attempts, received, processed"
+
+	// claimTimeout is the time after which the claim of an interrupted processing is taken over.
+	claimTimeout = 5 * time.Minute
+)
+
+type (
+	// repository implements the Repository interface.
+	repository struct {
+		db *sql.DB
+	}
+
+	// scanner is implemented by both *sql.Row and *sql.Rows.
+	scanner interface {
+		Scan(dest ...any) error
+	}
+)
+
+// NewRepository creates a new webhook repository.
+// It runs the queries in the transaction of the context, see db.TxManager.
+func NewRepository(sqlDB *sql.DB) Repository {
+	return &repository{sqlDB}
+}
+
+// querier returns the transaction of the context or the database.
+func (r *repository) querier(ctx context.Context) db.Querier {
+	return db.QuerierFrom(ctx, r.db)
+}
+
+// Create inserts a new record unless the provider event is already stored.
+func (r *repository) Create(ctx context.Context, record Record) (bool, error) {
+	res, err := r.querier(ctx).ExecContext(ctx,
+		"INSERT INTO webhook_event (id, provider, event_id, event_type, payload, status) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (provider, event_id) DO NOTHING",
+		record.ID, record.Provider, record.EventID, record.EventType, record.Payload, record.Status)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// FindByID returns a record by id.
+func (r *repository) FindByID(ctx context.Context, id uuid.UUID) (Record, error) {
+	record, err := scanRecord(r.querier(ctx).QueryRowContext(ctx, "SELECT "+recordColumns+" FROM webhook_event WHERE id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Record{}, ErrNotFound
+	}
+	return record, err
+}
+
+// FindByEventID returns a record by the provider and its event id.
+func (r *repository) FindByEventID(ctx context.Context, provider, eventID string) (Record, error) {
+	record, err := scanRecord(r.querier(ctx).QueryRowContext(ctx,
+		"SELECT "+recordColumns+" FROM webhook_event WHERE provider = $1 AND event_id = $2", provider, eventID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Record{}, ErrNotFound
+	}
+	return record, err
+}
+
+// FindByStatus returns the latest records with one of the given statuses.
+func (r *repository) FindByStatus(ctx context.Context, limit int, statuses ...Status) ([]Record, error) {
+	values := make([]string, len(statuses))
+	for i, status := range statuses {
+		values[i] = string(status)
+	}
+
+	rows, err := r.querier(ctx).QueryContext(ctx,
+		"SELECT "+recordColumns+" FROM webhook_event WHERE status = ANY($1) ORDER BY received DESC LIMIT $2",
+		pq.Array(values), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// Claim marks the record as processing if it has one of the given statuses,
+// or if its previous claim has timed out.
+func (r *repository) Claim(ctx context.Context, id uuid.UUID, statuses ...Status) (bool, error) {
+	values := make([]string, len(statuses))
+	for i, status := range statuses {
+		values[i] = string(status)
+	}
+
+	res, err := r.querier(ctx).ExecContext(ctx,
+		"UPDATE webhook_event SET status = $2, claimed = NOW() WHERE id = $1 AND (status = ANY($3) OR (status = $2 AND claimed < NOW() - make_interval(secs => $4)))",
+		id, StatusProcessing, pq.Array(values), claimTimeout.Seconds())
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// UpdateStatus stores the processing result and counts the attempt.
+func (r *repository) UpdateStatus(ctx context.Context, id uuid.UUID, status Status, message string) error {
+	res, err := r.querier(ctx).ExecContext(ctx,
+		"UPDATE webhook_event SET status = $2, error = $3, attempts = attempts + 1, processed = NOW() WHERE id = $1",
+		id, status, message)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// scanRecord scans a record row.
+func scanRecord(row scanner) (Record, error) {
+	var record Record
+	err := row.Scan(&record.ID, &record.Provider, &record.EventID, &record.EventType, &record.Payload,
+		&record.Status, &record.Error, &record.Attempts, &record.Received, &record.Processed)
+	return record, err
+}
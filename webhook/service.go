@@ -0,0 +1,219 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/shop"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// This file contains the webhook service implementation.
+
+// tracer traces the service methods.
+var tracer = otel.Tracer("github.com/GTA5-RP-Aristocracy/site-back/webhook")
+
+const (
+	// maxRecords limits the number of records listed or replayed at once.
+	maxRecords = 100
+)
+
+type (
+	// service implements the Service interface.
+	service struct {
+		repo      Repository
+		processor PaymentProcessor
+		providers map[string]Provider
+	}
+)
+
+// NewService creates a new webhook service for the given providers.
+func NewService(repo Repository, processor PaymentProcessor, providers ...Provider) Service {
+	s := &service{
+		repo:      repo,
+		processor: processor,
+		providers: make(map[string]Provider, len(providers)),
+	}
+	for _, provider := range providers {
+		s.providers[provider.Name()] = provider
+	}
+	return s
+}
+
+// Ingest verifies, stores and processes a webhook delivery.
+func (s *service) Ingest(ctx context.Context, providerName string, header http.Header, body []byte) (Record, error) {
+	ctx, span := tracer.Start(ctx, "webhook.Ingest")
+	defer span.End()
+
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return Record{}, ErrUnknownProvider
+	}
+
+	if err := provider.Verify(header, body); err != nil {
+		return Record{}, err
+	}
+	event, err := provider.Parse(body)
+	if err != nil {
+		return Record{}, err
+	}
+
+	// Persist the raw payload first, so it can be replayed whatever happens next.
+	record := Record{
+		ID:        uuid.New(),
+		Provider:  providerName,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Payload:   body,
+		Status:    StatusReceived,
+	}
+	inserted, err := s.repo.Create(ctx, record)
+	if err != nil {
+		return Record{}, fmt.Errorf("create record: %w", err)
+	}
+
+	claimable := []Status{StatusReceived}
+	if !inserted {
+		record, err = s.repo.FindByEventID(ctx, providerName, event.ID)
+		if err != nil {
+			return Record{}, fmt.Errorf("find record: %w", err)
+		}
+		// Redeliveries retry the events until one of them is processed, the stored one
+		// may have failed or its processing may have been interrupted.
+		if record.Status == StatusProcessed {
+			return record, nil
+		}
+		claimable = append(claimable, StatusFailed, StatusIgnored)
+	}
+
+	// Only one delivery processes the event at a time, the concurrent ones are acknowledged.
+	// Should the processing fail, the event is replayed along with the other failed ones.
+	claimed, err := s.repo.Claim(ctx, record.ID, claimable...)
+	if err != nil {
+		return Record{}, fmt.Errorf("claim record: %w", err)
+	}
+	if !claimed {
+		return record, nil
+	}
+
+	return s.process(ctx, provider, record, event)
+}
+
+// Replay processes a stored event again.
+func (s *service) Replay(ctx context.Context, id uuid.UUID) (Record, error) {
+	ctx, span := tracer.Start(ctx, "webhook.Replay")
+	defer span.End()
+
+	record, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return Record{}, err
+	}
+
+	provider, ok := s.providers[record.Provider]
+	if !ok {
+		return Record{}, ErrUnknownProvider
+	}
+	event, err := provider.Parse(record.Payload)
+	if err != nil {
+		return Record{}, err
+	}
+
+	claimed, err := s.repo.Claim(ctx, record.ID, StatusReceived, StatusProcessed, StatusIgnored, StatusFailed)
+	if err != nil {
+		return Record{}, fmt.Errorf("claim record: %w", err)
+	}
+	if !claimed {
+		return Record{}, ErrProcessing
+	}
+
+	return s.process(ctx, provider, record, event)
+}
+
+// ReplayFailed processes the stored events that haven't been processed successfully.
+// A failing event doesn't stop the replay, its error is reported in the returned record.
+// The events being processed are left alone, unless their processing was interrupted.
+func (s *service) ReplayFailed(ctx context.Context, limit int) ([]Record, error) {
+	ctx, span := tracer.Start(ctx, "webhook.ReplayFailed")
+	defer span.End()
+
+	records, err := s.repo.FindByStatus(ctx, clampLimit(limit), StatusFailed, StatusReceived, StatusProcessing)
+	if err != nil {
+		return nil, fmt.Errorf("find records: %w", err)
+	}
+
+	for i, record := range records {
+		replayed, err := s.Replay(ctx, record.ID)
+		if errors.Is(err, ErrProcessing) {
+			continue
+		}
+		if err != nil {
+			records[i].Status, records[i].Error = StatusFailed, err.Error()
+			continue
+		}
+		records[i] = replayed
+	}
+	return records, nil
+}
+
+// List fetches the latest stored events with the given status.
+func (s *service) List(ctx context.Context, status Status, limit int) ([]Record, error) {
+	ctx, span := tracer.Start(ctx, "webhook.List")
+	defer span.End()
+
+	return s.repo.FindByStatus(ctx, clampLimit(limit), status)
+}
+
+// process applies the event to its order and stores the outcome in the claimed record.
+// Processing errors are returned along with the updated record.
+func (s *service) process(ctx context.Context, provider Provider, record Record, event Event) (Record, error) {
+	applied, err := s.apply(ctx, provider, event)
+	if errors.Is(err, shop.ErrInvalidTransition) {
+		// The order changed concurrently, e.g. it has been paid by another event.
+		applied, err = false, nil
+	}
+
+	switch {
+	case err != nil:
+		record.Status, record.Error = StatusFailed, err.Error()
+	case applied, record.Status == StatusProcessed:
+		// Replaying a processed event changes nothing, it stays processed.
+		record.Status, record.Error = StatusProcessed, ""
+	default:
+		record.Status, record.Error = StatusIgnored, ""
+	}
+	record.Attempts++
+
+	if updateErr := s.repo.UpdateStatus(ctx, record.ID, record.Status, record.Error); updateErr != nil {
+		return Record{}, errors.Join(err, fmt.Errorf("update record: %w", updateErr))
+	}
+	return record, err
+}
+
+// apply forwards the event to the payment processor.
+func (s *service) apply(ctx context.Context, provider Provider, event Event) (bool, error) {
+	status, ok := event.Type.PaymentStatus()
+	if !ok {
+		// Providers send many event types, only the payment ones matter.
+		return false, nil
+	}
+
+	return s.processor.ApplyPayment(ctx, shop.PaymentEvent{
+		OrderID:   event.OrderID,
+		Provider:  provider.Name(),
+		Reference: event.Reference,
+		Status:    status,
+		Amount:    event.Amount,
+		Currency:  event.Currency,
+	})
+}
+
+// clampLimit keeps the limit within (0, maxRecords].
+func clampLimit(limit int) int {
+	if limit <= 0 || limit > maxRecords {
+		return maxRecords
+	}
+	return limit
+}
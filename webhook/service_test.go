@@ -0,0 +1,356 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/GTA5-RP-Aristocracy/site-back/shop"
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) Create(ctx context.Context, record Record) (bool, error) {
+	args := m.Called(record)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindByID(ctx context.Context, id uuid.UUID) (Record, error) {
+	args := m.Called(id)
+	return args.Get(0).(Record), args.Error(1)
+}
+
+func (m *MockRepo) FindByEventID(ctx context.Context, provider, eventID string) (Record, error) {
+	args := m.Called(provider, eventID)
+	return args.Get(0).(Record), args.Error(1)
+}
+
+func (m *MockRepo) FindByStatus(ctx context.Context, limit int, statuses ...Status) ([]Record, error) {
+	args := m.Called(limit, statuses)
+	return args.Get(0).([]Record), args.Error(1)
+}
+
+func (m *MockRepo) Claim(ctx context.Context, id uuid.UUID, statuses ...Status) (bool, error) {
+	args := m.Called(id, statuses)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) UpdateStatus(ctx context.Context, id uuid.UUID, status Status, message string) error {
+	args := m.Called(id, status, message)
+	return args.Error(0)
+}
+
+type MockProcessor struct {
+	mock.Mock
+}
+
+func (m *MockProcessor) ApplyPayment(ctx context.Context, event shop.PaymentEvent) (bool, error) {
+	args := m.Called(event)
+	return args.Bool(0), args.Error(1)
+}
+
+// signedDelivery returns a signed fake provider delivery of the event.
+func signedDelivery(t *testing.T, provider *FakeProvider, eventType EventType, orderID uuid.UUID) (http.Header, []byte) {
+	var payload FakePayload
+	payload.ID = "evt_1"
+	payload.Type = eventType
+	payload.Data.OrderID = orderID
+	payload.Data.Reference = "fake_1"
+	payload.Data.Amount = 100
+	payload.Data.Currency = "RUB"
+
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	header := http.Header{}
+	header.Set(SignatureHeader, provider.Sign(body, time.Now()))
+	return header, body
+}
+
+func TestService_Ingest(t *testing.T) {
+	orderID := uuid.New()
+	provider := NewFakeProvider("secret")
+	processingErr := errors.New("order is locked")
+
+	cases := []struct {
+		testName         string
+		eventType        EventType
+		inserted         bool
+		stored           Record
+		notClaimed       bool
+		applied          bool
+		applyErr         error
+		expectedStatus   Status
+		expectedError    error
+		expectedApplying bool
+	}{
+		{
+			testName:         "new event",
+			eventType:        EventPaymentSucceeded,
+			inserted:         true,
+			applied:          true,
+			expectedStatus:   StatusProcessed,
+			expectedApplying: true,
+		},
+		{
+			testName:         "out of order event",
+			eventType:        EventPaymentFailed,
+			inserted:         true,
+			expectedStatus:   StatusIgnored,
+			expectedApplying: true,
+		},
+		{
+			testName:       "unknown event type",
+			eventType:      "customer.created",
+			inserted:       true,
+			expectedStatus: StatusIgnored,
+		},
+		{
+			testName:         "processing error",
+			eventType:        EventPaymentSucceeded,
+			inserted:         true,
+			applyErr:         processingErr,
+			expectedStatus:   StatusFailed,
+			expectedError:    processingErr,
+			expectedApplying: true,
+		},
+		{
+			testName:       "duplicate of processed event",
+			eventType:      EventPaymentSucceeded,
+			stored:         Record{ID: uuid.New(), Status: StatusProcessed},
+			expectedStatus: StatusProcessed,
+		},
+		{
+			testName:         "duplicate of failed event",
+			eventType:        EventPaymentSucceeded,
+			stored:           Record{ID: uuid.New(), Status: StatusFailed},
+			applied:          true,
+			expectedStatus:   StatusProcessed,
+			expectedApplying: true,
+		},
+		{
+			testName:         "duplicate of received event",
+			eventType:        EventPaymentSucceeded,
+			stored:           Record{ID: uuid.New(), Status: StatusReceived},
+			applied:          true,
+			expectedStatus:   StatusProcessed,
+			expectedApplying: true,
+		},
+		{
+			testName:         "duplicate of ignored event",
+			eventType:        EventPaymentSucceeded,
+			stored:           Record{ID: uuid.New(), Status: StatusIgnored},
+			applied:          true,
+			expectedStatus:   StatusProcessed,
+			expectedApplying: true,
+		},
+		{
+			testName:       "duplicate of event being processed",
+			eventType:      EventPaymentSucceeded,
+			stored:         Record{ID: uuid.New(), Status: StatusProcessing},
+			notClaimed:     true,
+			expectedStatus: StatusProcessing,
+		},
+		{
+			testName:         "order changed concurrently",
+			eventType:        EventPaymentSucceeded,
+			inserted:         true,
+			applyErr:         shop.ErrInvalidTransition,
+			expectedStatus:   StatusIgnored,
+			expectedApplying: true,
+		},
+		{
+			testName:         "duplicate of received event failing",
+			eventType:        EventPaymentSucceeded,
+			stored:           Record{ID: uuid.New(), Status: StatusReceived},
+			applyErr:         processingErr,
+			expectedStatus:   StatusFailed,
+			expectedError:    processingErr,
+			expectedApplying: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			mockProcessor := new(MockProcessor)
+			svc := NewService(mockRepo, mockProcessor, provider)
+			header, body := signedDelivery(t, provider, tc.eventType, orderID)
+
+			mockRepo.On("Create", mock.MatchedBy(func(r Record) bool {
+				return r.Provider == shop.FakeProviderName && r.EventID == "evt_1" && string(r.Payload) == string(body)
+			})).Return(tc.inserted, nil)
+			mockRepo.On("FindByEventID", shop.FakeProviderName, "evt_1").Return(tc.stored, nil)
+			claimable := []Status{StatusReceived}
+			if !tc.inserted {
+				claimable = append(claimable, StatusFailed, StatusIgnored)
+			}
+			mockRepo.On("Claim", mock.Anything, claimable).Return(!tc.notClaimed, nil)
+			mockRepo.On("UpdateStatus", mock.Anything, tc.expectedStatus, mock.Anything).Return(nil)
+			mockProcessor.On("ApplyPayment", mock.MatchedBy(func(e shop.PaymentEvent) bool {
+				return e.OrderID == orderID && e.Provider == shop.FakeProviderName && e.Amount == 100
+			})).Return(tc.applied, tc.applyErr)
+
+			record, err := svc.Ingest(context.Background(), shop.FakeProviderName, header, body)
+
+			assert.ErrorIs(t, err, tc.expectedError)
+			assert.Equal(t, tc.expectedStatus, record.Status)
+			if tc.expectedApplying {
+				mockProcessor.AssertNumberOfCalls(t, "ApplyPayment", 1)
+			} else {
+				mockProcessor.AssertNotCalled(t, "ApplyPayment", mock.Anything)
+			}
+			if tc.notClaimed {
+				mockRepo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestService_Ingest_Rejected(t *testing.T) {
+	provider := NewFakeProvider("secret")
+	header, body := signedDelivery(t, provider, EventPaymentSucceeded, uuid.New())
+
+	cases := []struct {
+		testName      string
+		provider      string
+		header        http.Header
+		body          []byte
+		expectedError error
+	}{
+		{
+			testName:      "unknown provider",
+			provider:      "other",
+			header:        header,
+			body:          body,
+			expectedError: ErrUnknownProvider,
+		},
+		{
+			testName:      "invalid signature",
+			provider:      shop.FakeProviderName,
+			header:        http.Header{},
+			body:          body,
+			expectedError: ErrInvalidSignature,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			svc := NewService(mockRepo, new(MockProcessor), provider)
+
+			_, err := svc.Ingest(context.Background(), tc.provider, tc.header, tc.body)
+
+			assert.ErrorIs(t, err, tc.expectedError)
+			mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+		})
+	}
+}
+
+func TestService_ReplayFailed(t *testing.T) {
+	provider := NewFakeProvider("secret")
+	_, body := signedDelivery(t, provider, EventPaymentSucceeded, uuid.New())
+	ok := Record{ID: uuid.New(), Provider: shop.FakeProviderName, Payload: body, Status: StatusFailed}
+	broken := Record{ID: uuid.New(), Provider: shop.FakeProviderName, Payload: []byte("{"), Status: StatusReceived}
+
+	mockRepo := new(MockRepo)
+	mockProcessor := new(MockProcessor)
+	svc := NewService(mockRepo, mockProcessor, provider)
+
+	busy := Record{ID: uuid.New(), Provider: shop.FakeProviderName, Payload: body, Status: StatusProcessing}
+
+	mockRepo.On("FindByStatus", maxRecords, []Status{StatusFailed, StatusReceived, StatusProcessing}).Return([]Record{ok, broken, busy}, nil)
+	mockRepo.On("FindByID", ok.ID).Return(ok, nil)
+	mockRepo.On("FindByID", broken.ID).Return(broken, nil)
+	mockRepo.On("FindByID", busy.ID).Return(busy, nil)
+	mockRepo.On("Claim", ok.ID, mock.Anything).Return(true, nil)
+	mockRepo.On("Claim", busy.ID, mock.Anything).Return(false, nil)
+	mockRepo.On("UpdateStatus", ok.ID, StatusProcessed, "").Return(nil)
+	mockProcessor.On("ApplyPayment", mock.Anything).Return(true, nil)
+
+	records, err := svc.ReplayFailed(context.Background(), 0)
+	require.NoError(t, err)
+
+	require.Len(t, records, 3)
+	assert.Equal(t, StatusProcessed, records[0].Status)
+	assert.Equal(t, 1, records[0].Attempts)
+	assert.Equal(t, StatusFailed, records[1].Status)
+	assert.NotEmpty(t, records[1].Error)
+	// The event processed by a delivery is left alone.
+	assert.Equal(t, StatusProcessing, records[2].Status)
+	assert.Empty(t, records[2].Error)
+}
+
+func TestService_Replay(t *testing.T) {
+	provider := NewFakeProvider("secret")
+	_, body := signedDelivery(t, provider, EventPaymentSucceeded, uuid.New())
+
+	cases := []struct {
+		testName       string
+		stored         Status
+		notClaimed     bool
+		applied        bool
+		expectedStatus Status
+		expectedError  error
+	}{
+		{
+			testName:       "processed event",
+			stored:         StatusProcessed,
+			expectedStatus: StatusProcessed,
+		},
+		{
+			testName:       "ignored event",
+			stored:         StatusIgnored,
+			expectedStatus: StatusIgnored,
+		},
+		{
+			testName:       "failed event",
+			stored:         StatusFailed,
+			applied:        true,
+			expectedStatus: StatusProcessed,
+		},
+		{
+			testName:      "event being processed",
+			stored:        StatusProcessing,
+			notClaimed:    true,
+			expectedError: ErrProcessing,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.testName, func(t *testing.T) {
+			stored := Record{ID: uuid.New(), Provider: shop.FakeProviderName, Payload: body, Status: tc.stored, Attempts: 1}
+			mockRepo := new(MockRepo)
+			mockProcessor := new(MockProcessor)
+			svc := NewService(mockRepo, mockProcessor, provider)
+
+			mockRepo.On("FindByID", stored.ID).Return(stored, nil)
+			mockRepo.On("Claim", stored.ID, []Status{StatusReceived, StatusProcessed, StatusIgnored, StatusFailed}).Return(!tc.notClaimed, nil)
+			mockProcessor.On("ApplyPayment", mock.Anything).Return(tc.applied, nil)
+			if tc.expectedError == nil {
+				mockRepo.On("UpdateStatus", stored.ID, tc.expectedStatus, "").Return(nil)
+			}
+
+			record, err := svc.Replay(context.Background(), stored.ID)
+			mockRepo.AssertExpectations(t)
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+				mockProcessor.AssertNotCalled(t, "ApplyPayment", mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedStatus, record.Status)
+			assert.Equal(t, 2, record.Attempts)
+		})
+	}
+}